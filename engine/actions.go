@@ -0,0 +1,395 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type BindingDevice int
+
+const (
+	DeviceKey BindingDevice = iota
+	DeviceMouse
+	DeviceGamepadButton
+	DeviceGamepadAxis
+)
+
+// A single physical input that can trigger an action
+type Binding struct {
+	Device BindingDevice
+	Code   int     // key, mouse button, standard gamepad button or standard gamepad axis
+	Sign   float32 // gamepad axes only, which direction of the axis counts: 1 or -1
+}
+
+func KeyBinding(key int) Binding {
+	return Binding{Device: DeviceKey, Code: key}
+}
+
+func MouseBinding(button MouseButton) Binding {
+	return Binding{Device: DeviceMouse, Code: int(button)}
+}
+
+// Binds a standard gamepad button, e.g. GamepadA, on any connected gamepad
+func GamepadButtonBinding(button int) Binding {
+	return Binding{Device: DeviceGamepadButton, Code: button}
+}
+
+// Binds half of a standard gamepad axis, e.g. GamepadLeftX, on any connected gamepad.
+// sign picks the half, 1 for positive or -1 for negative
+func GamepadAxisBinding(axis int, sign float32) Binding {
+	if sign < 0 {
+		sign = -1
+	} else {
+		sign = 1
+	}
+	return Binding{Device: DeviceGamepadAxis, Code: axis, Sign: sign}
+}
+
+// Keys without a name are saved by their code, e.g. "key:162"
+const keyCodePrefix = "key:"
+
+// Name of the binding, using the same names as the saved bindings file, e.g. "W", "LMB", "PadA", "PadLeftX-"
+func (b Binding) String() string {
+	switch b.Device {
+	case DeviceMouse:
+		return mouseNames[MouseButton(b.Code)]
+	case DeviceGamepadButton:
+		return "Pad" + gamepadButtonNames[b.Code]
+	case DeviceGamepadAxis:
+		if b.Sign < 0 {
+			return "Pad" + gamepadAxisNames[b.Code] + "-"
+		}
+		return "Pad" + gamepadAxisNames[b.Code] + "+"
+	default:
+		if name, ok := buttonNames[b.Code]; ok {
+			return name
+		}
+		return keyCodePrefix + strconv.Itoa(b.Code)
+	}
+}
+
+// Parses a binding from its name, see Binding.String
+func ParseBinding(name string) (Binding, error) {
+	for code, n := range mouseNames {
+		if n == name {
+			return MouseBinding(code), nil
+		}
+	}
+	for code, n := range buttonNames {
+		if n == name && code != KeyUnknown {
+			return KeyBinding(code), nil
+		}
+	}
+	if code, ok := strings.CutPrefix(name, keyCodePrefix); ok {
+		if c, err := strconv.Atoi(code); err == nil && c >= 0 && c < KeyLast {
+			return KeyBinding(c), nil
+		}
+	}
+
+	if rest, ok := strings.CutPrefix(name, "Pad"); ok {
+		for code, n := range gamepadButtonNames {
+			if n == rest {
+				return GamepadButtonBinding(code), nil
+			}
+		}
+		if len(rest) > 1 {
+			sign := float32(1)
+			if rest[len(rest)-1] == '-' {
+				sign = -1
+			}
+			for code, n := range gamepadAxisNames {
+				if n+"+" == rest || n+"-" == rest {
+					return GamepadAxisBinding(code, sign), nil
+				}
+			}
+		}
+	}
+	return Binding{}, fmt.Errorf("unknown binding %q", name)
+}
+
+// True if the binding's name parses back to it, so it survives SaveBindings and LoadBindings
+func (b Binding) nameable() bool {
+	parsed, err := ParseBinding(b.String())
+	return err == nil && parsed == b
+}
+
+func (b Binding) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.String())
+}
+
+func (b *Binding) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	parsed, err := ParseBinding(name)
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
+
+// Analog gamepad axes count as pressed past this much travel
+const actionPressThreshold = 0.5
+
+// Named actions and axes, so gameplay code doesn't depend on which keys are used.
+// Axes are built from actions, so rebinding an action also rebinds any axes using it.
+type actionMap struct {
+	actions map[string][]Binding
+	axes    map[string][2]string // negative, positive action
+	axes2D  map[string][2]string // x axis, y axis
+	wasDown map[string]bool      // action state at the end of the last update
+
+	rebindAction string
+	rebindSlot   int
+	rebindDone   func(Binding)
+}
+
+func newActionMap() actionMap {
+	return actionMap{
+		actions: make(map[string][]Binding),
+		axes:    make(map[string][2]string),
+		axes2D:  make(map[string][2]string),
+		wasDown: make(map[string]bool),
+	}
+}
+
+// Adds bindings to an action, creating it if needed
+func (i *input) BindAction(action string, bindings ...Binding) {
+	i.actions.actions[action] = append(i.actions.actions[action], bindings...)
+}
+
+// Replaces all of an action's bindings
+func (i *input) SetBindings(action string, bindings ...Binding) {
+	i.actions.actions[action] = append([]Binding{}, bindings...)
+}
+
+func (i *input) Bindings(action string) []Binding {
+	return i.actions.actions[action]
+}
+
+// Removes the binding from every action that uses it
+func (i *input) Unbind(b Binding) {
+	for name, bindings := range i.actions.actions {
+		kept := bindings[:0]
+		for _, v := range bindings {
+			if v != b {
+				kept = append(kept, v)
+			}
+		}
+		i.actions.actions[name] = kept
+	}
+}
+
+// Defines a 1D axis from -1 to 1, driven by a negative and a positive action
+func (i *input) BindAxis(axis, negative, positive string) {
+	i.actions.axes[axis] = [2]string{negative, positive}
+}
+
+// Defines a 2D axis from two 1D axes. The result is clamped to length 1, so diagonals aren't faster
+func (i *input) BindAxis2D(axis, x, y string) {
+	i.actions.axes2D[axis] = [2]string{x, y}
+}
+
+// True while any of the action's bindings are held
+func (i *input) Action(action string) bool {
+	if i.paused() {
+		return false
+	}
+	return i.actionDown(action)
+}
+
+// True on the first update the action is held
+func (i *input) ActionOnce(action string) bool {
+	if i.paused() {
+		return false
+	}
+	return i.actionDown(action) && !i.actions.wasDown[action]
+}
+
+// True on the first update after the action is released
+func (i *input) ActionUp(action string) bool {
+	if i.paused() {
+		return false
+	}
+	return !i.actionDown(action) && i.actions.wasDown[action]
+}
+
+// How far the action is pressed, from 0 to 1. Keys and buttons are either 0 or 1
+func (i *input) ActionValue(action string) float32 {
+	if i.paused() {
+		return 0
+	}
+	return i.actionValue(action)
+}
+
+func (i *input) Axis(axis string) float32 {
+	if i.paused() {
+		return 0
+	}
+	return i.axisValue(axis)
+}
+
+func (i *input) Axis2D(axis string) mgl32.Vec2 {
+	if i.paused() {
+		return mgl32.Vec2{}
+	}
+	a := i.actions.axes2D[axis]
+	v := mgl32.Vec2{i.axisValue(a[0]), i.axisValue(a[1])}
+	if v.Len() > 1 {
+		v = v.Normalize()
+	}
+	return v
+}
+
+// Waits for the next key, mouse button or gamepad input and uses it as the action's binding at slot.
+// A slot past the end adds a new binding. Escape cancels. done, which can be nil, is called with the new binding
+func (i *input) Rebind(action string, slot int, done func(Binding)) {
+	i.actions.rebindAction = action
+	i.actions.rebindSlot = slot
+	i.actions.rebindDone = done
+}
+
+// True while waiting for an input after Rebind
+func (i *input) Rebinding() bool {
+	return i.actions.rebindAction != ""
+}
+
+func (i *input) CancelRebind() {
+	i.actions.rebindAction = ""
+	i.actions.rebindDone = nil
+}
+
+// Writes every action's bindings to a JSON file
+func (i *input) SaveBindings(path string) error {
+	data, err := json.MarshalIndent(i.actions.actions, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Replaces the bindings of actions in a file written by SaveBindings. Actions not in the file keep their bindings
+func (i *input) LoadBindings(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	actions := make(map[string][]Binding)
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return err
+	}
+	for name, bindings := range actions {
+		i.actions.actions[name] = bindings
+	}
+	return nil
+}
+
+func (i *input) actionDown(action string) bool {
+	return i.actionValue(action) >= actionPressThreshold
+}
+
+func (i *input) actionValue(action string) float32 {
+	var value float32
+	for _, b := range i.actions.actions[action] {
+		value = float32(math.Max(float64(value), float64(i.bindingValue(b))))
+	}
+	return value
+}
+
+func (i *input) axisValue(axis string) float32 {
+	a := i.actions.axes[axis]
+	return mgl32.Clamp(i.actionValue(a[1])-i.actionValue(a[0]), -1, 1)
+}
+
+func (i *input) bindingValue(b Binding) float32 {
+	switch b.Device {
+	case DeviceKey:
+		if b.Code >= 0 && b.Code < KeyLast && i.keysDown[b.Code] {
+			return 1
+		}
+	case DeviceMouse:
+		if validMouseButton(b.Code) && i.mouse.down[b.Code] {
+			return 1
+		}
+	case DeviceGamepadButton:
+		if i.anyPad(AnyGamepad, func(p *Gamepad) bool { return p.button(b.Code) }) {
+			return 1
+		}
+	case DeviceGamepadAxis:
+		return float32(math.Max(0, float64(i.gamepadAxis(AnyGamepad, b.Code)*b.Sign)))
+	}
+	return 0
+}
+
+// Checks for a newly pressed input while rebinding
+func (i *input) updateRebind() {
+	a := &i.actions
+	if a.rebindAction == "" {
+		return
+	}
+	if i.keysDown[KeyEscape] {
+		i.CancelRebind()
+		return
+	}
+
+	b, ok := i.pressedBinding()
+	if !ok {
+		return
+	}
+	bindings := a.actions[a.rebindAction]
+	if a.rebindSlot >= 0 && a.rebindSlot < len(bindings) {
+		bindings[a.rebindSlot] = b
+	} else {
+		bindings = append(bindings, b)
+	}
+	a.actions[a.rebindAction] = bindings
+
+	done := a.rebindDone
+	i.CancelRebind()
+	if done != nil {
+		done(b)
+	}
+}
+
+// Returns an input that was pressed since the last update. Inputs that couldn't be saved and loaded again are skipped
+func (i *input) pressedBinding() (Binding, bool) {
+	for code := 0; code < KeyLast; code++ {
+		if i.keysDown[code] && !i.currentKeys[code] && KeyBinding(code).nameable() {
+			return KeyBinding(code), true
+		}
+	}
+	for b := 0; b < mouseButtonCount; b++ {
+		if (i.mouse.pressed[b] || (i.mouse.down[b] && !i.mouse.current[b])) && MouseBinding(MouseButton(b)).nameable() {
+			return MouseBinding(MouseButton(b)), true
+		}
+	}
+	for _, p := range i.Gamepads() {
+		for b := 0; b < GamepadButtonCount; b++ {
+			if p.button(b) && !p.lastButton(b) {
+				return GamepadButtonBinding(b), true
+			}
+		}
+		for axis := 0; axis < GamepadAxisCount; axis++ {
+			v, last := i.deadzone(p.state, axis), i.deadzone(p.last, axis)
+			if math.Abs(float64(v)) >= actionPressThreshold && math.Abs(float64(last)) < actionPressThreshold {
+				return GamepadAxisBinding(axis, v), true
+			}
+		}
+	}
+	return Binding{}, false
+}
+
+// Records the state of every action, for ActionOnce and ActionUp on the next update
+func (i *input) updateActions() {
+	for name := range i.actions.actions {
+		i.actions.wasDown[name] = i.actionDown(name)
+	}
+}
@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// A key GLFW has a code for that buttonNames doesn't name
+func unnamedKey(t *testing.T) int {
+	t.Helper()
+	for code := 0; code < KeyLast; code++ {
+		if _, ok := buttonNames[code]; !ok {
+			return code
+		}
+	}
+	t.Skip("every key has a name")
+	return 0
+}
+
+func TestBindingJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		binding Binding
+		json    string
+	}{
+		{KeyBinding(KeyW), `"W"`},
+		{KeyBinding(KeySpace), `"Space"`},
+		{KeyBinding(KeyLeftShift), `"LeftShift"`},
+		{MouseBinding(MouseLeft), `"LMB"`},
+		{MouseBinding(Mouse5), `"MB5"`},
+		{MouseBinding(Mouse6), `"MB6"`},
+		{MouseBinding(Mouse8), `"MB8"`},
+		{GamepadButtonBinding(GamepadA), `"PadA"`},
+		{GamepadButtonBinding(GamepadDpadUp), `"PadDpadUp"`},
+		{GamepadAxisBinding(GamepadLeftX, 1), `"PadLeftX+"`},
+		{GamepadAxisBinding(GamepadLeftX, -1), `"PadLeftX-"`},
+		{GamepadAxisBinding(GamepadRightTrigger, 0.3), `"PadRightTrigger+"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.json, func(t *testing.T) {
+			data, err := json.Marshal(tt.binding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.json {
+				t.Errorf("marshalled to %s, want %s", data, tt.json)
+			}
+			var got Binding
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.binding {
+				t.Errorf("unmarshalled to %+v, want %+v", got, tt.binding)
+			}
+		})
+	}
+}
+
+func TestUnnamedKeyRoundTrip(t *testing.T) {
+	b := KeyBinding(unnamedKey(t))
+	want := `"key:` + strconv.Itoa(b.Code) + `"`
+	data, err := json.Marshal(b)
+	if err != nil || string(data) != want {
+		t.Fatalf("marshalled to %s %v, want %s", data, err, want)
+	}
+	var got Binding
+	if err := json.Unmarshal(data, &got); err != nil || got != b {
+		t.Errorf("unmarshalled to %+v %v, want %+v", got, err, b)
+	}
+}
+
+func TestBindingUnmarshalErrors(t *testing.T) {
+	outOfRange := `"key:` + strconv.Itoa(KeyLast) + `"`
+	for _, data := range []string{`"NotAKey"`, `"Pad"`, `"PadLeftX"`, `"Unknown"`, `3`, `"key:"`, `"key:W"`, `"key:-1"`, outOfRange} {
+		var b Binding
+		if err := json.Unmarshal([]byte(data), &b); err == nil {
+			t.Errorf("%s unmarshalled to %+v, want an error", data, b)
+		}
+	}
+}
+
+func TestSaveLoadBindings(t *testing.T) {
+	tests := []struct {
+		name    string
+		saved   map[string][]Binding
+		current map[string][]Binding // bindings when the file is loaded
+		want    map[string][]Binding
+	}{
+		{
+			name:  "every device",
+			saved: map[string][]Binding{"jump": {KeyBinding(KeySpace), MouseBinding(MouseRight), GamepadButtonBinding(GamepadA)}},
+			want:  map[string][]Binding{"jump": {KeyBinding(KeySpace), MouseBinding(MouseRight), GamepadButtonBinding(GamepadA)}},
+		},
+		{
+			name:  "mouse buttons past MB5",
+			saved: map[string][]Binding{"back": {MouseBinding(Mouse6)}, "forward": {MouseBinding(Mouse7), MouseBinding(Mouse8)}},
+			want:  map[string][]Binding{"back": {MouseBinding(Mouse6)}, "forward": {MouseBinding(Mouse7), MouseBinding(Mouse8)}},
+		},
+		{
+			name:    "actions not in the file are kept",
+			saved:   map[string][]Binding{"left": {GamepadAxisBinding(GamepadLeftX, -1)}},
+			current: map[string][]Binding{"left": {KeyBinding(KeyA)}, "fire": {MouseBinding(MouseLeft)}},
+			want:    map[string][]Binding{"left": {GamepadAxisBinding(GamepadLeftX, -1)}, "fire": {MouseBinding(MouseLeft)}},
+		},
+		{
+			name:    "unbound actions stay unbound",
+			saved:   map[string][]Binding{"pause": {}},
+			current: map[string][]Binding{"pause": {KeyBinding(KeyP)}},
+			want:    map[string][]Binding{"pause": {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "bindings.json")
+			saver := initInput()
+			for action, bindings := range tt.saved {
+				saver.SetBindings(action, bindings...)
+			}
+			if err := saver.SaveBindings(path); err != nil {
+				t.Fatal(err)
+			}
+
+			loader := initInput()
+			for action, bindings := range tt.current {
+				loader.SetBindings(action, bindings...)
+			}
+			if err := loader.LoadBindings(path); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(loader.actions.actions, tt.want) {
+				t.Errorf("loaded %v, want %v", loader.actions.actions, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebindSavesAndLoads(t *testing.T) {
+	tests := []struct {
+		name  string
+		press func(i *input)
+		want  Binding
+	}{
+		{"mouse button past MB5", func(i *input) { i.mouseButtonCallback(int(Mouse7), glfw.Press) }, MouseBinding(Mouse7)},
+		{"key without a name", func(i *input) { i.keysDown[unnamedKey(t)] = true }, KeyBinding(unnamedKey(t))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := initInput()
+			var bound Binding
+			i.Rebind("fire", 0, func(b Binding) { bound = b })
+			tt.press(i)
+			i.update()
+			if bound != tt.want || i.Rebinding() {
+				t.Fatalf("rebound to %+v, want %+v", bound, tt.want)
+			}
+
+			path := filepath.Join(t.TempDir(), "bindings.json")
+			if err := i.SaveBindings(path); err != nil {
+				t.Fatal(err)
+			}
+			loader := initInput()
+			if err := loader.LoadBindings(path); err != nil {
+				t.Fatal(err)
+			}
+			if got := loader.Bindings("fire"); !reflect.DeepEqual(got, []Binding{tt.want}) {
+				t.Errorf("loaded %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package engine
+
+import (
+	"log"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type PlayMode int
+
+const (
+	PlayLoop     PlayMode = iota // 0, 1, 2, 0, 1, 2...
+	PlayOnce                     // 0, 1, 2 then stops on the last frame
+	PlayPingPong                 // 0, 1, 2, 1, 0, 1...
+	PlayReverse                  // 2, 1, 0, 2, 1, 0...
+)
+
+type Animator struct {
+	Current     *Animation
+	currentName string
+	animations  map[string]*Animation
+
+	// Called with the animation name when a PlayOnce animation reaches its last frame
+	OnFinish func(name string)
+}
+
+func NewAnimator() Animator {
+	animator := Animator{
+		animations: make(map[string]*Animation),
+	}
+	return animator
+}
+
+func (a *Animator) Add(animation *Animation, name string) {
+	if a.animations == nil {
+		a.animations = make(map[string]*Animation)
+	}
+	a.animations[name] = animation
+}
+
+func (a *Animator) Get(name string) (*Animation, bool) {
+	anim, ok := a.animations[name]
+	return anim, ok
+}
+
+func (a *Animator) Trigger(name string) {
+	anim, ok := a.animations[name]
+	if !ok {
+		log.Println("animation does not exits: ", name)
+		return
+	}
+	// If we're already playing this animation, return
+	if a.Current == anim {
+		return
+	}
+	if a.Current != nil {
+		a.Current.Stop()
+	}
+	a.Current = anim
+	a.currentName = name
+	a.Current.Play()
+}
+
+// Name of the current animation
+func (a *Animator) CurrentName() string {
+	return a.currentName
+}
+
+// Advances the current animation. delta is in seconds, usually engine.Time.Delta()
+func (a *Animator) Update(delta float32) {
+	if a.Current == nil {
+		return
+	}
+	finished := a.Current.Finished()
+	a.Current.Update(delta)
+	if !finished && a.Current.Finished() && a.OnFinish != nil {
+		a.OnFinish(a.currentName)
+	}
+}
+
+func (a *Animator) Stop() {
+	if a.Current != nil {
+		a.Current.Stop()
+	}
+	a.Current = nil
+	a.currentName = ""
+}
+
+func (a *Animator) Frame() Texture {
+	return a.Current.Frame()
+}
+
+func (a *Animator) Trim() mgl32.Vec4 {
+	return a.Current.Trim()
+}
+
+type Animation struct {
+	IsPlaying bool
+	Changed   bool
+	Mode      PlayMode
+
+	OnFinish func() // called when a PlayOnce animation reaches its last frame
+	OnLoop   func() // called every time a looping animation starts a new cycle
+
+	textures  []Texture
+	durations []time.Duration // how long each frame is shown for
+	trims     []mgl32.Vec4    // where each frame sits in the untrimmed sprite, nil if frames aren't trimmed
+	currIndex int
+	direction int // 1 or -1, used by ping pong
+	elapsed   time.Duration
+	finished  bool
+}
+
+// Creates a looping animation from a uniform grid spritesheet
+func NewAnimation(sheet Image, fps, sheetWidth, sheetHeight, spriteWidth, spriteHeight int, flipX bool) *Animation {
+	frames := sheetWidth * sheetHeight
+	textures := make([]Texture, frames)
+	durations := make([]time.Duration, frames)
+	for i := range textures {
+		col := float32((i % sheetWidth))
+		row := float32(i / sheetWidth)
+		textures[i] = NewTextureFromAtlas(sheet, col*float32(spriteWidth), row*float32(spriteHeight), float32(spriteWidth), float32(spriteHeight), flipX)
+		durations[i] = time.Second / time.Duration(fps)
+	}
+
+	return NewAnimationFromFrames(textures, durations, PlayLoop)
+}
+
+// Creates an animation where every frame has its own duration. Extra textures or durations are dropped
+func NewAnimationFromFrames(textures []Texture, durations []time.Duration, mode PlayMode) *Animation {
+	if len(textures) != len(durations) {
+		n := min(len(textures), len(durations))
+		log.Println("animation frame and duration counts do not match, using the first", n)
+		textures, durations = textures[:n], durations[:n]
+	}
+	return &Animation{
+		IsPlaying: false,
+		Mode:      mode,
+		textures:  textures,
+		durations: durations,
+		direction: 1,
+	}
+}
+
+// Starts the animation from the beginning. Animations without frames don't play
+func (a *Animation) Play() {
+	if len(a.textures) == 0 {
+		return
+	}
+	a.currIndex = 0
+	if a.Mode == PlayReverse {
+		a.currIndex = len(a.textures) - 1
+	}
+	a.direction = 1
+	a.elapsed = 0
+	a.finished = false
+	a.IsPlaying = true
+	a.Changed = true
+}
+
+// Stops the animation where it is. Play restarts it from the beginning
+func (a *Animation) Stop() {
+	a.IsPlaying = false
+	a.elapsed = 0
+}
+
+// Advances the animation by delta seconds
+func (a *Animation) Update(delta float32) {
+	if !a.IsPlaying || len(a.textures) == 0 {
+		return
+	}
+
+	a.elapsed += time.Duration(float64(delta) * float64(time.Second))
+	for a.IsPlaying {
+		d := a.duration(a.currIndex)
+		if d <= 0 || a.elapsed < d {
+			break
+		}
+		a.elapsed -= d
+		a.next()
+	}
+}
+
+func (a *Animation) next() {
+	last := len(a.textures) - 1
+	prev := a.currIndex
+
+	switch a.Mode {
+	case PlayOnce:
+		if a.currIndex >= last {
+			a.IsPlaying = false
+			a.finished = true
+			if a.OnFinish != nil {
+				a.OnFinish()
+			}
+			return
+		}
+		a.currIndex++
+	case PlayPingPong:
+		if last == 0 {
+			break
+		}
+		if a.currIndex+a.direction > last || a.currIndex+a.direction < 0 {
+			a.direction = -a.direction
+		}
+		a.currIndex += a.direction
+		if a.currIndex == 0 && a.OnLoop != nil {
+			a.OnLoop()
+		}
+	case PlayReverse:
+		a.currIndex--
+		if a.currIndex < 0 {
+			a.currIndex = last
+			if a.OnLoop != nil {
+				a.OnLoop()
+			}
+		}
+	default:
+		a.currIndex++
+		if a.currIndex > last {
+			a.currIndex = 0
+			if a.OnLoop != nil {
+				a.OnLoop()
+			}
+		}
+	}
+
+	if a.currIndex != prev {
+		a.Changed = true
+	}
+}
+
+func (a *Animation) duration(i int) time.Duration {
+	if i < len(a.durations) {
+		return a.durations[i]
+	}
+	return 0
+}
+
+// Sets how long frame i is shown for
+func (a *Animation) SetFrameDuration(i int, d time.Duration) {
+	if i >= 0 && i < len(a.durations) {
+		a.durations[i] = d
+	}
+}
+
+// True once a PlayOnce animation has shown its last frame for its full duration
+func (a *Animation) Finished() bool {
+	return a.finished
+}
+
+// Index of the frame currently being shown
+func (a *Animation) FrameIndex() int {
+	return a.currIndex
+}
+
+func (a *Animation) Frames() int {
+	return len(a.textures)
+}
+
+// The frame being shown, or an empty Texture if the animation has no frames
+func (a *Animation) Frame() Texture {
+	a.Changed = false
+	if a.currIndex >= len(a.textures) {
+		return Texture{}
+	}
+	return a.textures[a.currIndex]
+}
+
+// Where the current frame sits within the untrimmed sprite, as fractions {x, y, w, h}.
+// Pass it to Sprite.SetTrimmedTexture along with Frame
+func (a *Animation) Trim() mgl32.Vec4 {
+	if a.currIndex < len(a.trims) {
+		return a.trims[a.currIndex]
+	}
+	return mgl32.Vec4{0, 0, 1, 1}
+}
@@ -0,0 +1,253 @@
+package engine
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Frames told apart by their u coordinate
+func testFrames(n int) []Texture {
+	textures := make([]Texture, n)
+	for i := range textures {
+		textures[i] = Texture{texCoords: mgl32.Vec4{float32(i), 0, 0, 0}}
+	}
+	return textures
+}
+
+const frameTime = 125 * time.Millisecond // exact in float seconds, so updates land on frame boundaries
+
+func TestAnimationModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     PlayMode
+		frames   int
+		want     []int // frame index after each update of one frame
+		loops    int
+		finishes int
+	}{
+		{"loop", PlayLoop, 3, []int{1, 2, 0, 1, 2, 0}, 2, 0},
+		{"once", PlayOnce, 3, []int{1, 2, 2, 2}, 0, 1},
+		{"ping pong", PlayPingPong, 3, []int{1, 2, 1, 0, 1, 2, 1, 0}, 2, 0},
+		{"reverse", PlayReverse, 3, []int{1, 0, 2, 1, 0, 2}, 2, 0},
+		{"single frame loop", PlayLoop, 1, []int{0, 0}, 2, 0},
+		{"single frame ping pong", PlayPingPong, 1, []int{0, 0}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			durations := make([]time.Duration, tt.frames)
+			for i := range durations {
+				durations[i] = frameTime
+			}
+			a := NewAnimationFromFrames(testFrames(tt.frames), durations, tt.mode)
+			loops, finishes := 0, 0
+			a.OnLoop = func() { loops++ }
+			a.OnFinish = func() { finishes++ }
+			a.Play()
+
+			got := []int{}
+			for range tt.want {
+				a.Update(float32(frameTime.Seconds()))
+				got = append(got, a.FrameIndex())
+				if u := a.Frame().texCoords[0]; int(u) != a.FrameIndex() {
+					t.Fatalf("Frame is texture %v on frame %d", u, a.FrameIndex())
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("frames were %v, want %v", got, tt.want)
+			}
+			if loops != tt.loops || finishes != tt.finishes {
+				t.Errorf("looped %d and finished %d times, want %d and %d", loops, finishes, tt.loops, tt.finishes)
+			}
+			if a.Finished() != (tt.finishes > 0) || a.IsPlaying == a.Finished() {
+				t.Errorf("finished %v and playing %v", a.Finished(), a.IsPlaying)
+			}
+		})
+	}
+}
+
+func TestAnimationStartsAtTheEndInReverse(t *testing.T) {
+	a := NewAnimationFromFrames(testFrames(4), []time.Duration{frameTime, frameTime, frameTime, frameTime}, PlayReverse)
+	a.Play()
+	if a.FrameIndex() != 3 || !a.Changed {
+		t.Errorf("reverse animation started on frame %d, changed %v", a.FrameIndex(), a.Changed)
+	}
+}
+
+func TestAnimationFrameDurations(t *testing.T) {
+	tests := []struct {
+		delta     time.Duration // one update from the start
+		want      int
+		loops     int
+		remaining time.Duration // carried into the next frame
+	}{
+		{0, 0, 0, 0},
+		{frameTime / 2, 0, 0, frameTime / 2},
+		{frameTime, 1, 0, 0},
+		{3 * frameTime, 2, 0, 0},
+		{6 * frameTime, 2, 0, 3 * frameTime},
+		{7 * frameTime, 0, 1, 0},
+		{8 * frameTime, 1, 1, 0}, // several frames in one update
+	}
+	for _, tt := range tests {
+		a := NewAnimationFromFrames(testFrames(3), []time.Duration{frameTime, 2 * frameTime, 4 * frameTime}, PlayLoop)
+		loops := 0
+		a.OnLoop = func() { loops++ }
+		a.Play()
+		a.Update(float32(tt.delta.Seconds()))
+		if a.FrameIndex() != tt.want || loops != tt.loops || a.elapsed != tt.remaining {
+			t.Errorf("after %v on frame %d with %v left and %d loops, want frame %d with %v and %d", tt.delta, a.FrameIndex(), a.elapsed, loops, tt.want, tt.remaining, tt.loops)
+		}
+	}
+
+	a := NewAnimationFromFrames(testFrames(2), []time.Duration{frameTime, frameTime}, PlayLoop)
+	a.SetFrameDuration(0, 2*frameTime)
+	a.SetFrameDuration(5, frameTime) // out of range, ignored
+	a.Play()
+	a.Update(float32(frameTime.Seconds()))
+	if a.FrameIndex() != 0 {
+		t.Errorf("lengthened frame ended after %v", frameTime)
+	}
+}
+
+func TestAnimationStopAndPlay(t *testing.T) {
+	a := NewAnimationFromFrames(testFrames(3), []time.Duration{frameTime, frameTime, frameTime}, PlayLoop)
+	a.Update(float32(frameTime.Seconds()))
+	if a.FrameIndex() != 0 {
+		t.Error("animation advanced before Play")
+	}
+	a.Play()
+	a.Update(float32(frameTime.Seconds()))
+	a.Stop()
+	a.Update(float32(frameTime.Seconds()))
+	if a.FrameIndex() != 1 || a.IsPlaying {
+		t.Errorf("stopped animation moved to frame %d", a.FrameIndex())
+	}
+	a.Play()
+	if a.FrameIndex() != 0 || !a.IsPlaying {
+		t.Errorf("Play restarted on frame %d", a.FrameIndex())
+	}
+}
+
+func TestEmptyAnimation(t *testing.T) {
+	for _, mode := range []PlayMode{PlayLoop, PlayOnce, PlayPingPong, PlayReverse} {
+		a := NewAnimationFromFrames(nil, nil, mode)
+		a.Play()
+		a.Update(1)
+		if a.IsPlaying || a.FrameIndex() != 0 || a.Frame() != (Texture{}) {
+			t.Errorf("mode %v: empty animation is playing %v on frame %d", mode, a.IsPlaying, a.FrameIndex())
+		}
+	}
+
+	animator := NewAnimator()
+	animator.Add(NewAnimationFromFrames(nil, nil, PlayReverse), "empty")
+	animator.Trigger("empty")
+	animator.Update(1)
+	if animator.Frame() != (Texture{}) {
+		t.Error("animator has a frame from an empty animation")
+	}
+}
+
+func TestAnimationMismatchedDurations(t *testing.T) {
+	tests := []struct {
+		name      string
+		frames    int
+		durations []time.Duration
+		want      int
+	}{
+		{"more frames", 3, []time.Duration{frameTime, frameTime}, 2},
+		{"more durations", 1, []time.Duration{frameTime, frameTime, frameTime}, 1},
+		{"no durations", 2, nil, 0},
+	}
+	for _, tt := range tests {
+		a := NewAnimationFromFrames(testFrames(tt.frames), tt.durations, PlayLoop)
+		if a.Frames() != tt.want || len(a.durations) != tt.want {
+			t.Errorf("%s: %d frames and %d durations, want %d of each", tt.name, a.Frames(), len(a.durations), tt.want)
+		}
+		a.Play()
+		for i := 0; i < 4; i++ {
+			a.Update(float32(frameTime.Seconds()))
+			a.Frame()
+		}
+	}
+}
+
+func TestAnimatorCallbacks(t *testing.T) {
+	animator := NewAnimator()
+	walk := NewAnimationFromFrames(testFrames(2), []time.Duration{frameTime, frameTime}, PlayLoop)
+	hit := NewAnimationFromFrames(testFrames(2), []time.Duration{frameTime, frameTime}, PlayOnce)
+	animator.Add(walk, "walk")
+	animator.Add(hit, "hit")
+	finished := []string{}
+	animator.OnFinish = func(name string) { finished = append(finished, name) }
+
+	animator.Trigger("walk")
+	for i := 0; i < 5; i++ {
+		animator.Update(float32(frameTime.Seconds()))
+	}
+	animator.Trigger("hit")
+	if walk.IsPlaying || animator.CurrentName() != "hit" {
+		t.Errorf("triggering hit left walk playing %v, current %q", walk.IsPlaying, animator.CurrentName())
+	}
+	for i := 0; i < 5; i++ {
+		animator.Update(float32(frameTime.Seconds()))
+	}
+	if !reflect.DeepEqual(finished, []string{"hit"}) {
+		t.Errorf("finished %v, want hit once", finished)
+	}
+
+	// triggering what's already playing doesn't restart it
+	animator.Trigger("walk")
+	animator.Update(float32(frameTime.Seconds()))
+	animator.Trigger("walk")
+	if walk.FrameIndex() != 1 {
+		t.Errorf("retriggering restarted walk on frame %d", walk.FrameIndex())
+	}
+	animator.Trigger("missing")
+	if animator.CurrentName() != "walk" {
+		t.Errorf("triggering a missing animation changed the current one to %q", animator.CurrentName())
+	}
+	animator.Stop()
+	animator.Update(1)
+	if walk.IsPlaying || animator.Current != nil {
+		t.Error("stopped animator is still playing")
+	}
+}
+
+// Animations only change in Update, so with -race this catches any background work touching them.
+// Each goroutine drives its own animations, the way separate scenes or workers would, sharing the sheet's textures
+func TestAnimationsOnlyChangeInUpdate(t *testing.T) {
+	before := runtime.NumGoroutine()
+	textures := testFrames(4)
+	durations := []time.Duration{frameTime, frameTime, frameTime, frameTime}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		animations := make([]*Animation, 25)
+		for i := range animations {
+			animations[i] = NewAnimationFromFrames(textures, durations, PlayMode(i%4))
+			animations[i].Play()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tick := 0; tick < 100; tick++ {
+				for _, a := range animations {
+					a.Update(float32(frameTime.Seconds()) / 3)
+					if a.Changed {
+						a.Frame()
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines left running after the animations were made and played", after-before)
+	}
+}
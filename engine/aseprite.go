@@ -0,0 +1,226 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// A spritesheet exported from Aseprite with "File > Export Sprite Sheet" and JSON data enabled.
+// Both the hash and array frame layouts are supported.
+type AsepriteSheet struct {
+	Animator Animator
+	Image    Image
+	Width    int // untrimmed size of a single frame
+	Height   int
+	Slices   map[string][]AsepriteSliceKey
+}
+
+// Slice bounds and pivot, in untrimmed frame pixels, from the given frame onwards
+type AsepriteSliceKey struct {
+	Frame    int
+	Bounds   mgl32.Vec4 // x, y, w, h
+	Pivot    mgl32.Vec2
+	HasPivot bool
+}
+
+type asepriteRect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type asepriteFrame struct {
+	Filename         string       `json:"filename"`
+	Frame            asepriteRect `json:"frame"`
+	Rotated          bool         `json:"rotated"`
+	Trimmed          bool         `json:"trimmed"`
+	SpriteSourceSize asepriteRect `json:"spriteSourceSize"`
+	SourceSize       asepriteRect `json:"sourceSize"`
+	Duration         int          `json:"duration"` // milliseconds
+}
+
+type asepriteFile struct {
+	Frames json.RawMessage `json:"frames"`
+	Meta   struct {
+		Image     string `json:"image"`
+		FrameTags []struct {
+			Name      string `json:"name"`
+			From      int    `json:"from"`
+			To        int    `json:"to"`
+			Direction string `json:"direction"`
+			Repeat    string `json:"repeat"`
+		} `json:"frameTags"`
+		Slices []struct {
+			Name string `json:"name"`
+			Keys []struct {
+				Frame  int           `json:"frame"`
+				Bounds asepriteRect  `json:"bounds"`
+				Pivot  *asepriteRect `json:"pivot"`
+			} `json:"keys"`
+		} `json:"slices"`
+	} `json:"meta"`
+}
+
+// Loads an Aseprite JSON export and the spritesheet image it references.
+// Every frame tag becomes a named animation on the Animator, untagged sheets get a single "default" animation.
+// If flipX is true all frames are mirrored horizontally.
+func LoadAseprite(path string, flipX bool) (*AsepriteSheet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file asepriteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing aseprite file %s: %w", path, err)
+	}
+
+	frames, err := parseAsepriteFrames(file.Frames)
+	if err != nil {
+		return nil, fmt.Errorf("parsing aseprite file %s: %w", path, err)
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("aseprite file %s has no frames", path)
+	}
+
+	img, err := NewImage(filepath.Join(filepath.Dir(path), file.Meta.Image))
+	if err != nil {
+		return nil, err
+	}
+
+	textures := make([]Texture, len(frames))
+	durations := make([]time.Duration, len(frames))
+	trims := make([]mgl32.Vec4, len(frames))
+	for i, f := range frames {
+		r := f.Frame
+		textures[i] = NewTextureFromAtlas(img, float32(r.X), float32(r.Y), float32(r.W), float32(r.H), flipX)
+		durations[i] = time.Duration(f.Duration) * time.Millisecond
+		trims[i] = f.trim(flipX)
+	}
+
+	sheet := &AsepriteSheet{
+		Animator: NewAnimator(),
+		Image:    img,
+		Width:    frames[0].SourceSize.W,
+		Height:   frames[0].SourceSize.H,
+		Slices:   make(map[string][]AsepriteSliceKey),
+	}
+
+	tags := file.Meta.FrameTags
+	if len(tags) == 0 {
+		anim := NewAnimationFromFrames(textures, durations, PlayLoop)
+		anim.trims = trims
+		sheet.Animator.Add(anim, "default")
+	}
+	for _, tag := range tags {
+		if tag.From < 0 || tag.To >= len(frames) || tag.From > tag.To {
+			return nil, fmt.Errorf("aseprite tag %s has invalid frames %d-%d", tag.Name, tag.From, tag.To)
+		}
+
+		mode := PlayLoop
+		switch tag.Direction {
+		case "reverse":
+			mode = PlayReverse
+		case "pingpong", "pingpong_reverse":
+			mode = PlayPingPong
+		}
+		if tag.Repeat == "1" && mode == PlayLoop {
+			mode = PlayOnce
+		}
+
+		// copy so animations can have their durations changed independently
+		from, to := tag.From, tag.To+1
+		anim := NewAnimationFromFrames(
+			append([]Texture{}, textures[from:to]...),
+			append([]time.Duration{}, durations[from:to]...),
+			mode,
+		)
+		anim.trims = append([]mgl32.Vec4{}, trims[from:to]...)
+		sheet.Animator.Add(anim, tag.Name)
+	}
+
+	for _, s := range file.Meta.Slices {
+		for _, k := range s.Keys {
+			key := AsepriteSliceKey{
+				Frame:  k.Frame,
+				Bounds: mgl32.Vec4{float32(k.Bounds.X), float32(k.Bounds.Y), float32(k.Bounds.W), float32(k.Bounds.H)},
+			}
+			if k.Pivot != nil {
+				key.Pivot = mgl32.Vec2{float32(k.Pivot.X), float32(k.Pivot.Y)}
+				key.HasPivot = true
+			}
+			sheet.Slices[s.Name] = append(sheet.Slices[s.Name], key)
+		}
+	}
+
+	return sheet, nil
+}
+
+// Returns the key for a slice that applies to the given frame
+func (s *AsepriteSheet) Slice(name string, frame int) (AsepriteSliceKey, bool) {
+	var found AsepriteSliceKey
+	ok := false
+	for _, k := range s.Slices[name] {
+		if k.Frame <= frame {
+			found = k
+			ok = true
+		}
+	}
+	return found, ok
+}
+
+// Where the trimmed frame sits in the untrimmed frame, as fractions {x, y, w, h}
+func (f asepriteFrame) trim(flipX bool) mgl32.Vec4 {
+	if !f.Trimmed || f.SourceSize.W == 0 || f.SourceSize.H == 0 {
+		return mgl32.Vec4{0, 0, 1, 1}
+	}
+	sw, sh := float32(f.SourceSize.W), float32(f.SourceSize.H)
+	r := f.SpriteSourceSize
+	x := float32(r.X) / sw
+	w := float32(r.W) / sw
+	if flipX {
+		x = 1 - x - w
+	}
+	return mgl32.Vec4{x, float32(r.Y) / sh, w, float32(r.H) / sh}
+}
+
+// Frames are either an array, or an object keyed by filename. The object order is the frame order,
+// so it is decoded token by token rather than into a map.
+func parseAsepriteFrames(raw json.RawMessage) ([]asepriteFrame, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	if raw[0] == '[' {
+		var frames []asepriteFrame
+		err := json.Unmarshal(raw, &frames)
+		return frames, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	frames := []asepriteFrame{}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var f asepriteFrame
+		if err := dec.Decode(&f); err != nil {
+			return nil, err
+		}
+		f.Filename, _ = key.(string)
+		frames = append(frames, f)
+	}
+	return frames, nil
+}
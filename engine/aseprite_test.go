@@ -0,0 +1,234 @@
+package engine
+
+import (
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestParseAsepriteFrames(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   string
+		names []string
+		rects []asepriteRect
+	}{
+		{
+			name:  "array",
+			raw:   `[{"filename": "a 0", "frame": {"x": 0, "y": 0, "w": 8, "h": 8}}, {"filename": "a 1", "frame": {"x": 8, "y": 0, "w": 8, "h": 8}}]`,
+			names: []string{"a 0", "a 1"},
+			rects: []asepriteRect{{0, 0, 8, 8}, {8, 0, 8, 8}},
+		},
+		{
+			name:  "hash keeps the file's order",
+			raw:   `{"z.ase": {"frame": {"x": 0, "y": 0, "w": 4, "h": 4}}, "a.ase": {"frame": {"x": 4, "y": 0, "w": 4, "h": 4}}, "m.ase": {"frame": {"x": 8, "y": 0, "w": 4, "h": 4}}}`,
+			names: []string{"z.ase", "a.ase", "m.ase"},
+			rects: []asepriteRect{{0, 0, 4, 4}, {4, 0, 4, 4}, {8, 0, 4, 4}},
+		},
+		{name: "empty array", raw: `[]`},
+		{name: "empty hash", raw: `{}`},
+		{name: "missing", raw: ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, err := parseAsepriteFrames(json.RawMessage(tt.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(frames) != len(tt.names) {
+				t.Fatalf("got %d frames, want %d", len(frames), len(tt.names))
+			}
+			for i, f := range frames {
+				if f.Filename != tt.names[i] || f.Frame != tt.rects[i] {
+					t.Errorf("frame %d is %q %v, want %q %v", i, f.Filename, f.Frame, tt.names[i], tt.rects[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseAsepriteFramesErrors(t *testing.T) {
+	for _, raw := range []string{`[{"frame": 1}]`, `{"a": {"frame": {"x": "one"}}}`, `{"a": `} {
+		if _, err := parseAsepriteFrames(json.RawMessage(raw)); err == nil {
+			t.Errorf("%s parsed without an error", raw)
+		}
+	}
+}
+
+func TestAsepriteFrameTrim(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame asepriteFrame
+		flipX bool
+		want  mgl32.Vec4
+	}{
+		{"untrimmed", asepriteFrame{SourceSize: asepriteRect{W: 16, H: 16}}, false, mgl32.Vec4{0, 0, 1, 1}},
+		{"trimmed", asepriteFrame{Trimmed: true, SpriteSourceSize: asepriteRect{4, 8, 8, 4}, SourceSize: asepriteRect{W: 16, H: 16}}, false, mgl32.Vec4{0.25, 0.5, 0.5, 0.25}},
+		{"trimmed and flipped", asepriteFrame{Trimmed: true, SpriteSourceSize: asepriteRect{0, 0, 4, 16}, SourceSize: asepriteRect{W: 16, H: 16}}, true, mgl32.Vec4{0.75, 0, 0.25, 1}},
+		{"no source size", asepriteFrame{Trimmed: true, SpriteSourceSize: asepriteRect{1, 1, 1, 1}}, false, mgl32.Vec4{0, 0, 1, 1}},
+	}
+	for _, tt := range tests {
+		if got := tt.frame.trim(tt.flipX); got != tt.want {
+			t.Errorf("%s: trim is %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+const asepriteTestMeta = `"meta": {
+	"image": "sheet.png",
+	"frameTags": [
+		{"name": "walk", "from": 0, "to": 2, "direction": "forward"},
+		{"name": "hit", "from": 3, "to": 3, "direction": "forward", "repeat": "1"},
+		{"name": "bob", "from": 1, "to": 2, "direction": "pingpong"}
+	],
+	"slices": [
+		{"name": "hitbox", "keys": [
+			{"frame": 0, "bounds": {"x": 2, "y": 2, "w": 4, "h": 6}},
+			{"frame": 2, "bounds": {"x": 1, "y": 2, "w": 6, "h": 6}, "pivot": {"x": 3, "y": 8}}
+		]}
+	]
+}`
+
+func TestLoadAseprite(t *testing.T) {
+	headless = true // images are decoded without a GL context
+	tests := []struct {
+		name   string
+		frames string
+	}{
+		{"array", `[
+			{"filename": "f0", "frame": {"x": 0, "y": 0, "w": 8, "h": 8}, "sourceSize": {"w": 8, "h": 8}, "duration": 100},
+			{"filename": "f1", "frame": {"x": 8, "y": 0, "w": 8, "h": 8}, "sourceSize": {"w": 8, "h": 8}, "duration": 100},
+			{"filename": "f2", "frame": {"x": 16, "y": 0, "w": 8, "h": 8}, "sourceSize": {"w": 8, "h": 8}, "duration": 150},
+			{"filename": "f3", "frame": {"x": 24, "y": 0, "w": 8, "h": 8}, "sourceSize": {"w": 8, "h": 8}, "duration": 50}
+		]`},
+		{"hash", `{
+			"f0": {"frame": {"x": 0, "y": 0, "w": 8, "h": 8}, "sourceSize": {"w": 8, "h": 8}, "duration": 100},
+			"f1": {"frame": {"x": 8, "y": 0, "w": 8, "h": 8}, "sourceSize": {"w": 8, "h": 8}, "duration": 100},
+			"f2": {"frame": {"x": 16, "y": 0, "w": 8, "h": 8}, "sourceSize": {"w": 8, "h": 8}, "duration": 150},
+			"f3": {"frame": {"x": 24, "y": 0, "w": 8, "h": 8}, "sourceSize": {"w": 8, "h": 8}, "duration": 50}
+		}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeAsepriteFiles(t, `{"frames": `+tt.frames+`, `+asepriteTestMeta+`}`)
+			sheet, err := LoadAseprite(path, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sheet.Width != 8 || sheet.Height != 8 {
+				t.Errorf("frame size is %dx%d, want 8x8", sheet.Width, sheet.Height)
+			}
+
+			anims := []struct {
+				name      string
+				mode      PlayMode
+				durations []time.Duration
+				firstU    float32
+			}{
+				{"walk", PlayLoop, []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 150 * time.Millisecond}, 0},
+				{"hit", PlayOnce, []time.Duration{50 * time.Millisecond}, 0.75},
+				{"bob", PlayPingPong, []time.Duration{100 * time.Millisecond, 150 * time.Millisecond}, 0.25},
+			}
+			for _, want := range anims {
+				a, ok := sheet.Animator.Get(want.name)
+				if !ok {
+					t.Errorf("no %s animation", want.name)
+					continue
+				}
+				if a.Mode != want.mode || !reflect.DeepEqual(a.durations, want.durations) {
+					t.Errorf("%s is mode %v with durations %v, want mode %v with %v", want.name, a.Mode, a.durations, want.mode, want.durations)
+				}
+				if u := a.textures[0].texCoords[0]; u != want.firstU {
+					t.Errorf("%s starts at u %v, want %v", want.name, u, want.firstU)
+				}
+			}
+			if _, ok := sheet.Animator.Get("default"); ok {
+				t.Error("tagged sheet has a default animation")
+			}
+
+			slices := []struct {
+				frame  int
+				bounds mgl32.Vec4
+				pivot  bool
+			}{
+				{0, mgl32.Vec4{2, 2, 4, 6}, false},
+				{1, mgl32.Vec4{2, 2, 4, 6}, false},
+				{3, mgl32.Vec4{1, 2, 6, 6}, true},
+			}
+			for _, want := range slices {
+				k, ok := sheet.Slice("hitbox", want.frame)
+				if !ok || k.Bounds != want.bounds || k.HasPivot != want.pivot {
+					t.Errorf("hitbox on frame %d is %+v, want bounds %v pivot %v", want.frame, k, want.bounds, want.pivot)
+				}
+			}
+			if _, ok := sheet.Slice("missing", 0); ok {
+				t.Error("found a slice that isn't in the file")
+			}
+		})
+	}
+}
+
+func TestLoadAsepriteUntagged(t *testing.T) {
+	headless = true // images are decoded without a GL context
+	path := writeAsepriteFiles(t, `{"frames": [
+		{"frame": {"x": 0, "y": 0, "w": 8, "h": 8}, "sourceSize": {"w": 8, "h": 8}, "duration": 100},
+		{"frame": {"x": 8, "y": 0, "w": 8, "h": 8}, "sourceSize": {"w": 8, "h": 8}, "duration": 100}
+	], "meta": {"image": "sheet.png"}}`)
+	sheet, err := LoadAseprite(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, ok := sheet.Animator.Get("default")
+	if !ok || a.Frames() != 2 || a.Mode != PlayLoop {
+		t.Errorf("untagged sheet should have a two frame looping default animation")
+	}
+}
+
+func TestLoadAsepriteErrors(t *testing.T) {
+	headless = true // images are decoded without a GL context
+	frame := `{"frame": {"x": 0, "y": 0, "w": 8, "h": 8}, "duration": 100}`
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"not json", `{"frames": `},
+		{"no frames", `{"frames": [], "meta": {"image": "sheet.png"}}`},
+		{"bad frames", `{"frames": "f0", "meta": {"image": "sheet.png"}}`},
+		{"missing image", `{"frames": [` + frame + `], "meta": {"image": "missing.png"}}`},
+		{"tag past the last frame", `{"frames": [` + frame + `], "meta": {"image": "sheet.png", "frameTags": [{"name": "a", "from": 0, "to": 1}]}}`},
+		{"backwards tag", `{"frames": [` + frame + `, ` + frame + `], "meta": {"image": "sheet.png", "frameTags": [{"name": "a", "from": 1, "to": 0}]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadAseprite(writeAsepriteFiles(t, tt.json), false); err == nil {
+				t.Error("loaded without an error")
+			}
+		})
+	}
+}
+
+// Writes the JSON and a blank 32x8 sheet.png next to it, returning the JSON's path
+func writeAsepriteFiles(t *testing.T, data string) string {
+	t.Helper()
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "sheet.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, 32, 8))); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "sheet.json")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
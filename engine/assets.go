@@ -0,0 +1,30 @@
+package engine
+
+import (
+	"embed"
+	"io"
+	"os"
+	"strings"
+)
+
+// Paths starting with this are read from the assets built into the engine, so the default theme
+// works whatever directory the game or its tests are run from
+const embeddedPrefix = "embed:"
+
+//go:embed assets
+var embeddedAssets embed.FS
+
+// Opens a file from disk, or from the built in assets
+func openAsset(path string) (io.ReadCloser, error) {
+	if name, ok := strings.CutPrefix(path, embeddedPrefix); ok {
+		return embeddedAssets.Open("assets/" + name)
+	}
+	return os.Open(path)
+}
+
+func readAsset(path string) ([]byte, error) {
+	if name, ok := strings.CutPrefix(path, embeddedPrefix); ok {
+		return embeddedAssets.ReadFile("assets/" + name)
+	}
+	return os.ReadFile(path)
+}
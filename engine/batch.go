@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Maximum number of quads in a single batched draw call
+const maxBatchQuads = 10000
+
+// Counts from the last rendered frame
+type RenderStats struct {
+	Items          int // scene items submitted with PushItem
+	DrawCalls      int // scene draw calls, including batches
+	Batches        int // batched draw calls
+	BatchedSprites int // sprites drawn as part of a batch
+}
+
+// Sprites sharing a texture are transformed on the CPU into one streaming vertex buffer
+// and drawn with a single call, instead of one call and three matrix uploads each.
+type spriteBatch struct {
+	vao      uint32
+	vbo      uint32
+	vertices []float32
+
+	image      Image
+	normals    Image
+	useNormals bool
+	quads      int
+
+	stats *RenderStats
+}
+
+func newSpriteBatch(stats *RenderStats) *spriteBatch {
+	b := &spriteBatch{
+		vertices: make([]float32, 0, maxBatchQuads*4*5),
+		stats:    stats,
+	}
+	if headless {
+		return b
+	}
+
+	// Index pattern is the same for every quad, so it is only uploaded once
+	indices := make([]uint32, 0, maxBatchQuads*6)
+	for q := uint32(0); q < maxBatchQuads; q++ {
+		o := q * 4
+		indices = append(indices, o, o+1, o+3, o+1, o+2, o+3)
+	}
+
+	var ebo uint32
+	gl.GenVertexArrays(1, &b.vao)
+	gl.GenBuffers(1, &b.vbo)
+	gl.GenBuffers(1, &ebo)
+
+	gl.BindVertexArray(b.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, b.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, 4*cap(b.vertices), nil, gl.STREAM_DRAW)
+
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, ebo)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, 4*len(indices), gl.Ptr(indices), gl.STATIC_DRAW)
+
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 5*4, nil)
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointerWithOffset(1, 2, gl.FLOAT, false, 5*4, 3*4)
+	gl.EnableVertexAttribArray(1)
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gl.BindVertexArray(0)
+	return b
+}
+
+// True if ri can be drawn in the same batch as what is already queued
+func (b *spriteBatch) accepts(ri renderItem) bool {
+	if b.quads == 0 {
+		return true
+	}
+	if b.quads >= maxBatchQuads || ri.image.id != b.image.id || ri.useNormals != b.useNormals {
+		return false
+	}
+	return !ri.useNormals || ri.normals.id == b.normals.id
+}
+
+// Queues a sprite, flushing first if it can't share the current batch
+func (b *spriteBatch) add(ri renderItem, view, projection mgl32.Mat4) {
+	if !b.accepts(ri) {
+		b.flush(view, projection)
+	}
+	if b.quads == 0 {
+		b.image = ri.image
+		b.normals = ri.normals
+		b.useNormals = ri.useNormals
+	}
+
+	b.vertices = appendTransformed(b.vertices, ri.vertices, GetMatrix(ri.transform))
+	b.quads++
+	b.stats.BatchedSprites++
+}
+
+// Draws everything queued in one call
+func (b *spriteBatch) flush(view, projection mgl32.Mat4) {
+	if b.quads == 0 {
+		return
+	}
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	b.image.Use()
+	objectShader.SetBool("useNormals", b.useNormals)
+	if b.useNormals {
+		gl.ActiveTexture(gl.TEXTURE1)
+		b.normals.Use()
+		gl.ActiveTexture(gl.TEXTURE0)
+	}
+	objectShader.loadUniforms(mgl32.Ident4(), view, projection)
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, b.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, 4*cap(b.vertices), nil, gl.STREAM_DRAW) // orphan the old buffer
+	gl.BufferSubData(gl.ARRAY_BUFFER, 0, 4*len(b.vertices), gl.Ptr(b.vertices))
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+
+	gl.BindVertexArray(b.vao)
+	gl.DrawElements(gl.TRIANGLES, int32(b.quads*6), gl.UNSIGNED_INT, nil)
+
+	b.stats.Batches++
+	b.stats.DrawCalls++
+	b.vertices = b.vertices[:0]
+	b.quads = 0
+}
+
+// Appends local space x, y, z, u, v vertices to dst, transformed into world space by model
+func appendTransformed(dst, vertices []float32, model mgl32.Mat4) []float32 {
+	for i := 0; i+4 < len(vertices); i += 5 {
+		p := model.Mul4x1(mgl32.Vec4{vertices[i], vertices[i+1], vertices[i+2], 1})
+		dst = append(dst, p[0], p[1], p[2], vertices[i+3], vertices[i+4])
+	}
+	return dst
+}
+
+// Counts the draw calls a list of items would be rendered with, batching consecutive sprites
+func countBatches(items []renderItem) RenderStats {
+	stats := RenderStats{Items: len(items)}
+	b := spriteBatch{stats: &stats}
+	for _, ri := range items {
+		if ri.vertices == nil {
+			if b.quads > 0 {
+				b.quads = 0
+				stats.Batches++
+				stats.DrawCalls++
+			}
+			stats.DrawCalls++
+			continue
+		}
+		if !b.accepts(ri) {
+			b.quads = 0
+			stats.Batches++
+			stats.DrawCalls++
+		}
+		if b.quads == 0 {
+			b.image, b.normals, b.useNormals = ri.image, ri.normals, ri.useNormals
+		}
+		b.quads++
+		stats.BatchedSprites++
+	}
+	if b.quads > 0 {
+		stats.Batches++
+		stats.DrawCalls++
+	}
+	return stats
+}
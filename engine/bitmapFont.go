@@ -0,0 +1,315 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// An AngelCode BMFont, read from a text, XML or binary .fnt file and its page images.
+// Glyphs are copied from the pages as they're drawn, and scaled from the size the font was made at
+type bitmapFont struct {
+	size       int // glyphs are drawn unscaled at this size
+	lineHeight int
+	base       int // from the top of a line to the baseline
+	pageCount  int // from the common line, 0 if it's missing
+	pageFiles  []string
+	pages      []*image.RGBA
+	chars      map[rune]bitmapChar
+	kerning    map[[2]rune]int
+}
+
+type bitmapChar struct {
+	rect    image.Rectangle // in its page
+	offset  image.Point     // of rect from the pen position at the top of the line
+	advance int
+	page    int
+	channel int // bits for the channels the glyph is in: 1 blue, 2 green, 4 red, 8 alpha. 15 is a coloured glyph
+}
+
+// Page ids are bytes in the binary format, so no font has more
+const maxBitmapPages = 256
+
+// Attributes of a line in a text .fnt file, or an element in an XML one
+type bitmapAttrs map[string]string
+
+func (a bitmapAttrs) int(key string) int {
+	v, _ := strconv.Atoi(a[key])
+	return v
+}
+
+// True for the start of a .fnt file in any of the BMFont formats
+func isBitmapFont(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("BMF")) ||
+		bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) ||
+		bytes.HasPrefix(data, []byte("info "))
+}
+
+func loadBitmapFont(path string, data []byte) (*Font, error) {
+	b := &bitmapFont{
+		chars:   make(map[rune]bitmapChar),
+		kerning: make(map[[2]rune]int),
+	}
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("BMF")):
+		err = b.parseBinary(data)
+	case bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")):
+		err = b.parseXML(data)
+	default:
+		err = b.parseText(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing bitmap font %s: %w", path, err)
+	}
+	if len(b.chars) == 0 {
+		return nil, fmt.Errorf("bitmap font %s has no characters", path)
+	}
+	if b.size <= 0 {
+		b.size = b.lineHeight
+	}
+	if b.size <= 0 {
+		return nil, fmt.Errorf("bitmap font %s has no size or line height", path)
+	}
+
+	// pages are relative to the .fnt file
+	for _, file := range b.pageFiles {
+		img, err := decodeImage(filepath.Join(filepath.Dir(path), file))
+		if err != nil {
+			return nil, fmt.Errorf("loading bitmap font page %s: %w", file, err)
+		}
+		b.pages = append(b.pages, toRGBA(img))
+	}
+	for id, c := range b.chars {
+		if c.rect.Empty() {
+			continue
+		}
+		if c.page < 0 || c.page >= len(b.pages) || !c.rect.In(b.pages[c.page].Bounds()) {
+			return nil, fmt.Errorf("bitmap font %s character %d is outside its page", path, id)
+		}
+	}
+
+	return &Font{
+		path:        path,
+		bitmap:      b,
+		sizes:       make(map[int]*fontSize),
+		glyphs:      make(map[glyphKey]*glyph),
+		renderDatas: make(map[textKey]*stringRenderItemSize),
+	}, nil
+}
+
+func decodeImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	return img, err
+}
+
+// Reads lines like: char id=65 x=0 y=0 width=8 height=8 ...
+func (b *bitmapFont) parseText(data []byte) error {
+	for _, line := range strings.Split(string(data), "\n") {
+		tag, attrs := parseBitmapLine(strings.TrimSpace(line))
+		if err := b.apply(tag, attrs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseBitmapLine(line string) (string, bitmapAttrs) {
+	tag, rest, _ := strings.Cut(line, " ")
+	attrs := make(bitmapAttrs)
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		if strings.HasPrefix(value, `"`) {
+			// quoted values, like file names, can have spaces
+			end := strings.IndexByte(value[1:], '"')
+			if end < 0 {
+				end = len(value) - 1
+			}
+			attrs[key] = value[1 : end+1]
+			rest = value[min(end+2, len(value)):]
+		} else {
+			value, rest, _ = strings.Cut(value, " ")
+			attrs[key] = value
+		}
+	}
+	return tag, attrs
+}
+
+func (b *bitmapFont) parseXML(data []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if el, ok := tok.(xml.StartElement); ok {
+			attrs := make(bitmapAttrs)
+			for _, a := range el.Attr {
+				attrs[a.Name.Local] = a.Value
+			}
+			if err := b.apply(el.Name.Local, attrs); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Adds a line of a text file, or an element of an XML file, to the font. Tags it doesn't use are ignored
+func (b *bitmapFont) apply(tag string, attrs bitmapAttrs) error {
+	switch tag {
+	case "info":
+		// negative sizes are matched to the character height rather than the cell height
+		b.size = absInt(attrs.int("size"))
+	case "common":
+		b.lineHeight = attrs.int("lineHeight")
+		b.base = attrs.int("base")
+		b.pageCount = attrs.int("pages")
+	case "page":
+		id := attrs.int("id")
+		if err := b.checkPage(id); err != nil {
+			return err
+		}
+		for len(b.pageFiles) <= id {
+			b.pageFiles = append(b.pageFiles, "")
+		}
+		b.pageFiles[id] = attrs["file"]
+	case "char":
+		x, y := attrs.int("x"), attrs.int("y")
+		b.chars[rune(attrs.int("id"))] = bitmapChar{
+			rect:    image.Rect(x, y, x+attrs.int("width"), y+attrs.int("height")),
+			offset:  image.Pt(attrs.int("xoffset"), attrs.int("yoffset")),
+			advance: attrs.int("xadvance"),
+			page:    attrs.int("page"),
+			channel: attrs.int("chnl"),
+		}
+	case "kerning":
+		b.kerning[[2]rune{rune(attrs.int("first")), rune(attrs.int("second"))}] = attrs.int("amount")
+	}
+	return nil
+}
+
+// Page ids have to be below the number of pages the common line gives, and the binary format's limit
+func (b *bitmapFont) checkPage(id int) error {
+	if id < 0 || id >= maxBitmapPages || (b.pageCount > 0 && id >= b.pageCount) {
+		return fmt.Errorf("page id %d is out of range", id)
+	}
+	return nil
+}
+
+// Reads the version 3 binary format: "BMF", the version, then blocks of a type byte, a length and the data
+func (b *bitmapFont) parseBinary(data []byte) error {
+	if len(data) < 4 || data[3] != 3 {
+		return fmt.Errorf("only version 3 of the binary format is supported")
+	}
+	le := binary.LittleEndian
+	for r := data[4:]; len(r) > 0; {
+		if len(r) < 5 {
+			return io.ErrUnexpectedEOF
+		}
+		kind, size := r[0], int(le.Uint32(r[1:5]))
+		if size > len(r)-5 {
+			return io.ErrUnexpectedEOF
+		}
+		block := r[5 : 5+size]
+		r = r[5+size:]
+
+		switch kind {
+		case 1: // info
+			if len(block) >= 2 {
+				b.size = absInt(int(int16(le.Uint16(block))))
+			}
+		case 2: // common
+			if len(block) >= 4 {
+				b.lineHeight = int(le.Uint16(block))
+				b.base = int(le.Uint16(block[2:]))
+			}
+			if len(block) >= 10 {
+				b.pageCount = int(le.Uint16(block[8:]))
+			}
+		case 3: // pages, null terminated file names
+			for _, name := range bytes.Split(bytes.TrimRight(block, "\x00"), []byte{0}) {
+				if err := b.checkPage(len(b.pageFiles)); err != nil {
+					return err
+				}
+				b.pageFiles = append(b.pageFiles, string(name))
+			}
+		case 4: // chars, 20 bytes each
+			for c := block; len(c) >= 20; c = c[20:] {
+				x, y := int(le.Uint16(c[4:])), int(le.Uint16(c[6:]))
+				b.chars[rune(le.Uint32(c))] = bitmapChar{
+					rect:    image.Rect(x, y, x+int(le.Uint16(c[8:])), y+int(le.Uint16(c[10:]))),
+					offset:  image.Pt(int(int16(le.Uint16(c[12:]))), int(int16(le.Uint16(c[14:])))),
+					advance: int(int16(le.Uint16(c[16:]))),
+					page:    int(c[18]),
+					channel: int(c[19]),
+				}
+			}
+		case 5: // kerning pairs, 10 bytes each
+			for k := block; len(k) >= 10; k = k[10:] {
+				b.kerning[[2]rune{rune(le.Uint32(k)), rune(le.Uint32(k[4:]))}] = int(int16(le.Uint16(k[8:])))
+			}
+		}
+	}
+	return nil
+}
+
+// A length in pixels at the font's size scaled to size
+func (b *bitmapFont) scaled(size, v int) int {
+	return int(math.Round(float64(v*size) / float64(b.size)))
+}
+
+// Copies a character out of its page. Returns nil if it has nothing to draw, and the offset from the pen position
+// on the baseline. Glyphs packed into one channel are made white, like TrueType ones
+func (b *bitmapFont) glyphImage(char rune) (*image.RGBA, image.Point) {
+	c, ok := b.chars[char]
+	if !ok || c.rect.Empty() {
+		return nil, image.Point{}
+	}
+	page := b.pages[c.page]
+	img := image.NewRGBA(image.Rect(0, 0, c.rect.Dx(), c.rect.Dy()))
+	channel := -1
+	for bit, i := range [4]int{2, 1, 0, 3} { // blue, green, red, alpha
+		if c.channel == 1<<bit {
+			channel = i
+		}
+	}
+	if channel < 0 {
+		draw.Draw(img, img.Bounds(), page, c.rect.Min, draw.Src)
+	} else {
+		mask := image.NewAlpha(img.Bounds())
+		for y := 0; y < c.rect.Dy(); y++ {
+			for x := 0; x < c.rect.Dx(); x++ {
+				mask.Pix[mask.PixOffset(x, y)] = page.Pix[page.PixOffset(c.rect.Min.X+x, c.rect.Min.Y+y)+channel]
+			}
+		}
+		draw.DrawMask(img, img.Bounds(), image.White, image.Point{}, mask, image.Point{}, draw.Src)
+	}
+	return img, image.Pt(c.offset.X, c.offset.Y-b.base)
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,278 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// The same two page font in each format: A on the first page in colour, B on the second packed into the red channel
+const bitmapTestText = `info face="Test" size=-8 bold=0
+common lineHeight=10 base=8 scaleW=16 scaleH=16 pages=2 packed=0
+page id=0 file="p0.png"
+page id=1 file="page one.png"
+chars count=3
+char id=65 x=0 y=0 width=4 height=6 xoffset=0 yoffset=2 xadvance=5 page=0 chnl=15
+char id=66 x=4 y=4 width=4 height=6 xoffset=1 yoffset=2 xadvance=6 page=1 chnl=4
+char id=32 x=0 y=0 width=0 height=0 xoffset=0 yoffset=0 xadvance=3 page=0 chnl=15
+kernings count=1
+kerning first=65 second=66 amount=-1
+`
+
+const bitmapTestXML = `<?xml version="1.0"?>
+<font>
+  <info face="Test" size="-8" bold="0"/>
+  <common lineHeight="10" base="8" scaleW="16" scaleH="16" pages="2" packed="0"/>
+  <pages>
+    <page id="0" file="p0.png"/>
+    <page id="1" file="page one.png"/>
+  </pages>
+  <chars count="3">
+    <char id="65" x="0" y="0" width="4" height="6" xoffset="0" yoffset="2" xadvance="5" page="0" chnl="15"/>
+    <char id="66" x="4" y="4" width="4" height="6" xoffset="1" yoffset="2" xadvance="6" page="1" chnl="4"/>
+    <char id="32" x="0" y="0" width="0" height="0" xoffset="0" yoffset="0" xadvance="3" page="0" chnl="15"/>
+  </chars>
+  <kernings count="1">
+    <kerning first="65" second="66" amount="-1"/>
+  </kernings>
+</font>
+`
+
+// Blocks of the binary format, laid out as the BMFont docs give them
+type (
+	bmfInfo struct {
+		Size                   int16
+		Bits, Charset          uint8
+		StretchH               uint16
+		AA, PadUp, PadR, PadDn uint8
+		PadL, SpaceH, SpaceV   uint8
+		Outline                uint8
+	}
+	bmfCommon struct {
+		LineHeight, Base, ScaleW, ScaleH, Pages uint16
+		Bits, Alpha, Red, Green, Blue           uint8
+	}
+	bmfChar struct {
+		ID                    uint32
+		X, Y, Width, Height   uint16
+		XOffset, YOffset, Adv int16
+		Page, Channel         uint8
+	}
+	bmfKerning struct {
+		First, Second uint32
+		Amount        int16
+	}
+)
+
+func bmfBlock(kind byte, fields ...any) []byte {
+	var data bytes.Buffer
+	for _, f := range fields {
+		if s, ok := f.(string); ok {
+			data.WriteString(s)
+		} else {
+			binary.Write(&data, binary.LittleEndian, f)
+		}
+	}
+	block := []byte{kind, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(block[1:], uint32(data.Len()))
+	return append(block, data.Bytes()...)
+}
+
+func bmfFile(blocks ...[]byte) string {
+	data := []byte("BMF\x03")
+	for _, b := range blocks {
+		data = append(data, b...)
+	}
+	return string(data)
+}
+
+var bitmapTestBinary = bmfFile(
+	bmfBlock(1, bmfInfo{Size: -8}, "Test\x00"),
+	bmfBlock(2, bmfCommon{LineHeight: 10, Base: 8, ScaleW: 16, ScaleH: 16, Pages: 2}),
+	bmfBlock(3, "p0.png\x00page one.png\x00"),
+	bmfBlock(4,
+		bmfChar{ID: 65, Width: 4, Height: 6, YOffset: 2, Adv: 5, Page: 0, Channel: 15},
+		bmfChar{ID: 66, X: 4, Y: 4, Width: 4, Height: 6, XOffset: 1, YOffset: 2, Adv: 6, Page: 1, Channel: 4},
+		bmfChar{ID: 32, Adv: 3, Channel: 15},
+	),
+	bmfBlock(5, bmfKerning{First: 65, Second: 66, Amount: -1}),
+)
+
+// Writes the .fnt and both pages to a directory, returning the .fnt's path
+func writeBitmapFont(t *testing.T, fnt string) string {
+	t.Helper()
+	dir := t.TempDir()
+	p0 := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	p1 := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 4; x++ {
+			p0.Set(x, y, color.RGBA{255, 0, 0, 255})
+			p1.Set(4+x, 4+y, color.RGBA{200, 10, 20, 255})
+		}
+	}
+	for name, img := range map[string]image.Image{"p0.png": p0, "page one.png": p1} {
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(file, img); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+	}
+	path := filepath.Join(dir, "test.fnt")
+	if err := os.WriteFile(path, []byte(fnt), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadBitmapFont(t *testing.T) {
+	headless = true // glyphs are packed without a GL context
+	want := map[rune]bitmapChar{
+		'A': {rect: image.Rect(0, 0, 4, 6), offset: image.Pt(0, 2), advance: 5, page: 0, channel: 15},
+		'B': {rect: image.Rect(4, 4, 8, 10), offset: image.Pt(1, 2), advance: 6, page: 1, channel: 4},
+		' ': {rect: image.Rect(0, 0, 0, 0), advance: 3, channel: 15},
+	}
+	tests := []struct {
+		name string
+		fnt  string
+	}{
+		{"text", bitmapTestText},
+		{"xml", bitmapTestXML},
+		{"binary", bitmapTestBinary},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := LoadFont(writeBitmapFont(t, tt.fnt))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := f.bitmap
+			if b == nil {
+				t.Fatal("loaded as a TrueType font")
+			}
+			if b.size != 8 || b.lineHeight != 10 || b.base != 8 {
+				t.Errorf("size %d, line height %d and base %d, want 8, 10 and 8", b.size, b.lineHeight, b.base)
+			}
+			if !reflect.DeepEqual(b.pageFiles, []string{"p0.png", "page one.png"}) || len(b.pages) != 2 {
+				t.Errorf("pages are %q, %d loaded", b.pageFiles, len(b.pages))
+			}
+			if !reflect.DeepEqual(b.chars, want) {
+				t.Errorf("chars are %+v, want %+v", b.chars, want)
+			}
+			if !reflect.DeepEqual(b.kerning, map[[2]rune]int{{'A', 'B'}: -1}) {
+				t.Errorf("kerning is %v", b.kerning)
+			}
+
+			// kerning and advances scale with the size drawn at
+			for _, size := range []int{8, 16} {
+				l := f.Layout(size, "AB A", TextOptions{})
+				xs := []float32{}
+				for _, g := range l.Glyphs {
+					xs = append(xs, g.Rect[0])
+				}
+				scale := float32(size / 8)
+				if wantXs := []float32{0, 4 * scale, 10 * scale, 13 * scale}; !reflect.DeepEqual(xs, wantXs) {
+					t.Errorf("at size %d glyphs are at %v, want %v", size, xs, wantXs)
+				}
+				if l.Lines[0].Height != 10*scale {
+					t.Errorf("at size %d lines are %v high, want %v", size, l.Lines[0].Height, 10*scale)
+				}
+			}
+		})
+	}
+}
+
+func TestBitmapGlyphImage(t *testing.T) {
+	path := writeBitmapFont(t, bitmapTestText)
+	f, err := LoadFont(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		char   rune
+		size   image.Point
+		pixel  color.RGBA
+		offset image.Point
+	}{
+		{'A', image.Pt(4, 6), color.RGBA{255, 0, 0, 255}, image.Pt(0, -6)},
+		{'B', image.Pt(4, 6), color.RGBA{200, 200, 200, 200}, image.Pt(1, -6)}, // white, premultiplied by the red channel
+	}
+	for _, tt := range tests {
+		img, offset := f.bitmap.glyphImage(tt.char)
+		if img == nil {
+			t.Errorf("%q has no image", tt.char)
+			continue
+		}
+		if img.Bounds().Size() != tt.size || img.RGBAAt(1, 1) != tt.pixel || offset != tt.offset {
+			t.Errorf("%q is %v with %v at offset %v, want %v with %v at %v", tt.char, img.Bounds().Size(), img.RGBAAt(1, 1), offset, tt.size, tt.pixel, tt.offset)
+		}
+	}
+	if img, _ := f.bitmap.glyphImage(' '); img != nil {
+		t.Error("space has an image")
+	}
+	if img, _ := f.bitmap.glyphImage('Z'); img != nil {
+		t.Error("missing character has an image")
+	}
+}
+
+func TestParseBitmapLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		tag   string
+		attrs bitmapAttrs
+	}{
+		{`char id=65 x=0`, "char", bitmapAttrs{"id": "65", "x": "0"}},
+		{`info face="Some Font" size=12`, "info", bitmapAttrs{"face": "Some Font", "size": "12"}},
+		{`page id=0 file="unterminated`, "page", bitmapAttrs{"id": "0", "file": "unterminated"}},
+		{`page id=0   file="a.png"  `, "page", bitmapAttrs{"id": "0", "file": "a.png"}},
+		{`chars`, "chars", bitmapAttrs{}},
+		{`common lineHeight=10 junk`, "common", bitmapAttrs{"lineHeight": "10"}},
+	}
+	for _, tt := range tests {
+		tag, attrs := parseBitmapLine(tt.line)
+		if tag != tt.tag || !reflect.DeepEqual(attrs, tt.attrs) {
+			t.Errorf("%q parsed as %q %v, want %q %v", tt.line, tag, attrs, tt.tag, tt.attrs)
+		}
+	}
+}
+
+func TestLoadBitmapFontErrors(t *testing.T) {
+	const common = "info size=8\ncommon lineHeight=10 base=8 pages=1\npage id=0 file=\"p0.png\"\n"
+	char := bmfBlock(4, bmfChar{ID: 65, Width: 4, Height: 6, Adv: 5})
+	tests := []struct {
+		name string
+		fnt  string
+	}{
+		{"binary header cut off", "BMF"},
+		{"binary version 2", "BMF\x02"},
+		{"binary block header cut off", "BMF\x03\x01\x02\x00"},
+		{"binary block past the end", "BMF\x03\x01\x10\x00\x00\x00\x08\x00"},
+		{"binary chars cut off", bmfFile(bmfBlock(1, bmfInfo{Size: 8}), bmfBlock(3, "p0.png\x00"), char[:len(char)-4])},
+		{"bad xml attribute", `<font><info size="8"/><char id="65" x=0 /></font>`},
+		{"unclosed xml", `<font><info size="8"/><chars>`},
+		{"no characters", common},
+		{"no size", "char id=65 x=0 y=0 width=4 height=6 xadvance=5 page=0\n"},
+		{"missing page", "info size=8\npage id=0 file=\"missing.png\"\nchar id=65 width=4 height=6 page=0\n"},
+		{"character outside its page", common + "char id=65 x=14 y=0 width=4 height=6 page=0\n"},
+		{"character on a page that isn't there", common + "char id=65 x=0 y=0 width=4 height=6 page=1\n"},
+		{"page id past the page count", common + "page id=1 file=\"p0.png\"\nchar id=65 width=4 height=6 page=0\n"},
+		{"negative page id", common + "page id=-1 file=\"p0.png\"\nchar id=65 width=4 height=6 page=0\n"},
+		{"xml page id past the binary limit", `<font><info size="8"/><page id="256" file="p0.png"/><char id="65" width="4" height="6"/></font>`},
+		{"binary pages past the page count", bmfFile(bmfBlock(1, bmfInfo{Size: 8}), bmfBlock(2, bmfCommon{LineHeight: 10, Pages: 1}), bmfBlock(3, "p0.png\x00p0.png\x00"), char)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadFont(writeBitmapFont(t, tt.fnt)); err == nil {
+				t.Error("loaded without an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package engine
+
+import (
+	"time"
+)
+
+const (
+	defaultTickRate = 60
+	defaultMaxFPS   = 120
+)
+
+// Provides wall clock time to a Clock. Swap in a ManualTime to drive the game deterministically
+type TimeSource interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type systemTime struct{}
+
+func (systemTime) Now() time.Time        { return time.Now() }
+func (systemTime) Sleep(d time.Duration) { time.Sleep(d) }
+
+// The real wall clock
+var SystemTime TimeSource = systemTime{}
+
+// A TimeSource that only moves when told to. Sleeping advances it instantly
+type ManualTime struct {
+	now time.Time
+}
+
+func NewManualTime(start time.Time) *ManualTime {
+	return &ManualTime{now: start}
+}
+
+func (m *ManualTime) Now() time.Time {
+	return m.now
+}
+
+func (m *ManualTime) Sleep(d time.Duration) {
+	m.now = m.now.Add(d)
+}
+
+func (m *ManualTime) Advance(d time.Duration) {
+	m.now = m.now.Add(d)
+}
+
+// The engine clock. Updates run on fixed ticks of real time, and every tick advances
+// game time by the tick delta multiplied by the time scale. Pausing stops game time
+// (animations, tilemaps, timers) while updates and input keep running, so pause menus still work.
+type Clock struct {
+	source    TimeSource
+	tickRate  int
+	tickDelta time.Duration
+	maxFPS    int
+
+	timeScale float64
+	paused    bool
+	steps     int // ticks still allowed to advance while paused
+
+	ticks    uint64
+	gameTime time.Duration
+	delta    time.Duration // game time advanced by the current tick
+
+	prev       time.Time
+	acc        time.Duration
+	frameStart time.Time
+
+	// FPS tracking
+	statStart time.Time
+	frames    int
+	updates   int
+	fps, ups  int
+
+	timers      []*clockTimer
+	timersDirty bool
+}
+
+// The engine clock used by the game loop, animations and input
+var Time = NewClock(SystemTime, defaultTickRate)
+
+func NewClock(source TimeSource, tickRate int) *Clock {
+	c := &Clock{
+		source:    source,
+		maxFPS:    defaultMaxFPS,
+		timeScale: 1,
+	}
+	c.SetTickRate(tickRate)
+	return c
+}
+
+func (c *Clock) SetSource(source TimeSource) {
+	c.source = source
+	c.start()
+}
+
+// Sets the number of fixed updates per second
+func (c *Clock) SetTickRate(tickRate int) {
+	if tickRate <= 0 {
+		tickRate = defaultTickRate
+	}
+	c.tickRate = tickRate
+	c.tickDelta = time.Second / time.Duration(tickRate)
+}
+
+func (c *Clock) TickRate() int {
+	return c.tickRate
+}
+
+// Real time between fixed updates
+func (c *Clock) TickDelta() time.Duration {
+	return c.tickDelta
+}
+
+// Caps the render rate, 0 disables the cap
+func (c *Clock) SetMaxFPS(fps int) {
+	c.maxFPS = fps
+}
+
+func (c *Clock) Pause() {
+	c.paused = true
+	c.steps = 0
+}
+
+func (c *Clock) Resume() {
+	c.paused = false
+	c.steps = 0
+}
+
+func (c *Clock) Paused() bool {
+	return c.paused
+}
+
+// Lets game time advance for the next n ticks while paused
+func (c *Clock) Step(n int) {
+	if c.paused {
+		c.steps += n
+	}
+}
+
+// Speeds up or slows down game time. 1 is normal speed
+func (c *Clock) SetTimeScale(scale float64) {
+	if scale < 0 {
+		scale = 0
+	}
+	c.timeScale = scale
+}
+
+func (c *Clock) TimeScale() float64 {
+	return c.timeScale
+}
+
+// Seconds of game time advanced by the current tick. 0 while paused
+func (c *Clock) Delta() float32 {
+	return float32(c.delta.Seconds())
+}
+
+// Total game time. Affected by pausing and the time scale
+func (c *Clock) Now() time.Duration {
+	return c.gameTime
+}
+
+// Number of fixed updates run so far
+func (c *Clock) Ticks() uint64 {
+	return c.ticks
+}
+
+// Unscaled time since the game started, in whole ticks. Keeps running while paused
+func (c *Clock) Uptime() time.Duration {
+	return time.Duration(c.ticks) * c.tickDelta
+}
+
+// Frames rendered in the last second
+func (c *Clock) FPS() int {
+	return c.fps
+}
+
+// Updates run in the last second
+func (c *Clock) UPS() int {
+	return c.ups
+}
+
+func (c *Clock) start() {
+	now := c.source.Now()
+	c.prev = now
+	c.frameStart = now
+	c.statStart = now
+	c.acc = 0
+}
+
+// Returns the number of ticks to run this frame, based on real time passed since the last frame
+func (c *Clock) frame() int {
+	now := c.source.Now()
+	c.frameStart = now
+	c.acc += now.Sub(c.prev)
+	c.prev = now
+
+	n := int(c.acc / c.tickDelta)
+	c.acc -= time.Duration(n) * c.tickDelta
+	return n
+}
+
+// Advances the clock by one fixed update, firing any timers that are due
+func (c *Clock) tick() {
+	c.ticks++
+	c.updates++
+
+	c.delta = 0
+	if !c.paused || c.steps > 0 {
+		if c.steps > 0 {
+			c.steps--
+		}
+		c.delta = time.Duration(float64(c.tickDelta) * c.timeScale)
+	}
+	c.gameTime += c.delta
+
+	for _, t := range c.timers {
+		for !t.stopped && t.period > 0 && c.gameTime >= t.next {
+			t.next += t.period
+			t.fn()
+		}
+	}
+
+	if c.timersDirty {
+		live := c.timers[:0]
+		for _, t := range c.timers {
+			if !t.stopped {
+				live = append(live, t)
+			}
+		}
+		c.timers = live
+		c.timersDirty = false
+	}
+}
+
+// Called after every rendered frame. Tracks FPS and sleeps if we are running faster than maxFPS
+func (c *Clock) endFrame() {
+	c.frames++
+
+	now := c.source.Now()
+	if now.Sub(c.statStart) >= time.Second {
+		c.fps, c.ups = c.frames, c.updates
+		c.frames, c.updates = 0, 0
+		c.statStart = now
+	}
+
+	if c.maxFPS > 0 {
+		target := time.Second / time.Duration(c.maxFPS)
+		if elapsed := now.Sub(c.frameStart); elapsed < target {
+			c.source.Sleep(target - elapsed)
+		}
+	}
+}
+
+// Like a time.Ticker, but runs on game time and fires from the update loop
+type clockTimer struct {
+	clock   *Clock
+	period  time.Duration
+	next    time.Duration
+	fn      func()
+	stopped bool
+}
+
+func (c *Clock) every(period time.Duration, fn func()) *clockTimer {
+	t := &clockTimer{
+		clock:  c,
+		period: period,
+		next:   c.gameTime + period,
+		fn:     fn,
+	}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Restarts the timer with a new period, counting from the current game time
+func (t *clockTimer) reset(period time.Duration) {
+	t.period = period
+	t.next = t.clock.gameTime + period
+}
+
+func (t *clockTimer) stop() {
+	t.stopped = true
+	t.clock.timersDirty = true
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockFrameTicks(t *testing.T) {
+	tests := []struct {
+		name     string
+		tickRate int
+		advances []time.Duration
+		want     []int // ticks run by each frame
+	}{
+		{"one tick", 60, []time.Duration{time.Second / 60}, []int{1}},
+		{"less than a tick", 60, []time.Duration{time.Millisecond}, []int{0}},
+		{"remainder carries over", 10, []time.Duration{150 * time.Millisecond, 50 * time.Millisecond}, []int{1, 1}},
+		{"long frame catches up", 10, []time.Duration{time.Second}, []int{10}},
+		{"bad tick rate uses the default", 0, []time.Duration{time.Second}, []int{defaultTickRate}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := NewManualTime(time.Unix(0, 0))
+			c := NewClock(src, tt.tickRate)
+			c.SetSource(src)
+			for i, d := range tt.advances {
+				src.Advance(d)
+				if got := c.frame(); got != tt.want[i] {
+					t.Errorf("frame %d ran %d ticks, want %d", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestClockGameTime(t *testing.T) {
+	tick := time.Second / 10
+	tests := []struct {
+		name      string
+		setup     func(c *Clock)
+		ticks     int
+		wantTime  time.Duration
+		wantDelta float32
+	}{
+		{"normal speed", func(c *Clock) {}, 3, 3 * tick, 0.1},
+		{"half speed", func(c *Clock) { c.SetTimeScale(0.5) }, 4, 2 * tick, 0.05},
+		{"negative scale stops time", func(c *Clock) { c.SetTimeScale(-1) }, 2, 0, 0},
+		{"paused", func(c *Clock) { c.Pause() }, 5, 0, 0},
+		{"stepped while paused", func(c *Clock) { c.Pause(); c.Step(2) }, 5, 2 * tick, 0},
+		{"step ignored while running", func(c *Clock) { c.Step(2); c.Pause() }, 3, 0, 0},
+		{"resumed", func(c *Clock) { c.Pause(); c.Resume() }, 2, 2 * tick, 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClock(NewManualTime(time.Unix(0, 0)), 10)
+			tt.setup(c)
+			for i := 0; i < tt.ticks; i++ {
+				c.tick()
+			}
+			if c.Now() != tt.wantTime {
+				t.Errorf("game time is %v, want %v", c.Now(), tt.wantTime)
+			}
+			if c.Delta() != tt.wantDelta {
+				t.Errorf("delta is %v, want %v", c.Delta(), tt.wantDelta)
+			}
+			if c.Ticks() != uint64(tt.ticks) || c.Uptime() != time.Duration(tt.ticks)*tick {
+				t.Errorf("ran %d ticks for %v of uptime, want %d", c.Ticks(), c.Uptime(), tt.ticks)
+			}
+		})
+	}
+}
+
+func TestClockTimers(t *testing.T) {
+	tests := []struct {
+		name   string
+		period time.Duration
+		ticks  int
+		stopAt int // stops the timer before this tick, 0 never stops it
+		want   int
+	}{
+		{"fires every period", 200 * time.Millisecond, 10, 0, 5},
+		{"fires every tick", 100 * time.Millisecond, 3, 0, 3},
+		{"shorter than a tick fires several times", 50 * time.Millisecond, 2, 0, 4},
+		{"stopped", 200 * time.Millisecond, 10, 5, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClock(NewManualTime(time.Unix(0, 0)), 10)
+			fired := 0
+			timer := c.every(tt.period, func() { fired++ })
+			for i := 1; i <= tt.ticks; i++ {
+				if i == tt.stopAt {
+					timer.stop()
+				}
+				c.tick()
+			}
+			if fired != tt.want {
+				t.Errorf("fired %d times, want %d", fired, tt.want)
+			}
+		})
+	}
+}
+
+func TestClockMaxFPSSleeps(t *testing.T) {
+	tests := []struct {
+		maxFPS  int
+		elapsed time.Duration
+		want    time.Duration // time passed once the frame ends
+	}{
+		{100, 4 * time.Millisecond, 10 * time.Millisecond},
+		{100, 15 * time.Millisecond, 15 * time.Millisecond},
+		{0, 4 * time.Millisecond, 4 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		src := NewManualTime(time.Unix(0, 0))
+		c := NewClock(src, 60)
+		c.SetSource(src)
+		c.SetMaxFPS(tt.maxFPS)
+		c.frame()
+		src.Advance(tt.elapsed)
+		c.endFrame()
+		if got := src.Now().Sub(time.Unix(0, 0)); got != tt.want {
+			t.Errorf("max fps %d after %v: frame ended at %v, want %v", tt.maxFPS, tt.elapsed, got, tt.want)
+		}
+	}
+}
@@ -1,28 +1,22 @@
 package engine
 
 import (
-	"fmt"
 	"log"
+	"math"
 	"runtime"
-	"time"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
 )
 
-const (
-	updatesPS   = 60
-	targetDelta = time.Second / updatesPS
-)
-
-type Scene interface {
-	Update()
-}
-
 type Game struct {
 	window *window
-	scene  Scene
 
-	quit bool
+	scenes     []Scene // bottom to top, only the top scene is updated
+	pending    []sceneOp
+	transition *sceneTransition
+
+	quit         bool
+	quitOnEscape bool
 }
 
 // Engine Systems
@@ -33,12 +27,17 @@ var UI *ui
 var ScreenW, ScreenH float32
 var dispW, dispH float32
 
-// setup the game
-func CreateGame(width, height float32) *Game {
+// setup the game. width and height are the logical resolution the game is drawn at,
+// see SetScaleMode for how it is fitted to the window
+func CreateGame(width, height float32, config WindowConfig) *Game {
 	runtime.LockOSThread()
 
+	if config.Width == 0 || config.Height == 0 {
+		config.Width, config.Height = int(width), int(height)
+	}
+
 	// Create GLFW window and input
-	win := createWindow(int(width), int(height))
+	win := createWindow(config)
 	Input = initInput()
 	Input.setWindow(win)
 
@@ -55,88 +54,113 @@ func CreateGame(width, height float32) *Game {
 	gl.ClearColor(0.5, 0.5, 1, 1)
 
 	Renderer = Renderer2DInit(width, height)
-	uifont, _ := LoadFont("res/ProggyClean.ttf")
+	UI = initUI()
 
-	UI = &ui{
-		font:  uifont,
-		input: Input,
-		skin:  NewTexture("res/ui9slice.png"),
+	dispW, dispH = win.getFramebuffer()
+	ScreenW, ScreenH = width, height
+	initViewport(width, height, config.ScaleMode)
+
+	return &Game{
+		window:       win,
+		quit:         false,
+		quitOnEscape: config.QuitOnEscape,
 	}
+}
 
-	dispW, dispH = win.getFramebuffer()
+// Sets up a game without a window or OpenGL context, for tests and build machines.
+// Renderer submissions are collected by a HeadlessRenderer, and are rasterized into
+// an image.RGBA after every frame if rasterize is true.
+func CreateHeadlessGame(width, height float32, rasterize bool) *Game {
+	headless = true
+	Input = initInput()
+
+	Renderer = HeadlessRenderer2DInit(width, height, rasterize)
+	UI = initUI()
+
+	dispW, dispH = width, height
 	ScreenW, ScreenH = width, height
+	initViewport(width, height, ScaleLetterbox)
 
 	return &Game{
-		window: win,
-		quit:   false,
+		quit: false,
 	}
 }
 
-func (g *Game) Run() {
-	// Track FPS
-	tick := time.NewTicker(time.Second * 1)
-	defer tick.Stop()
-	fps, ups := 0, 0 // increments on every render call
-
-	// sTicker pings every second, when it does print current fps and reset
-	go func() {
-		for range tick.C {
-			fmt.Printf("FPS: %d, UPS: %d\n", fps, ups)
-			fps = 0
-			ups = 0
-			tick.Reset(time.Second * 1)
-		}
-	}()
+func initUI() *ui {
+	theme := defaultTheme()
+	if err := theme.load(); err != nil {
+		panic(err)
+	}
 
-	prev := time.Now()
-	var acc float64 //LAG
+	return &ui{
+		input: Input,
+		theme: theme,
+		// not a uiPass, so the first Begin starts a UI even before the first update
+		pass:    math.MaxUint64,
+		idsUsed: make(map[uint64]int),
+	}
+}
+
+func (g *Game) Run() {
+	Time.start()
 
 	for {
-		dispW, dispH = g.window.getFramebuffer()
-		delta := time.Since(prev).Seconds()
-		prev = time.Now()
-		acc += delta
-
-		g.window.pollEvents()
-		for acc >= targetDelta.Seconds() {
-			g.update()
-			Input.update()
-			ups++
-			acc -= targetDelta.Seconds()
+		if g.window != nil {
+			dispW, dispH = g.window.getFramebuffer()
+			screen.update(dispW, dispH)
+			g.window.pollEvents()
+		}
+
+		for n := Time.frame(); n > 0; n-- {
+			g.tick()
 		}
 
 		// Rendering
+		g.draw()
 		Renderer.render()
-		g.window.redraw()
-
-		fps++
-
-		// Slow down if running too fast
-		if fps > 120 {
-			time.Sleep(time.Millisecond * 20)
+		if g.window != nil {
+			g.window.redraw()
 		}
+		Time.endFrame()
 
 		if g.quit {
-			g.window.close()
+			if g.window != nil {
+				g.window.close()
+			}
 			break
 		}
 	}
 
-	g.window.Terminate()
+	if g.window != nil {
+		g.window.Terminate()
+	}
 	runtime.UnlockOSThread()
 }
 
-func (g *Game) SetScene(s Scene) {
-	Input.pauseInput(time.Millisecond * 300)
-	g.scene = s
+// Runs a single update and render. Lets tests drive a headless game one frame at a time
+func (g *Game) Step() {
+	g.tick()
+	g.draw()
+	Renderer.render()
+}
+
+// Runs one fixed update
+func (g *Game) tick() {
+	Time.tick()
+	g.updateScenes()
+	g.update()
+	Input.update()
 }
 
 func (g *Game) update() {
-	if Input.KeyDown(KeyEscape) {
+	if g.quitOnEscape && Input.KeyDown(KeyEscape) {
 		g.Quit()
 		return
 	}
-	g.scene.Update()
+	uiPass++
+	if s := g.Scene(); s != nil {
+		s.Update()
+	}
 }
 
 func (g *Game) Quit() {
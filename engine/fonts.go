@@ -3,38 +3,84 @@ package engine
 import (
 	"bufio"
 	"image"
+	"image/draw"
 	"image/png"
 	"log"
 	"math"
 	"os"
+	"unicode"
 
-	"github.com/go-gl/gl/v4.1-core/gl"
-	"github.com/go-gl/gltext"
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/golang/freetype"
 	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 )
 
+// A TrueType or bitmap font. Glyphs are rasterized the first time they're drawn at each size and packed into
+// atlas pages shared by every size. Characters the font doesn't have come from its fallbacks
 type Font struct {
-	ttf           truetype.Font
-	glyphs        map[int][]glyph
-	atlas         map[int]Image
-	renderDatas   map[int]map[string]stringRenderItemSize
-	renderedSizes []int
+	path      string
+	ttf       truetype.Font
+	bitmap    *bitmapFont // set for BMFont fonts instead of ttf
+	fallbacks []*Font
+	sizes     map[int]*fontSize
+	sdf       bool
+
+	glyphs      map[glyphKey]*glyph
+	pages       []*glyphPage
+	retired     []retired
+	renderDatas map[textKey]*stringRenderItemSize
+	stats       FontStats
 }
 
+// Text drawn as one renderItem per atlas page and colour it uses
 type stringRenderItemSize struct {
-	ri   renderItem
-	size mgl32.Vec2
+	items []renderItem
+	vbos  []uint32
+	pages []int
+	marks []bool // whether markup set each item's colour
+	size  mgl32.Vec2
+	used  uint64 // uiPass it was last drawn in
 }
 
+// A rasterizer and line metrics for one size, in pixels
+type fontSize struct {
+	face     font.Face
+	height   int // of a line
+	baseline int // from the top of a line
+}
+
+type glyphKey struct {
+	size int
+	char rune
+}
+
+type glyph struct {
+	page    int             // -1 if there's nothing to draw, like for spaces
+	rect    image.Rectangle // in the page
+	offset  image.Point     // of the rect from the pen position on the baseline, in atlas pixels
+	scale   float32         // screen pixels per atlas pixel, for glyphs drawn from another size
+	advance int
+	font    *Font // in the fallback chain it came from, for kerning
+	char    rune
+	index   truetype.Index
+}
+
+// Loads a TrueType font, or an AngelCode BMFont .fnt file in the text, XML or binary format
 func LoadFont(path string) (*Font, error) {
-	data, err := os.ReadFile(path)
+	data, err := readAsset(path)
 	if err != nil {
 		log.Println("Error loading font: ", err)
 		return nil, err
 	}
+	if isBitmapFont(data) {
+		f, err := loadBitmapFont(path, data)
+		if err != nil {
+			log.Println("Error loading font: ", err)
+		}
+		return f, err
+	}
 
 	ttf, err := freetype.ParseFont(data)
 	if err != nil {
@@ -43,90 +89,151 @@ func LoadFont(path string) (*Font, error) {
 	}
 
 	return &Font{
-		ttf:           *ttf,
-		glyphs:        make(map[int][]glyph),
-		atlas:         make(map[int]Image),
-		renderDatas:   make(map[int]map[string]stringRenderItemSize),
-		renderedSizes: []int{},
+		path:        path,
+		ttf:         *ttf,
+		sizes:       make(map[int]*fontSize),
+		glyphs:      make(map[glyphKey]*glyph),
+		renderDatas: make(map[textKey]*stringRenderItemSize),
 	}, nil
 }
 
-func (f *Font) genNewFontSize(size int) {
-	low, high := rune(32), rune(127)
-	glyphs := make([]glyph, high-low+1)
-
-	gc := int32(len(glyphs))
-	glyphsPerRow := int32(16)
-	glyphsPerCol := (gc / glyphsPerRow) + 1
-
-	gb := f.ttf.Bounds(fixed.Int26_6(size))
-	gw := int32(gb.Max.X - gb.Min.X)
-	gh := int32((gb.Max.Y - gb.Min.Y) + 5)
-	iw := gltext.Pow2(uint32(gw * glyphsPerRow))
-	ih := gltext.Pow2(uint32(gh * glyphsPerCol))
-
-	rect := image.Rect(0, 0, int(iw), int(ih))
-	img := image.NewRGBA(rect)
-
-	c := freetype.NewContext()
-	c.SetDPI(72)
-	c.SetFont(&f.ttf)
-	c.SetFontSize(float64(size))
-	c.SetClip(img.Bounds())
-	c.SetDst(img)
-	c.SetSrc(image.White)
-
-	var gi int
-	var gx, gy int32
-
-	for ch := low; ch <= high; ch++ {
-		index := f.ttf.Index(ch)
-		metric := f.ttf.HMetric(fixed.Int26_6(size), index)
+// Sets the fonts characters are taken from, in order, when this font doesn't have them.
+// Characters none of them have are drawn as '?'
+func (f *Font) SetFallbacks(fonts ...*Font) {
+	f.fallbacks = fonts
+	f.clearGlyphs()
+}
 
-		glyphs[gi].advance = int(metric.AdvanceWidth)
-		glyphs[gi].x = int(gx)
-		glyphs[gi].y = int(gy) - int(gh)/2
-		glyphs[gi].width = int(gw)
-		glyphs[gi].height = int(gh)
+// Empties the atlas, for when glyphs would be drawn differently
+func (f *Font) clearGlyphs() {
+	for _, p := range f.pages {
+		f.retireImage(p.image)
+	}
+	f.pages = nil
+	f.glyphs = make(map[glyphKey]*glyph)
+}
 
-		pt := freetype.Pt(int(gx), int(gy)+int(c.PointToFixed(float64(size))>>8))
-		c.DrawString(string(ch), pt)
+func (f *Font) atSize(size int) *fontSize {
+	if s, ok := f.sizes[size]; ok {
+		return s
+	}
+	if b := f.bitmap; b != nil {
+		s := &fontSize{height: b.scaled(size, b.lineHeight), baseline: b.scaled(size, b.base)}
+		f.sizes[size] = s
+		return s
+	}
+	// the bounding box of every glyph, plus some space between lines
+	const leading = 5
+	b := f.ttf.Bounds(fixed.Int26_6(size))
+	s := &fontSize{
+		face:     truetype.NewFace(&f.ttf, &truetype.Options{Size: float64(size), DPI: 72}),
+		height:   int(b.Max.Y-b.Min.Y) + leading,
+		baseline: int(b.Max.Y) + leading/2,
+	}
+	f.sizes[size] = s
+	return s
+}
 
-		if gi%16 == 0 {
-			gx = 0
-			gy += gh
-		} else {
-			gx += gw
+// Returns the font in the fallback chain that has char, and the character to draw
+func (f *Font) fontFor(char rune) (*Font, rune) {
+	if char < 32 {
+		return f, '?'
+	}
+	if f.has(char) {
+		return f, char
+	}
+	for _, fb := range f.fallbacks {
+		// SDF glyphs are made from outlines, which bitmap fonts don't have
+		if fb.has(char) && !(f.sdf && fb.bitmap != nil) {
+			return fb, char
 		}
-		gi++
 	}
+	return f, '?'
+}
 
-	//== Build up OpenGL texture data
-	img = gltext.Pow2Image(img).(*image.RGBA)
-	ib := img.Bounds()
-	texWidth, texHeight := float32(ib.Dx()), float32(ib.Dy())
+func (f *Font) has(char rune) bool {
+	if f.bitmap != nil {
+		_, ok := f.bitmap.chars[char]
+		return ok
+	}
+	return f.ttf.Index(char) != 0
+}
+
+// Advance in pixels of char at size
+func (f *Font) advance(size int, char rune) int {
+	if f.bitmap != nil {
+		return f.bitmap.scaled(size, f.bitmap.chars[char].advance)
+	}
+	return int(f.ttf.HMetric(fixed.Int26_6(size), f.ttf.Index(char)).AdvanceWidth)
+}
 
-	var tex uint32
-	gl.GenTextures(1, &tex)
-	gl.BindTexture(gl.TEXTURE_2D, tex)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
-	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, int32(texWidth), int32(texHeight), 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+// Returns the glyph for a character at a size, rasterizing it into the atlas if it's new
+func (f *Font) glyph(size int, char rune) *glyph {
+	key := glyphKey{size, char}
+	g, ok := f.glyphs[key]
+	if !ok {
+		g = f.rasterize(size, char)
+		f.glyphs[key] = g
+	}
+	if g.page >= 0 {
+		f.pages[g.page].used = uiPass
+	}
+	return g
+}
 
-	atlas := Image{
-		id:     tex,
-		width:  texWidth,
-		height: texHeight,
+func (f *Font) rasterize(size int, char rune) *glyph {
+	src, char := f.fontFor(char)
+	g := &glyph{
+		page:    -1,
+		scale:   1,
+		advance: src.advance(size, char),
+		font:    src,
+		char:    char,
+	}
+	if src.bitmap == nil {
+		g.index = src.ttf.Index(char)
 	}
 
-	for i, glyph := range glyphs {
-		glyphs[i].texture = NewTextureFromAtlas(atlas, float32(glyph.x), float32(glyph.y), float32(glyph.width), float32(glyph.height), false)
+	// SDF and bitmap glyphs are drawn at one size, and scaled to the others
+	native := size
+	if src.bitmap != nil {
+		native = src.bitmap.size
+	} else if f.sdf {
+		native = sdfSize
+	}
+	if size != native {
+		scaled := *f.glyph(native, char)
+		scaled.advance = g.advance
+		scaled.scale = float32(size) / float32(native)
+		return &scaled
 	}
 
-	f.glyphs[size] = glyphs
-	f.atlas[size] = atlas
-	f.renderDatas[size] = make(map[string]stringRenderItemSize)
-	f.renderedSizes = append(f.renderedSizes, size)
+	var img *image.RGBA
+	var offset image.Point
+	switch {
+	case src.bitmap != nil:
+		img, offset = src.bitmap.glyphImage(char)
+	case f.sdf:
+		img, offset = f.rasterizeSDF(src, char)
+	default:
+		dr, mask, maskp, _, ok := src.atSize(size).face.Glyph(fixed.Point26_6{}, char)
+		if ok && !dr.Empty() {
+			img = image.NewRGBA(image.Rect(0, 0, dr.Dx(), dr.Dy()))
+			draw.DrawMask(img, img.Bounds(), image.White, image.Point{}, mask, maskp, draw.Src)
+			offset = dr.Min
+		}
+	}
+	if img == nil {
+		return g
+	}
+	f.stats.GlyphsRasterized++
+	g.page, g.rect = f.pack(img.Rect.Dx(), img.Rect.Dy())
+	g.offset = offset
+	page := f.pages[g.page]
+	draw.Draw(page.img, g.rect, img, image.Point{}, draw.Src)
+	page.dirty = page.dirty.Union(g.rect)
+	page.used = uiPass
+	return g
 }
 
 func toPNG(img image.Image) {
@@ -150,95 +257,174 @@ func toPNG(img image.Image) {
 	}
 }
 
+// True if the font, or one of its fallbacks, has a glyph for char
 func (f *Font) isPrintable(char rune) bool {
-	if len(f.renderedSizes) == 0 {
+	if !unicode.IsPrint(char) {
 		return false
 	}
-	if int(char)-32 < len(f.glyphs[f.renderedSizes[0]]) {
-		return true
+	_, drawn := f.fontFor(char)
+	return drawn == char
+}
+
+// Height in pixels of a line of text at size
+func (f *Font) lineHeight(size int) float32 {
+	return float32(f.atSize(size).height)
+}
+
+// Kerning in pixels between two glyphs from this font
+func (f *Font) kern(size int, a, b *glyph) int {
+	if f.bitmap != nil {
+		return f.bitmap.scaled(size, f.bitmap.kerning[[2]rune{a.char, b.char}])
+	}
+	return int(f.ttf.Kern(fixed.Int26_6(size), a.index, b.index))
+}
+
+// Returns the x offset of every caret position in str, from before the first character to after the last
+func (f *Font) caretPositions(size int, str []rune) []float32 {
+	positions := make([]float32, len(str)+1)
+	var prev textItem
+	for i, char := range str {
+		it := textItem{char: char, size: size, glyph: f.glyph(size, char)}
+		positions[i+1] = positions[i] + float32(it.glyph.advance)
+		if i > 0 {
+			// kerning moves the character, so the caret before it too
+			kern := f.kerning(prev, it)
+			positions[i] += kern
+			positions[i+1] += kern
+		}
+		prev = it
 	}
-	return false
+	return positions
+}
+
+// Returns the width and height in pixels of str drawn at size
+func (f *Font) Measure(size int, str string) mgl32.Vec2 {
+	return f.Layout(size, str, TextOptions{}).Size
 }
 
-// Returns the renderItem to be drawn, and the width in pixels from left edge to right edge
+// Returns the renderItems to draw str at x, y, and the size in pixels of the text
 func (f *Font) renderItem(x, y float32, size int, str string) stringRenderItemSize {
-	// Check if we have already rendered font atlas for the desired size
-	_, ok := f.atlas[size]
-	if !ok {
-		f.genNewFontSize(size)
-		println("Gen size: ", size)
-	}
-
-	// Use existing renderItem
-	rd, ok := f.renderDatas[size][str]
-	if ok {
-		rd.ri.transform = NewTransform(x, y, 9)
-		return rd
-	}
-
-	// Create new renderItem
-	vertices := make([]float32, 0, 5*4*len(str))
-	indices := make([]uint32, 0, 6*len(str))
-	offset := uint32(0)
-	currentX := float32(0)
-	currentY := float32(0)
-	maxHeight := 0.0
-
-	for _, v := range str {
-		g := f.glyphs[size][v-32]
-		maxHeight = math.Max(maxHeight, float64(g.height))
-		vertices = append(vertices,
-			currentX, currentY, 0, g.texture.texCoords[0], g.texture.texCoords[2],
-			currentX+float32(g.width-1), currentY, 0, g.texture.texCoords[1], g.texture.texCoords[2],
-			currentX+float32(g.width-1), currentY+float32(g.height-1), 0, g.texture.texCoords[1], g.texture.texCoords[3],
-			currentX, currentY+float32(g.height-1), 0, g.texture.texCoords[0], g.texture.texCoords[3],
-		)
-		currentX += float32(g.advance)
+	return f.renderText(x, y, size, str, TextOptions{})
+}
 
-		indices = append(indices,
-			0+offset, 1+offset, 3+offset,
-			1+offset, 2+offset, 3+offset,
-		)
-		offset += 4
-	}
+// Lays out text and returns the renderItems to draw it at x, y, one for each atlas page and colour it uses
+func (f *Font) renderText(x, y float32, size int, str string, opts TextOptions) stringRenderItemSize {
+	f.releaseRetired()
 
-	var vbo, vao, ebo uint32
-	gl.GenVertexArrays(1, &vao)
-	gl.GenBuffers(1, &vbo)
-	gl.GenBuffers(1, &ebo)
+	// Use existing renderItems. Styles are uniforms, so don't change them
+	opts.Style = TextStyle{}
+	key := textKey{size, str, opts}
+	if rd, ok := f.cachedText(key); ok {
+		return rd.at(x, y)
+	}
 
-	gl.BindVertexArray(vao)
-	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
-	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.STATIC_DRAW)
+	// Rasterizes any new glyphs first, as pages growing moves the others' texture coordinates
+	layout := f.Layout(size, str, opts)
+	for _, p := range f.pages {
+		p.upload()
+	}
 
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, ebo)
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(indices)*4, gl.Ptr(indices), gl.STATIC_DRAW)
+	// One batch of quads per page and colour, and scale for SDF glyphs
+	type batch struct {
+		page     int
+		colour   mgl32.Vec4
+		coloured bool
+		scale    float32
+		vertices []float32
+		indices  []uint32
+	}
+	var batches []*batch
+	for _, tg := range layout.Glyphs {
+		g := tg.glyph
+		if g.page < 0 {
+			continue
+		}
+		scale := g.scale
+		var b *batch
+		for _, existing := range batches {
+			if existing.page == g.page && existing.colour == tg.Colour && existing.coloured == tg.Coloured && existing.scale == scale {
+				b = existing
+			}
+		}
+		if b == nil {
+			b = &batch{page: g.page, colour: tg.Colour, coloured: tg.Coloured, scale: scale}
+			batches = append(batches, b)
+		}
 
-	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 5*4, nil)
-	gl.EnableVertexAttribArray(0)
-	gl.VertexAttribPointerWithOffset(1, 2, gl.FLOAT, false, 5*4, 3*4)
-	gl.EnableVertexAttribArray(1)
+		line := layout.Lines[tg.Line]
+		uv := f.pages[g.page].uv(g.rect)
+		x0, y0 := tg.Rect[0]+float32(g.offset.X)*scale, line.Y+line.Baseline+float32(g.offset.Y)*scale
+		x1, y1 := x0+float32(g.rect.Dx())*scale, y0+float32(g.rect.Dy())*scale
+		offset := uint32(len(b.vertices) / 5)
+		b.vertices = append(b.vertices,
+			x0, y0, 0, uv[0], uv[2],
+			x1, y0, 0, uv[1], uv[2],
+			x1, y1, 0, uv[1], uv[3],
+			x0, y1, 0, uv[0], uv[3],
+		)
+		b.indices = append(b.indices,
+			0+offset, 1+offset, 3+offset,
+			1+offset, 2+offset, 3+offset,
+		)
+	}
 
-	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
-	gl.BindVertexArray(0)
+	rd := &stringRenderItemSize{size: layout.Size}
+	for _, b := range batches {
+		vao, vbo, _ := genVAO(b.vertices, b.indices)
+		ri := renderItem{
+			vao:     vao,
+			indices: int32(len(b.indices)),
+			image:   f.pages[b.page].image,
+			colour:  b.colour,
+		}
+		if f.sdf {
+			ri.sdf = &sdfText{
+				distanceRange: 2 * sdfSpread * b.scale,
+				pixelUV:       mgl32.Vec2{1 / (b.scale * ri.image.width), 1 / (b.scale * ri.image.height)},
+			}
+		}
+		rd.items = append(rd.items, ri)
+		rd.vbos = append(rd.vbos, vbo)
+		rd.pages = append(rd.pages, b.page)
+		rd.marks = append(rd.marks, b.coloured)
+	}
+	f.cacheText(key, rd)
+	return rd.at(x, y)
+}
 
-	renderItem := renderItem{
-		vao:       vao,
-		indices:   int32(len(indices)),
-		image:     f.atlas[size],
-		transform: NewTransform(x, y, 9),
+// A copy of the text's renderItems positioned at x, y. Snapped to whole pixels, as glyphs sampled
+// between texels, like text centred in an odd width, are drawn with rows and columns doubled or missing
+func (s stringRenderItemSize) at(x, y float32) stringRenderItemSize {
+	x, y = float32(math.Round(float64(x))), float32(math.Round(float64(y)))
+	items := make([]renderItem, len(s.items))
+	for i, ri := range s.items {
+		ri.transform = NewTransform(x, y, 9)
+		items[i] = ri
 	}
+	s.items = items
+	return s
+}
 
-	stringPrintData := stringRenderItemSize{ri: renderItem, size: mgl32.Vec2{currentX, float32(maxHeight)}}
-	f.renderDatas[size][str] = stringPrintData
-	return stringPrintData
+// Sets the effects SDF text is drawn with
+func (s stringRenderItemSize) styled(style TextStyle) stringRenderItemSize {
+	for i, ri := range s.items {
+		if ri.sdf != nil {
+			sdf := *ri.sdf
+			sdf.style = style
+			s.items[i].sdf = &sdf
+		}
+	}
+	return s
 }
 
-type glyph struct {
-	x       int
-	y       int
-	width   int
-	height  int
-	advance int
-	texture Texture
+// Sets the colour of every renderItem that markup didn't. Markup colours keep their own, faded by colour's alpha
+func (s stringRenderItemSize) coloured(colour mgl32.Vec4) []renderItem {
+	for i := range s.items {
+		if !s.marks[i] {
+			s.items[i].colour = colour
+		} else {
+			s.items[i].colour[3] *= colour[3]
+		}
+	}
+	return s.items
 }
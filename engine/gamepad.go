@@ -0,0 +1,468 @@
+package engine
+
+import (
+	"math"
+	"runtime"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Standard gamepad buttons, laid out like an Xbox controller
+const (
+	GamepadA = iota
+	GamepadB
+	GamepadX
+	GamepadY
+	GamepadLeftBumper
+	GamepadRightBumper
+	GamepadBack
+	GamepadStart
+	GamepadGuide
+	GamepadLeftThumb
+	GamepadRightThumb
+	GamepadDpadUp
+	GamepadDpadRight
+	GamepadDpadDown
+	GamepadDpadLeft
+	GamepadButtonCount
+)
+
+// Standard gamepad axes. Sticks go from -1 to 1 with y down, triggers from 0 to 1
+const (
+	GamepadLeftX = iota
+	GamepadLeftY
+	GamepadRightX
+	GamepadRightY
+	GamepadLeftTrigger
+	GamepadRightTrigger
+	GamepadAxisCount
+)
+
+const maxGamepads = 16 // GLFW's joystick limit
+
+// Pass as the pad to check every connected gamepad
+const AnyGamepad = -1
+
+var gamepadButtonNames = map[int]string{
+	GamepadA:           "A",
+	GamepadB:           "B",
+	GamepadX:           "X",
+	GamepadY:           "Y",
+	GamepadLeftBumper:  "LeftBumper",
+	GamepadRightBumper: "RightBumper",
+	GamepadBack:        "Back",
+	GamepadStart:       "Start",
+	GamepadGuide:       "Guide",
+	GamepadLeftThumb:   "LeftThumb",
+	GamepadRightThumb:  "RightThumb",
+	GamepadDpadUp:      "DpadUp",
+	GamepadDpadRight:   "DpadRight",
+	GamepadDpadDown:    "DpadDown",
+	GamepadDpadLeft:    "DpadLeft",
+}
+
+var gamepadAxisNames = map[int]string{
+	GamepadLeftX:        "LeftX",
+	GamepadLeftY:        "LeftY",
+	GamepadRightX:       "RightX",
+	GamepadRightY:       "RightY",
+	GamepadLeftTrigger:  "LeftTrigger",
+	GamepadRightTrigger: "RightTrigger",
+}
+
+// Which raw joystick button and axis each standard input comes from. -1 means the controller doesn't have it.
+// GLFW 3.2 reports joysticks as the platform does, so the raw layout differs between platforms and drivers
+type GamepadMapping struct {
+	Buttons [GamepadButtonCount]int
+	Axes    [GamepadAxisCount]int
+
+	// The d-pad is the raw x and y axes in DpadAxes, pointing down and right when positive, rather than buttons
+	HatDpad  bool
+	DpadAxes [2]int
+}
+
+// Layout of controllers read through XInput on Windows. XInput doesn't report the guide button
+var xinputGamepadMapping = GamepadMapping{
+	Buttons: [GamepadButtonCount]int{0, 1, 2, 3, 4, 5, 6, 7, -1, 8, 9, 10, 11, 12, 13},
+	Axes:    [GamepadAxisCount]int{0, 1, 2, 3, 4, 5},
+}
+
+// Layout of the Linux xpad driver's Xbox controllers through the joystick API, with the d-pad as a hat
+var xpadGamepadMapping = GamepadMapping{
+	Buttons:  [GamepadButtonCount]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, -1, -1, -1, -1},
+	Axes:     [GamepadAxisCount]int{0, 1, 3, 4, 2, 5},
+	HatDpad:  true,
+	DpadAxes: [2]int{6, 7},
+}
+
+// Layout of the Linux hid-sony driver's DualShock 4. Cross is A, circle B, square X and triangle Y
+var dualShock4GamepadMapping = GamepadMapping{
+	Buttons:  [GamepadButtonCount]int{0, 1, 3, 2, 4, 5, 8, 9, 10, 11, 12, -1, -1, -1, -1},
+	Axes:     [GamepadAxisCount]int{0, 1, 3, 4, 2, 5},
+	HatDpad:  true,
+	DpadAxes: [2]int{6, 7},
+}
+
+// Used for controllers without a mapping of their own. XInput's layout on Windows, xpad's on Linux,
+// and the standard order elsewhere
+var DefaultGamepadMapping = defaultGamepadMapping(runtime.GOOS)
+
+func defaultGamepadMapping(goos string) GamepadMapping {
+	switch goos {
+	case "windows":
+		return xinputGamepadMapping
+	case "linux":
+		return xpadGamepadMapping
+	}
+	return GamepadMapping{
+		Buttons: [GamepadButtonCount]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14},
+		Axes:    [GamepadAxisCount]int{0, 1, 2, 3, 4, 5},
+	}
+}
+
+// Mappings for controllers whose raw layout differs from the default, keyed by joystick name
+var gamepadMappings = knownGamepadMappings(runtime.GOOS)
+
+// Controllers whose names say which layout they report
+func knownGamepadMappings(goos string) map[string]GamepadMapping {
+	mappings := map[string]GamepadMapping{}
+	switch goos {
+	case "windows":
+		// the names GLFW gives XInput devices
+		mappings["Xbox 360 Controller"] = xinputGamepadMapping
+		mappings["Wireless Xbox 360 Controller"] = xinputGamepadMapping
+	case "linux":
+		for _, name := range []string{"Microsoft X-Box 360 pad", "Microsoft X-Box One pad", "Microsoft Xbox One S pad", "Xbox Wireless Controller"} {
+			mappings[name] = xpadGamepadMapping
+		}
+		for _, name := range []string{"Sony Interactive Entertainment Wireless Controller", "Sony Computer Entertainment Wireless Controller", "Wireless Controller"} {
+			mappings[name] = dualShock4GamepadMapping
+		}
+	}
+	return mappings
+}
+
+// Uses mapping for every controller reporting name
+func SetGamepadMapping(name string, mapping GamepadMapping) {
+	gamepadMappings[name] = mapping
+}
+
+// State of every standard input on a gamepad, used to feed synthetic input
+type GamepadState struct {
+	Buttons [GamepadButtonCount]bool
+	Axes    [GamepadAxisCount]float32 // before deadzones, in the standard ranges
+}
+
+type Gamepad struct {
+	ID        int
+	Name      string
+	Synthetic bool // driven by SetSyntheticGamepad rather than hardware
+
+	state GamepadState
+	last  GamepadState // state at the end of the previous update
+}
+
+type gamepads struct {
+	pads            [maxGamepads]*Gamepad
+	synthetic       [maxGamepads]*GamepadState
+	stickDeadzone   float32
+	triggerDeadzone float32
+	events          []gamepadEvent
+	onConnect       func(pad int, name string)
+	onDisconnect    func(pad int)
+}
+
+type gamepadEvent struct {
+	pad       int
+	connected bool
+}
+
+func newGamepads() gamepads {
+	return gamepads{
+		stickDeadzone:   0.2,
+		triggerDeadzone: 0.1,
+	}
+}
+
+// Connected gamepads, in id order
+func (i *input) Gamepads() []*Gamepad {
+	pads := []*Gamepad{}
+	for _, p := range i.pads.pads {
+		if p != nil {
+			pads = append(pads, p)
+		}
+	}
+	return pads
+}
+
+// Returns the gamepad with the given id, or nil if it isn't connected
+func (i *input) Gamepad(pad int) *Gamepad {
+	if pad < 0 || pad >= maxGamepads {
+		return nil
+	}
+	return i.pads.pads[pad]
+}
+
+// Called from Input.update when a gamepad is connected
+func (i *input) OnGamepadConnected(fn func(pad int, name string)) {
+	i.pads.onConnect = fn
+}
+
+// Called from Input.update when a gamepad is disconnected
+func (i *input) OnGamepadDisconnected(fn func(pad int)) {
+	i.pads.onDisconnect = fn
+}
+
+// Sets the deadzones for sticks and triggers, from 0 to 1. Defaults to 0.2 and 0.1
+func (i *input) SetGamepadDeadzones(stick, trigger float32) {
+	i.pads.stickDeadzone = stick
+	i.pads.triggerDeadzone = trigger
+}
+
+// Drives pad from state instead of hardware until RemoveSyntheticGamepad, connecting it if needed.
+// Like real input, the state is picked up on the next Input update. Lets tests and replays provide controller input
+func (i *input) SetSyntheticGamepad(pad int, state GamepadState) {
+	if pad < 0 || pad >= maxGamepads {
+		return
+	}
+	s := state
+	i.pads.synthetic[pad] = &s
+	if i.pads.pads[pad] == nil || !i.pads.pads[pad].Synthetic {
+		i.connectGamepad(pad, "Synthetic Gamepad", true)
+	}
+}
+
+func (i *input) RemoveSyntheticGamepad(pad int) {
+	if pad < 0 || pad >= maxGamepads || i.pads.synthetic[pad] == nil {
+		return
+	}
+	i.pads.synthetic[pad] = nil
+	i.disconnectGamepad(pad)
+	if !headless && glfw.JoystickPresent(glfw.Joystick(pad)) {
+		i.connectGamepad(pad, glfw.GetJoystickName(glfw.Joystick(pad)), false)
+	}
+}
+
+func (i *input) ButtonDown(pad, button int) bool {
+	if i.paused() {
+		return false
+	}
+	return i.anyPad(pad, func(p *Gamepad) bool { return p.button(button) })
+}
+
+// True on the first update the button is held, like KeyOnce
+func (i *input) ButtonOnce(pad, button int) bool {
+	if i.paused() {
+		return false
+	}
+	return i.anyPad(pad, func(p *Gamepad) bool { return p.button(button) && !p.lastButton(button) })
+}
+
+// True on the first update after the button is released, like KeyUp
+func (i *input) ButtonUp(pad, button int) bool {
+	if i.paused() {
+		return false
+	}
+	return i.anyPad(pad, func(p *Gamepad) bool { return !p.button(button) && p.lastButton(button) })
+}
+
+// Value of an axis with deadzones applied. With AnyGamepad, the value furthest from rest is used
+func (i *input) GamepadAxis(pad, axis int) float32 {
+	if i.paused() {
+		return 0
+	}
+	return i.gamepadAxis(pad, axis)
+}
+
+// Left or right stick as a vector, with a radial deadzone so small movements in any direction are ignored
+func (i *input) GamepadStick(pad int, right bool) mgl32.Vec2 {
+	if i.paused() {
+		return mgl32.Vec2{}
+	}
+	x, y := GamepadLeftX, GamepadLeftY
+	if right {
+		x, y = GamepadRightX, GamepadRightY
+	}
+	return mgl32.Vec2{i.gamepadAxis(pad, x), i.gamepadAxis(pad, y)}
+}
+
+func (i *input) anyPad(pad int, fn func(p *Gamepad) bool) bool {
+	if pad != AnyGamepad {
+		p := i.Gamepad(pad)
+		return p != nil && fn(p)
+	}
+	for _, p := range i.pads.pads {
+		if p != nil && fn(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *input) gamepadAxis(pad, axis int) float32 {
+	if pad != AnyGamepad {
+		if p := i.Gamepad(pad); p != nil {
+			return i.deadzone(p.state, axis)
+		}
+		return 0
+	}
+	var value float32
+	for _, p := range i.pads.pads {
+		if p == nil {
+			continue
+		}
+		if v := i.deadzone(p.state, axis); math.Abs(float64(v)) > math.Abs(float64(value)) {
+			value = v
+		}
+	}
+	return value
+}
+
+// Sticks use a radial deadzone, rescaled so the output still covers the full range
+func (i *input) deadzone(s GamepadState, axis int) float32 {
+	if axis < 0 || axis >= GamepadAxisCount {
+		return 0
+	}
+	if axis == GamepadLeftTrigger || axis == GamepadRightTrigger {
+		return rescaleDeadzone(s.Axes[axis], i.pads.triggerDeadzone)
+	}
+
+	x, y := GamepadLeftX, GamepadLeftY
+	if axis == GamepadRightX || axis == GamepadRightY {
+		x, y = GamepadRightX, GamepadRightY
+	}
+	stick := mgl32.Vec2{s.Axes[x], s.Axes[y]}
+	length := stick.Len()
+	if length <= i.pads.stickDeadzone {
+		return 0
+	}
+	scaled := rescaleDeadzone(float32(math.Min(float64(length), 1)), i.pads.stickDeadzone)
+	return s.Axes[axis] / length * scaled
+}
+
+func rescaleDeadzone(v, deadzone float32) float32 {
+	if math.Abs(float64(v)) <= float64(deadzone) || deadzone >= 1 {
+		return 0
+	}
+	sign := float32(1)
+	if v < 0 {
+		sign = -1
+	}
+	return sign * (float32(math.Abs(float64(v))) - deadzone) / (1 - deadzone)
+}
+
+func (p *Gamepad) button(b int) bool {
+	return b >= 0 && b < GamepadButtonCount && p.state.Buttons[b]
+}
+
+func (p *Gamepad) lastButton(b int) bool {
+	return b >= 0 && b < GamepadButtonCount && p.last.Buttons[b]
+}
+
+func (i *input) connectGamepad(pad int, name string, synthetic bool) {
+	i.pads.pads[pad] = &Gamepad{ID: pad, Name: name, Synthetic: synthetic}
+	i.pads.events = append(i.pads.events, gamepadEvent{pad: pad, connected: true})
+}
+
+func (i *input) disconnectGamepad(pad int) {
+	if i.pads.pads[pad] == nil {
+		return
+	}
+	i.pads.pads[pad] = nil
+	i.pads.events = append(i.pads.events, gamepadEvent{pad: pad, connected: false})
+}
+
+// Finds gamepads that were connected before the window was created
+func (i *input) scanGamepads() {
+	for pad := 0; pad < maxGamepads; pad++ {
+		if glfw.JoystickPresent(glfw.Joystick(pad)) {
+			i.connectGamepad(pad, glfw.GetJoystickName(glfw.Joystick(pad)), false)
+		}
+	}
+}
+
+// Called when GLFW reports a joystick being plugged in or removed
+func (i *input) joystickCallback(joy, event int) {
+	if joy < 0 || joy >= maxGamepads || i.pads.synthetic[joy] != nil || i.playback != nil {
+		return
+	}
+	if event == int(glfw.Connected) {
+		i.connectGamepad(joy, glfw.GetJoystickName(glfw.Joystick(joy)), false)
+	} else {
+		i.disconnectGamepad(joy)
+	}
+}
+
+// Saves the previous state, reads the new one and sends connection events
+func (i *input) pollGamepads() {
+	for pad, p := range i.pads.pads {
+		if p == nil {
+			continue
+		}
+		p.last = p.state
+		if synthetic := i.pads.synthetic[pad]; synthetic != nil {
+			p.state = *synthetic
+			continue
+		}
+		if headless {
+			continue
+		}
+		p.state = readGamepad(glfw.Joystick(pad), p.Name)
+	}
+
+	events := i.pads.events
+	i.pads.events = nil
+	for _, e := range events {
+		if e.connected && i.pads.onConnect != nil {
+			name := ""
+			if p := i.pads.pads[e.pad]; p != nil {
+				name = p.Name
+			}
+			i.pads.onConnect(e.pad, name)
+		} else if !e.connected && i.pads.onDisconnect != nil {
+			i.pads.onDisconnect(e.pad)
+		}
+	}
+}
+
+func readGamepad(joy glfw.Joystick, name string) GamepadState {
+	mapping, ok := gamepadMappings[name]
+	if !ok {
+		mapping = DefaultGamepadMapping
+	}
+	return mapGamepad(mapping, glfw.GetJoystickButtons(joy), glfw.GetJoystickAxes(joy))
+}
+
+// Converts raw joystick buttons and axes to the standard layout
+func mapGamepad(mapping GamepadMapping, buttons []byte, axes []float32) GamepadState {
+	var s GamepadState
+	for b, raw := range mapping.Buttons {
+		if raw >= 0 && raw < len(buttons) {
+			s.Buttons[b] = glfw.Action(buttons[raw]) == glfw.Press
+		}
+	}
+	for a, raw := range mapping.Axes {
+		if raw >= 0 && raw < len(axes) {
+			s.Axes[a] = axes[raw]
+		}
+	}
+	// raw triggers rest at -1
+	for _, t := range []int{GamepadLeftTrigger, GamepadRightTrigger} {
+		if mapping.Axes[t] >= 0 && mapping.Axes[t] < len(axes) {
+			s.Axes[t] = (s.Axes[t] + 1) / 2
+		}
+	}
+	if mapping.HatDpad {
+		x, y := mapping.DpadAxes[0], mapping.DpadAxes[1]
+		if x >= 0 && x < len(axes) {
+			s.Buttons[GamepadDpadLeft] = axes[x] < -0.5
+			s.Buttons[GamepadDpadRight] = axes[x] > 0.5
+		}
+		if y >= 0 && y < len(axes) {
+			s.Buttons[GamepadDpadUp] = axes[y] < -0.5
+			s.Buttons[GamepadDpadDown] = axes[y] > 0.5
+		}
+	}
+	return s
+}
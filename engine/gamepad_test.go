@@ -0,0 +1,311 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func nearly(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-4
+}
+
+func TestRescaleDeadzone(t *testing.T) {
+	tests := []struct {
+		v, deadzone, want float32
+	}{
+		{0, 0.2, 0},
+		{0.2, 0.2, 0},
+		{-0.1, 0.2, 0},
+		{0.6, 0.2, 0.5},
+		{-0.6, 0.2, -0.5},
+		{1, 0.2, 1},
+		{-1, 0.2, -1},
+		{0.5, 0, 0.5},
+		{0.9, 1, 0},
+	}
+	for _, tt := range tests {
+		if got := rescaleDeadzone(tt.v, tt.deadzone); !nearly(got, tt.want) {
+			t.Errorf("rescaleDeadzone(%v, %v) = %v, want %v", tt.v, tt.deadzone, got, tt.want)
+		}
+	}
+}
+
+func TestGamepadStickDeadzone(t *testing.T) {
+	tests := []struct {
+		name   string
+		x, y   float32
+		want   mgl32.Vec2
+		wantLT float32
+	}{
+		{"at rest", 0, 0, mgl32.Vec2{0, 0}, 0},
+		{"inside the deadzone on one axis", 0.15, 0, mgl32.Vec2{0, 0}, 0},
+		{"inside the deadzone diagonally", 0.1, 0.1, mgl32.Vec2{0, 0}, 0},
+		{"past the deadzone diagonally, though neither axis is", 0.15, 0.15, mgl32.Vec2{0.01072, 0.01072}, 0},
+		{"half way", 0.6, 0, mgl32.Vec2{0.5, 0}, 0},
+		{"keeps the direction", 0, -0.6, mgl32.Vec2{0, -0.5}, 0},
+		{"full tilt", 0, 1, mgl32.Vec2{0, 1}, 0},
+		{"past full tilt is clamped to the unit circle", 1, 1, mgl32.Vec2{0.70711, 0.70711}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := initInput()
+			var s GamepadState
+			s.Axes[GamepadLeftX], s.Axes[GamepadLeftY] = tt.x, tt.y
+			i.SetSyntheticGamepad(0, s)
+			i.pollGamepads()
+
+			got := i.GamepadStick(0, false)
+			if !nearly(got[0], tt.want[0]) || !nearly(got[1], tt.want[1]) {
+				t.Errorf("stick at (%v, %v) read %v, want %v", tt.x, tt.y, got, tt.want)
+			}
+			if x := i.GamepadAxis(0, GamepadLeftX); x != got[0] {
+				t.Errorf("GamepadAxis read %v, GamepadStick %v", x, got[0])
+			}
+			if r := i.GamepadStick(0, true); r != (mgl32.Vec2{}) {
+				t.Errorf("right stick moved with the left: %v", r)
+			}
+		})
+	}
+}
+
+func TestGamepadTriggerDeadzone(t *testing.T) {
+	i := initInput()
+	i.SetGamepadDeadzones(0.5, 0.2)
+	var s GamepadState
+	s.Axes[GamepadLeftTrigger] = 0.1
+	s.Axes[GamepadRightTrigger] = 0.6
+	s.Axes[GamepadRightX] = 0.4
+	i.SetSyntheticGamepad(0, s)
+	i.pollGamepads()
+
+	if v := i.GamepadAxis(0, GamepadLeftTrigger); v != 0 {
+		t.Errorf("left trigger read %v inside the deadzone", v)
+	}
+	if v := i.GamepadAxis(0, GamepadRightTrigger); !nearly(v, 0.5) {
+		t.Errorf("right trigger read %v, want 0.5", v)
+	}
+	if v := i.GamepadAxis(0, GamepadRightX); v != 0 {
+		t.Errorf("right stick read %v inside a 0.5 deadzone", v)
+	}
+	if v := i.GamepadAxis(0, GamepadAxisCount); v != 0 {
+		t.Errorf("out of range axis read %v", v)
+	}
+}
+
+func TestGamepadAnyPad(t *testing.T) {
+	i := initInput()
+	var a, b GamepadState
+	a.Axes[GamepadLeftX] = 0.6
+	b.Axes[GamepadLeftX] = -1
+	b.Buttons[GamepadStart] = true
+	i.SetSyntheticGamepad(0, a)
+	i.SetSyntheticGamepad(3, b)
+	i.pollGamepads()
+
+	if v := i.GamepadAxis(AnyGamepad, GamepadLeftX); v != -1 {
+		t.Errorf("any pad read %v, want the furthest from rest", v)
+	}
+	if !i.ButtonDown(AnyGamepad, GamepadStart) || i.ButtonDown(0, GamepadStart) {
+		t.Error("start should only be down on pad 3")
+	}
+	if i.ButtonDown(5, GamepadStart) || i.GamepadAxis(5, GamepadLeftX) != 0 {
+		t.Error("a disconnected pad reported input")
+	}
+	if n := len(i.Gamepads()); n != 2 {
+		t.Errorf("%d gamepads connected, want 2", n)
+	}
+}
+
+func TestGamepadButtonEdges(t *testing.T) {
+	i := initInput()
+	type edges struct{ down, once, up bool }
+	steps := []struct {
+		held bool
+		want edges
+	}{
+		{false, edges{}},
+		{true, edges{down: true, once: true}},
+		{true, edges{down: true}},
+		{false, edges{up: true}},
+		{false, edges{}},
+	}
+	for n, step := range steps {
+		var s GamepadState
+		s.Buttons[GamepadA] = step.held
+		i.SetSyntheticGamepad(0, s)
+		i.pollGamepads()
+		got := edges{i.ButtonDown(0, GamepadA), i.ButtonOnce(0, GamepadA), i.ButtonUp(0, GamepadA)}
+		if got != step.want {
+			t.Errorf("update %d saw %+v, want %+v", n, got, step.want)
+		}
+	}
+}
+
+func TestGamepadConnectionEvents(t *testing.T) {
+	i := initInput()
+	connected, disconnected := []int{}, []int{}
+	i.OnGamepadConnected(func(pad int, name string) { connected = append(connected, pad) })
+	i.OnGamepadDisconnected(func(pad int) { disconnected = append(disconnected, pad) })
+
+	i.SetSyntheticGamepad(2, GamepadState{})
+	i.SetSyntheticGamepad(2, GamepadState{}) // already connected
+	if len(connected) != 0 {
+		t.Error("connection reported before the update")
+	}
+	i.pollGamepads()
+	i.RemoveSyntheticGamepad(2)
+	i.pollGamepads()
+	if len(connected) != 1 || connected[0] != 2 || len(disconnected) != 1 || disconnected[0] != 2 {
+		t.Errorf("connected %v and disconnected %v, want pad 2 once each", connected, disconnected)
+	}
+	if i.Gamepad(2) != nil {
+		t.Error("removed pad is still connected")
+	}
+}
+
+func TestMapGamepad(t *testing.T) {
+	press, release := byte(glfw.Press), byte(glfw.Release)
+	standard := defaultGamepadMapping("")
+	swapped := standard
+	swapped.Buttons[GamepadA], swapped.Buttons[GamepadB] = 1, 0
+	swapped.Axes[GamepadRightTrigger] = -1
+
+	tests := []struct {
+		name    string
+		mapping GamepadMapping
+		buttons []byte
+		axes    []float32
+		down    []int
+		want    [GamepadAxisCount]float32
+	}{
+		{
+			name:    "default layout",
+			mapping: standard,
+			buttons: []byte{press, release, release, press},
+			axes:    []float32{0.5, -0.25, 0, 1, -1, 1},
+			down:    []int{GamepadA, GamepadY},
+			want:    [GamepadAxisCount]float32{0.5, -0.25, 0, 1, 0, 1},
+		},
+		{
+			name:    "triggers half pressed",
+			mapping: standard,
+			axes:    []float32{0, 0, 0, 0, 0, 0},
+			want:    [GamepadAxisCount]float32{0, 0, 0, 0, 0.5, 0.5},
+		},
+		{
+			name:    "remapped and missing inputs",
+			mapping: swapped,
+			buttons: []byte{press, release},
+			axes:    []float32{0, 0, 0, 0, -1, 1},
+			down:    []int{GamepadB},
+			want:    [GamepadAxisCount]float32{0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:    "controller with fewer axes than the mapping",
+			mapping: standard,
+			axes:    []float32{1, 1},
+			want:    [GamepadAxisCount]float32{1, 1, 0, 0, 0, 0},
+		},
+		{
+			name:    "xinput has no guide button",
+			mapping: xinputGamepadMapping,
+			buttons: []byte{release, release, release, release, release, release, release, release, press, release},
+			axes:    []float32{0, 0, 0, 0, -1, -1},
+			down:    []int{GamepadLeftThumb},
+		},
+		{
+			name:    "xpad triggers and right stick",
+			mapping: xpadGamepadMapping,
+			axes:    []float32{0.1, 0.2, 1, 0.3, 0.4, -1, 0, 0},
+			want:    [GamepadAxisCount]float32{0.1, 0.2, 0.3, 0.4, 1, 0},
+		},
+		{
+			name:    "hat d-pad up and left",
+			mapping: xpadGamepadMapping,
+			axes:    []float32{0, 0, -1, 0, 0, -1, -1, -1},
+			down:    []int{GamepadDpadUp, GamepadDpadLeft},
+		},
+		{
+			name:    "hat d-pad down and right",
+			mapping: xpadGamepadMapping,
+			axes:    []float32{0, 0, -1, 0, 0, -1, 1, 0.9},
+			down:    []int{GamepadDpadDown, GamepadDpadRight},
+		},
+		{
+			name:    "hat d-pad centred",
+			mapping: xpadGamepadMapping,
+			axes:    []float32{0, 0, -1, 0, 0, -1, 0.4, -0.4},
+		},
+		{
+			name:    "hat d-pad on a controller without the axes",
+			mapping: xpadGamepadMapping,
+			axes:    []float32{0, 0, -1, 0, 0, -1},
+		},
+		{
+			name:    "DualShock 4 face buttons",
+			mapping: dualShock4GamepadMapping,
+			buttons: []byte{press, release, press, release, release, release, release, release, release, press},
+			axes:    []float32{0, 0, -1, 0, 0, -1, 0, 0},
+			down:    []int{GamepadA, GamepadY, GamepadStart},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mapGamepad(tt.mapping, tt.buttons, tt.axes)
+			var want [GamepadButtonCount]bool
+			for _, b := range tt.down {
+				want[b] = true
+			}
+			if s.Buttons != want {
+				t.Errorf("buttons are %v, want %v", s.Buttons, want)
+			}
+			if s.Axes != tt.want {
+				t.Errorf("axes are %v, want %v", s.Axes, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultGamepadMappings(t *testing.T) {
+	tests := []struct {
+		goos    string
+		mapping GamepadMapping
+		known   map[string]GamepadMapping
+	}{
+		{"windows", xinputGamepadMapping, map[string]GamepadMapping{"Xbox 360 Controller": xinputGamepadMapping}},
+		{"linux", xpadGamepadMapping, map[string]GamepadMapping{
+			"Microsoft X-Box 360 pad": xpadGamepadMapping,
+			"Wireless Controller":     dualShock4GamepadMapping,
+		}},
+		{"darwin", defaultGamepadMapping(""), map[string]GamepadMapping{}},
+	}
+	for _, tt := range tests {
+		if got := defaultGamepadMapping(tt.goos); got != tt.mapping {
+			t.Errorf("default mapping on %s is %+v, want %+v", tt.goos, got, tt.mapping)
+		}
+		known := knownGamepadMappings(tt.goos)
+		for name, want := range tt.known {
+			if got, ok := known[name]; !ok || got != want {
+				t.Errorf("%q on %s is mapped as %+v, want %+v", name, tt.goos, got, want)
+			}
+		}
+		if len(tt.known) == 0 && len(known) != 0 {
+			t.Errorf("%s has mappings for %d controllers, want none", tt.goos, len(known))
+		}
+	}
+
+	standard := defaultGamepadMapping("")
+	for b, raw := range standard.Buttons {
+		if raw != b {
+			t.Errorf("standard mapping reads button %d from %d", b, raw)
+		}
+	}
+	for a, raw := range standard.Axes {
+		if raw != a {
+			t.Errorf("standard mapping reads axis %d from %d", a, raw)
+		}
+	}
+}
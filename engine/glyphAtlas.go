@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const (
+	glyphPageStart = 256  // width and height of a new page
+	glyphPageMax   = 2048 // pages double in size up to this, then a new page is started
+	glyphPagesMax  = 4    // then the page drawn from least recently is cleared and reused
+	glyphPadding   = 1    // empty pixels between glyphs, so filtering doesn't bleed
+)
+
+// A texture glyphs are packed into in rows, as they're first drawn
+type glyphPage struct {
+	img     *image.RGBA
+	image   Image
+	shelves []glyphShelf
+	dirty   image.Rectangle // drawn to since the last upload
+	used    uint64          // uiPass it was last drawn from
+	linear  bool            // filtered smoothly, for SDF glyphs
+}
+
+// A row of glyphs no taller than h, filled left to right
+type glyphShelf struct {
+	y, h int
+	x    int // where the next glyph goes
+}
+
+func newGlyphPage(side int, linear bool) *glyphPage {
+	img := image.NewRGBA(image.Rect(0, 0, side, side))
+	return &glyphPage{
+		img:    img,
+		image:  newGlyphImage(img, linear),
+		used:   uiPass,
+		linear: linear,
+	}
+}
+
+func newGlyphImage(img *image.RGBA, linear bool) Image {
+	if headless {
+		// shares the pixels, so nothing needs uploading
+		atlas := newSoftImage(img)
+		atlas.translucent = true
+		return atlas
+	}
+	b := img.Bounds()
+	var tex uint32
+	gl.GenTextures(1, &tex)
+	gl.BindTexture(gl.TEXTURE_2D, tex)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	filter := int32(gl.NEAREST)
+	if linear {
+		filter = gl.LINEAR
+	}
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, filter)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, filter)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, int32(b.Dx()), int32(b.Dy()), 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+	return Image{
+		id:          tex,
+		width:       float32(b.Dx()),
+		height:      float32(b.Dy()),
+		translucent: true,
+	}
+}
+
+// Finds room for a w x h glyph, starting a new shelf if none fit. Returns false if the page is full
+func (p *glyphPage) place(w, h int) (image.Rectangle, bool) {
+	side := p.img.Bounds().Dx()
+	best := -1
+	for n, s := range p.shelves {
+		// the shortest shelf it fits on, so tall shelves aren't filled with short glyphs
+		if h <= s.h && s.x+w <= side && (best < 0 || s.h < p.shelves[best].h) {
+			best = n
+		}
+	}
+	// only open a new shelf when the best one wastes a lot of its height
+	if best >= 0 && (h >= p.shelves[best].h*2/3 || !p.roomFor(h)) {
+		s := &p.shelves[best]
+		r := image.Rect(s.x, s.y, s.x+w, s.y+h)
+		s.x += w + glyphPadding
+		return r, true
+	}
+	if !p.roomFor(h) || w > side {
+		return image.Rectangle{}, false
+	}
+	y := p.shelfTop()
+	p.shelves = append(p.shelves, glyphShelf{y: y, h: h, x: w + glyphPadding})
+	return image.Rect(0, y, w, y+h), true
+}
+
+// Where a new shelf would start, below the others
+func (p *glyphPage) shelfTop() int {
+	if len(p.shelves) == 0 {
+		return 0
+	}
+	last := p.shelves[len(p.shelves)-1]
+	return last.y + last.h + glyphPadding
+}
+
+func (p *glyphPage) roomFor(h int) bool {
+	return p.shelfTop()+h <= p.img.Bounds().Dy()
+}
+
+// Doubles the page's size, keeping its glyphs where they are. Returns the texture it replaced
+func (p *glyphPage) grow() Image {
+	side := p.img.Bounds().Dx() * 2
+	img := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(img, p.img.Bounds(), p.img, image.Point{}, draw.Src)
+	old := p.image
+	p.img = img
+	p.image = newGlyphImage(img, p.linear)
+	p.dirty = image.Rectangle{}
+	return old
+}
+
+// Empties the page for reuse
+func (p *glyphPage) clear() {
+	draw.Draw(p.img, p.img.Bounds(), image.Transparent, image.Point{}, draw.Src)
+	p.shelves = nil
+	p.dirty = p.img.Bounds()
+}
+
+// Copies the rows drawn to since the last upload to the texture
+func (p *glyphPage) upload() {
+	if p.dirty.Empty() {
+		return
+	}
+	if !headless {
+		b := p.img.Bounds()
+		gl.BindTexture(gl.TEXTURE_2D, p.image.id)
+		gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, int32(p.dirty.Min.Y), int32(b.Dx()), int32(p.dirty.Dy()),
+			gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(p.img.Pix[p.img.PixOffset(0, p.dirty.Min.Y):]))
+	}
+	p.dirty = image.Rectangle{}
+}
+
+// Texture coordinates of a rectangle in the page
+func (p *glyphPage) uv(r image.Rectangle) mgl32.Vec4 {
+	w, h := p.image.width, p.image.height
+	return mgl32.Vec4{float32(r.Min.X) / w, float32(r.Max.X) / w, float32(r.Min.Y) / h, float32(r.Max.Y) / h}
+}
+
+// Finds room for a w x h glyph, growing pages, adding one or clearing the least recently used as needed.
+// Returns the page index and where in it the glyph goes
+func (f *Font) pack(w, h int) (int, image.Rectangle) {
+	for n, p := range f.pages {
+		if r, ok := p.place(w, h); ok {
+			return n, r
+		}
+	}
+	for n, p := range f.pages {
+		for p.img.Bounds().Dx() < glyphPageMax {
+			f.retireImage(p.grow())
+			if r, ok := p.place(w, h); ok {
+				return n, r
+			}
+		}
+	}
+
+	evict := -1
+	if len(f.pages) >= glyphPagesMax {
+		for n, p := range f.pages {
+			// text drawn this pass may be using anything drawn from this pass
+			if p.used != uiPass && (evict < 0 || p.used < f.pages[evict].used) {
+				evict = n
+			}
+		}
+	}
+	if evict < 0 {
+		side := glyphPageStart
+		for side < w || side < h {
+			side *= 2
+		}
+		f.pages = append(f.pages, newGlyphPage(side, f.sdf))
+		evict = len(f.pages) - 1
+	} else {
+		f.evict(evict)
+	}
+	r, _ := f.pages[evict].place(w, h)
+	return evict, r
+}
+
+// Forgets every glyph on a page and clears it
+func (f *Font) evict(page int) {
+	for key, g := range f.glyphs {
+		if g.page == page {
+			delete(f.glyphs, key)
+		}
+	}
+	f.pages[page].clear()
+	f.pages[page].used = uiPass
+	f.stats.PagesCleared++
+	f.clearRenderDatas()
+}
+
+// Keeps a replaced texture until nothing drawn with it is waiting to be rendered
+func (f *Font) retireImage(image Image) {
+	f.retire(func() {
+		if !headless {
+			gl.DeleteTextures(1, &image.id)
+		}
+	})
+	f.clearRenderDatas()
+}
@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"image"
+	"testing"
+)
+
+func TestGlyphPagePlace(t *testing.T) {
+	type placed struct {
+		w, h int
+		want image.Rectangle
+		ok   bool
+	}
+	tests := []struct {
+		name   string
+		glyphs []placed
+	}{
+		{"first glyph opens a shelf", []placed{
+			{4, 6, image.Rect(0, 0, 4, 6), true},
+		}},
+		{"glyphs fill a shelf left to right with padding", []placed{
+			{4, 6, image.Rect(0, 0, 4, 6), true},
+			{5, 6, image.Rect(5, 0, 10, 6), true},
+			{3, 5, image.Rect(11, 0, 14, 5), true},
+		}},
+		{"a full shelf opens another below it", []placed{
+			{10, 6, image.Rect(0, 0, 10, 6), true},
+			{10, 6, image.Rect(0, 7, 10, 13), true},
+		}},
+		{"taller glyphs open a new shelf", []placed{
+			{4, 4, image.Rect(0, 0, 4, 4), true},
+			{4, 8, image.Rect(0, 5, 4, 13), true},
+			{4, 4, image.Rect(5, 0, 9, 4), true},
+		}},
+		{"short glyphs don't waste a tall shelf", []placed{
+			{4, 9, image.Rect(0, 0, 4, 9), true},
+			{4, 3, image.Rect(0, 10, 4, 13), true},
+			{4, 8, image.Rect(5, 0, 9, 8), true},
+		}},
+		{"short glyphs use a tall shelf when there's no room below", []placed{
+			{4, 14, image.Rect(0, 0, 4, 14), true},
+			{4, 3, image.Rect(5, 0, 9, 3), true},
+		}},
+		{"full page", []placed{
+			{16, 10, image.Rect(0, 0, 16, 10), true},
+			{4, 6, image.Rectangle{}, false},
+			{4, 5, image.Rect(0, 11, 4, 16), true},
+		}},
+		{"wider than the page", []placed{
+			{17, 2, image.Rectangle{}, false},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &glyphPage{img: image.NewRGBA(image.Rect(0, 0, 16, 16))}
+			for n, g := range tt.glyphs {
+				r, ok := p.place(g.w, g.h)
+				if r != g.want || ok != g.ok {
+					t.Errorf("glyph %d (%dx%d) placed at %v %v, want %v %v", n, g.w, g.h, r, ok, g.want, g.ok)
+				}
+			}
+		})
+	}
+}
+
+func TestFontPackGrows(t *testing.T) {
+	restore := saveGlyphAtlas()
+	defer restore()
+
+	f := &Font{glyphs: map[glyphKey]*glyph{}}
+	page, r := f.pack(10, 10)
+	if page != 0 || r != image.Rect(0, 0, 10, 10) || len(f.pages) != 1 {
+		t.Fatalf("first glyph packed on page %d at %v with %d pages", page, r, len(f.pages))
+	}
+	if side := f.pages[0].img.Bounds().Dx(); side != glyphPageStart {
+		t.Errorf("new page is %d wide, want %d", side, glyphPageStart)
+	}
+
+	f.pages[0].img.Pix[0] = 255
+	page, r = f.pack(glyphPageStart+1, 20)
+	if page != 0 || r != image.Rect(0, 11, glyphPageStart+1, 31) {
+		t.Errorf("wide glyph packed on page %d at %v, want page 0 after growing", page, r)
+	}
+	if side := f.pages[0].img.Bounds().Dx(); side != glyphPageStart*2 || len(f.pages) != 1 {
+		t.Errorf("%d pages, the first %d wide, want one grown to %d", len(f.pages), side, glyphPageStart*2)
+	}
+	if f.pages[0].img.Pix[0] != 255 {
+		t.Error("growing lost the glyphs already on the page")
+	}
+
+	page, _ = f.pack(glyphPageMax*2, 1)
+	if page != 1 || f.pages[1].img.Bounds().Dx() != glyphPageMax*2 {
+		t.Errorf("glyph bigger than a full page packed on page %d", page)
+	}
+}
+
+func TestFontPackEvicts(t *testing.T) {
+	restore := saveGlyphAtlas()
+	defer restore()
+
+	f := &Font{glyphs: map[glyphKey]*glyph{}}
+	for n := 0; n < glyphPagesMax; n++ {
+		if page, _ := f.pack(glyphPageMax, glyphPageMax); page != n {
+			t.Fatalf("page sized glyph %d packed on page %d", n, page)
+		}
+	}
+	if page, _ := f.pack(glyphPageMax, glyphPageMax); page != glyphPagesMax {
+		t.Fatalf("packed on page %d while every page is drawn from this pass, want a new page", page)
+	}
+
+	uiPass = 10
+	f.pages = f.pages[:glyphPagesMax]
+	for n, used := range []uint64{3, 1, 2, uiPass} {
+		f.pages[n].used = used
+	}
+	f.glyphs[glyphKey{size: 12, char: 'a'}] = &glyph{page: 1}
+	f.glyphs[glyphKey{size: 12, char: 'b'}] = &glyph{page: 2}
+	f.pages[1].img.Pix[0] = 255
+
+	page, r := f.pack(8, 8)
+	if page != 1 || r != image.Rect(0, 0, 8, 8) {
+		t.Errorf("packed on page %d at %v, want the least recently used page 1 cleared", page, r)
+	}
+	if len(f.pages) != glyphPagesMax || f.pages[1].used != uiPass || f.pages[1].img.Pix[0] != 0 {
+		t.Error("evicted page wasn't cleared and reused")
+	}
+	if _, ok := f.glyphs[glyphKey{size: 12, char: 'a'}]; ok {
+		t.Error("glyph on the evicted page is still cached")
+	}
+	if _, ok := f.glyphs[glyphKey{size: 12, char: 'b'}]; !ok {
+		t.Error("glyph on another page was forgotten")
+	}
+}
+
+// Packs without a GL context, and keeps the pass count from leaking into other tests
+func saveGlyphAtlas() func() {
+	h, pass := headless, uiPass
+	headless = true
+	return func() {
+		headless, uiPass = h, pass
+	}
+}
@@ -0,0 +1,239 @@
+package engine
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Set when the game is running without a window or OpenGL context.
+// GPU resources are then kept on the CPU so they can be rasterized in software.
+var headless bool
+
+type softMesh struct {
+	vertices []float32 // x, y, z, u, v
+	indices  []uint32
+}
+
+var softMeshes = make(map[uint32]*softMesh) // keyed by vao
+var softVBOs = make(map[uint32]*softMesh)   // keyed by vbo
+var softIDs uint32
+
+// fake GL object names so headless resources can still be looked up by id
+func nextSoftID() uint32 {
+	softIDs++
+	return softIDs
+}
+
+// returns vao, vbo, indices
+func genSoftVAO(p []float32, i []uint32) (uint32, uint32, int32) {
+	mesh := &softMesh{
+		vertices: append([]float32{}, p...),
+		indices:  append([]uint32{}, i...),
+	}
+	vao, vbo := nextSoftID(), nextSoftID()
+	softMeshes[vao] = mesh
+	softVBOs[vbo] = mesh
+	return vao, vbo, int32(len(i))
+}
+
+func updateSoftVBO(vbo uint32, p []float32) {
+	if mesh, ok := softVBOs[vbo]; ok {
+		mesh.vertices = append(mesh.vertices[:0], p...)
+	}
+}
+
+func newSoftImage(img *image.RGBA) Image {
+	b := img.Bounds()
+	return Image{
+		id:          nextSoftID(),
+		width:       float32(b.Dx()),
+		height:      float32(b.Dy()),
+		pixels:      img,
+		translucent: hasTranslucency(img.Pix),
+	}
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// HeadlessRenderer collects everything submitted through Renderer2D without touching the GPU.
+// If rasterizing is enabled, each frame is drawn in software into an image.RGBA.
+type HeadlessRenderer struct {
+	items        []renderItem
+	uiBuffer     []renderItem
+	lights       []Light
+	ambientLight mgl32.Vec3
+	exposure     float32
+	activeCam    Camera
+	projection   mgl32.Mat4
+	postShader   string
+	ySort        map[int]bool
+	targets      []*RenderTarget
+	post         *PostChain
+
+	rasterize bool
+	raster    *rasterizer
+}
+
+// Initialises a headless 2D renderer. Takes in the width and height of the render target
+func HeadlessRenderer2DInit(width, height float32, rasterize bool) *HeadlessRenderer {
+	shaderMap = make(map[string]Shader)
+
+	r := &HeadlessRenderer{
+		items:        []renderItem{},
+		uiBuffer:     []renderItem{},
+		lights:       []Light{},
+		ambientLight: mgl32.Vec3{1, 1, 1},
+		exposure:     1,
+		activeCam:    Camera2D{},
+		projection:   mgl32.Ortho(0, width, height, 0, -0.1, 10.1),
+		ySort:        make(map[int]bool),
+		post:         &PostChain{},
+		rasterize:    rasterize,
+	}
+	if rasterize {
+		r.raster = newRasterizer(int(width), int(height))
+	}
+	return r
+}
+
+func (r *HeadlessRenderer) BeginScene(c Camera, ambientLight mgl32.Vec3, exposure float32) {
+	r.items = []renderItem{}
+	r.lights = []Light{}
+	r.uiBuffer = []renderItem{}
+	r.targets = []*RenderTarget{}
+	r.activeCam = c
+	r.ambientLight = ambientLight
+	r.exposure = exposure
+}
+
+func (r *HeadlessRenderer) beginUI() {
+	r.uiBuffer = []renderItem{}
+}
+
+func (r *HeadlessRenderer) PushItem(renderable renderable) {
+	r.items = append(r.items, renderable.renderItem()...)
+}
+
+func (r *HeadlessRenderer) PushLight(light Light) {
+	r.lights = append(r.lights, light)
+}
+
+func (r *HeadlessRenderer) PushUI(ri renderItem) {
+	r.uiBuffer = append(r.uiBuffer, ri)
+}
+
+func (r *HeadlessRenderer) PushTarget(t *RenderTarget) {
+	r.targets = append(r.targets, t)
+}
+
+// Custom post shaders can't be run in software, so the name is only recorded
+func (r *HeadlessRenderer) SetPostShader(name string) {
+	if _, ok := shaderMap[name]; !ok {
+		r.postShader = ""
+		return
+	}
+	r.postShader = name
+}
+
+func (r *HeadlessRenderer) resize(width, height float32) {
+	r.projection = mgl32.Ortho(0, width, height, 0, -0.1, 10.1)
+	if r.rasterize {
+		r.raster = newRasterizer(int(width), int(height))
+	}
+}
+
+// Effects are kept so they can be configured, but like post shaders they aren't rasterized
+func (r *HeadlessRenderer) PostProcess() *PostChain {
+	return r.post
+}
+
+func (r *HeadlessRenderer) render() {
+	if !r.rasterize {
+		return
+	}
+
+	for _, t := range r.targets {
+		r.renderTarget(t)
+	}
+
+	r.raster.clear()
+	r.drawScene(r.raster, r.items, r.lights, r.activeCam.ViewMatrix(), r.projection, r.ambientLight)
+
+	if r.postShader == "" {
+		r.raster.toneMap(r.exposure)
+	}
+
+	drawSoftUI(r.raster, r.uiBuffer, r.projection)
+}
+
+func (r *HeadlessRenderer) drawScene(raster *rasterizer, items []renderItem, lights []Light, view, projection mgl32.Mat4, ambientLight mgl32.Vec3) {
+	raster.setLights(lights, view, projection, ambientLight)
+	sortRenderItems(items, r.ySort)
+	layerRuns(items, func(layer []renderItem) {
+		raster.clearDepth()
+		for _, ri := range layer {
+			raster.drawScene(ri, projection.Mul4(view).Mul4(GetMatrix(ri.transform)), !ri.image.translucent)
+		}
+	})
+}
+
+func drawSoftUI(raster *rasterizer, items []renderItem, projection mgl32.Mat4) {
+	uiView := mgl32.Translate3D(0, 0, -10)
+	for _, ri := range items {
+		raster.drawUI(ri, projection.Mul4(uiView).Mul4(GetMatrix(ri.transform)))
+	}
+}
+
+func (r *HeadlessRenderer) SetYSort(layer int, enabled bool) {
+	r.ySort[layer] = enabled
+}
+
+// Draw calls the GL renderer would have made for the submitted items
+func (r *HeadlessRenderer) Stats() RenderStats {
+	items := append([]renderItem{}, r.items...)
+	sortRenderItems(items, r.ySort)
+	return countBatches(items)
+}
+
+// Returns the last rasterized frame, or nil if rasterizing is disabled
+func (r *HeadlessRenderer) Frame() *image.RGBA {
+	if !r.rasterize {
+		return nil
+	}
+	return r.raster.image()
+}
+
+// Number of scene items submitted since the last BeginScene
+func (r *HeadlessRenderer) ItemCount() int {
+	return len(r.items)
+}
+
+// Number of lights submitted since the last BeginScene
+func (r *HeadlessRenderer) LightCount() int {
+	return len(r.lights)
+}
+
+// Number of UI items submitted since the last beginUI
+func (r *HeadlessRenderer) UICount() int {
+	return len(r.uiBuffer)
+}
+
+// Name of the selected post shader, empty for the default
+func (r *HeadlessRenderer) PostShader() string {
+	return r.postShader
+}
+
+// Camera passed to the last BeginScene
+func (r *HeadlessRenderer) camera() Camera {
+	return r.activeCam
+}
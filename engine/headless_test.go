@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden images in testdata")
+
+type goldenScene struct {
+	sprite Sprite
+}
+
+func (s *goldenScene) Update() {
+	Renderer.BeginScene(NewCamera2D(0, 0), mgl32.Vec3{0.5, 0.5, 0.5}, 1)
+	Renderer.PushItem(s.sprite)
+	Renderer.PushLight(NewLight(100, 80, 50, 1, 0.8, 0.6, 0.3, 0.3, 0.3, 2))
+	UI.Begin()
+	UI.Button(10, 10, 120, 40, "Hello", mgl32.Vec4{1, 0.3, 0.2, 1})
+	UI.Label("Headless", 10, 60, 16, mgl32.Vec4{0, 0, 0, 1})
+	UI.ProgressBar(10, 90, 120, 12, 0.6, mgl32.Vec4{0.3, 0.8, 0.3, 1})
+	UI.End()
+}
+
+func TestHeadlessGolden(t *testing.T) {
+	g := CreateHeadlessGame(200, 120, true)
+	tex := NewTexture(embeddedPrefix + "ui9slice.png")
+	g.SetScene(&goldenScene{sprite: NewSprite(48, 48, 150, 70, 10, tex, nil)})
+	g.Step()
+
+	frame := Renderer.(*HeadlessRenderer).Frame()
+	path := filepath.Join("testdata", "headless.png")
+	if *updateGolden {
+		writePNG(t, path, frame)
+		return
+	}
+	want := readPNG(t, path)
+	if !frame.Bounds().Eq(want.Bounds()) {
+		t.Fatalf("frame is %v, golden image is %v", frame.Bounds(), want.Bounds())
+	}
+	diffs := 0
+	for i := range frame.Pix {
+		if frame.Pix[i] != want.Pix[i] {
+			diffs++
+		}
+	}
+	if diffs > 0 {
+		t.Fatalf("%d bytes differ from %s, run with -update if the change is intended", diffs, path)
+	}
+}
+
+func writePNG(t *testing.T, path string, img image.Image) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readPNG(t *testing.T, path string) *image.RGBA {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return toRGBA(img)
+}
@@ -2,7 +2,6 @@ package engine
 
 import (
 	"fmt"
-	"runtime"
 	"time"
 
 	"github.com/go-gl/glfw/v3.2/glfw"
@@ -16,10 +15,18 @@ type input struct {
 	currentKeys [KeyLast]bool
 	keysOnce    [KeyLast]bool
 	keysUp      [KeyLast]bool
-	mouseDown   [3]bool
+	keysRepeat  [KeyLast]bool // pressed or repeated by the OS since the last update
+	mouse       mouseState
 
-	screenScale   float32 // On mac, we need to divide the framebuffer size by 2 to get the accurate screen position for the mouse input
-	pauseStart    time.Time
+	actions actionMap
+	pads    gamepads
+
+	clipboard string // used instead of the system clipboard when there's no window
+
+	recorder *InputRecording // nil unless recording
+	playback *replay         // nil unless playing a recording
+
+	pauseStart    time.Duration // clock uptime when input was paused
 	pauseDuration time.Duration
 }
 
@@ -28,91 +35,133 @@ func initInput() *input {
 	currentKeys := [KeyLast]bool{}
 	keysOnce := [KeyLast]bool{}
 	keysUp := [KeyLast]bool{}
-	mouseDown := [3]bool{}
-
-	var screenScale float32
-	if runtime.GOOS == "darwin" {
-		screenScale = 2
-	} else {
-		screenScale = 1
-	}
 
 	return &input{
 		keysDown:    keysDown,
 		currentKeys: currentKeys,
 		keysOnce:    keysOnce,
 		keysUp:      keysUp,
-		mouseDown:   mouseDown,
-		screenScale: screenScale,
+		actions:     newActionMap(),
+		pads:        newGamepads(),
 	}
 }
 
 func (i *input) setCallBacks(w *window) {
 	w.win.SetMouseButtonCallback(func(win *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
-		if int(button) < len(i.mouseDown) {
-			switch action {
-			case glfw.Press:
-				i.keysDown[button] = true
-				i.keysOnce[button] = true
-			case glfw.Release:
-				i.keysDown[button] = false
-				i.keysOnce[button] = false
-				i.keysUp[button] = true
-			}
-		}
+		i.mouseButtonCallback(int(button), action)
+	})
+
+	w.win.SetCursorPosCallback(func(win *glfw.Window, x, y float64) {
+		i.cursorPosCallback(x, y)
 	})
 
 	w.win.SetKeyCallback(func(win *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
-		switch action {
-		case glfw.Press:
-			i.keysDown[key] = true
-		case glfw.Release:
-			i.keysDown[key] = false
-			i.keysUp[key] = true
-		}
+		i.keyCallback(int(key), action)
 	})
 
 	w.win.SetScrollCallback(func(window *glfw.Window, x, y float64) {
+		i.scrollCallback(x, y)
 	})
 
 	w.win.SetCharCallback(func(w *glfw.Window, char rune) {
+		if i.playback != nil {
+			return
+		}
 		i.textInput = append(i.textInput, char)
 	})
+
+	glfw.SetJoystickCallback(i.joystickCallback)
+}
+
+// GLFW reports keys it can't identify as KeyUnknown (-1), which has no slot to record it in
+func (i *input) keyCallback(key int, action glfw.Action) {
+	if i.playback != nil || key < 0 || key >= KeyLast {
+		return
+	}
+	switch action {
+	case glfw.Press:
+		i.keysDown[key] = true
+		i.keysRepeat[key] = true
+	case glfw.Repeat:
+		i.keysRepeat[key] = true
+	case glfw.Release:
+		i.keysDown[key] = false
+		i.keysUp[key] = true
+	}
 }
 
 func (i *input) setWindow(w *window) {
 	i.setCallBacks(w)
 	i.window = w
+	i.scanGamepads()
 }
 
 func (i *input) KeyDown(key int) bool {
-	if time.Now().Sub(i.pauseStart) < i.pauseDuration {
+	if i.paused() {
 		return false
 	}
 	return i.keysDown[key]
 }
 
 func (i *input) KeyOnce(key int) bool {
-	if time.Now().Sub(i.pauseStart) < i.pauseDuration {
+	if i.paused() {
 		return false
 	}
 	return i.keysOnce[key]
 }
 
 func (i *input) KeyUp(key int) bool {
-	if time.Now().Sub(i.pauseStart) < i.pauseDuration {
+	if i.paused() {
 		return false
 	}
 	return i.keysUp[key]
 }
 
+// True when the key is pressed, and again each time the OS repeats it while held, at the user's repeat rate.
+// For text editing and menus
+func (i *input) KeyRepeat(key int) bool {
+	if i.paused() {
+		return false
+	}
+	return i.keysRepeat[key]
+}
+
+// Text on the system clipboard
+func (i *input) Clipboard() string {
+	if i.window == nil {
+		return i.clipboard
+	}
+	text, err := i.window.win.GetClipboardString()
+	if err != nil {
+		return ""
+	}
+	return text
+}
+
+func (i *input) SetClipboard(text string) {
+	if i.window == nil {
+		i.clipboard = text
+		return
+	}
+	i.window.win.SetClipboardString(text)
+}
+
 func (i *input) MousePosition() mgl32.Vec2 {
+	if i.playback != nil {
+		return i.playback.cursor
+	}
+	if i.window == nil {
+		return mgl32.Vec2{}
+	}
 	x, y := i.window.win.GetCursorPos()
 
-	// TODO: scaling input based on resolution
-	xRatio := ScreenW / (dispW / i.screenScale)
-	yRatio := ScreenH / (dispH / i.screenScale)
-	return mgl32.Vec2{float32(x) * xRatio, float32(y) * yRatio}
+	// The cursor is in window coordinates, which are smaller than the framebuffer on high DPI displays
+	winW, winH := i.window.getSize()
+	if winW == 0 || winH == 0 {
+		return mgl32.Vec2{}
+	}
+	sx, sy := DisplayToScreen(float32(x)*dispW/winW, float32(y)*dispH/winH)
+	return mgl32.Vec2{sx, sy}
 }
 
 func (i *input) pauseInput(duration time.Duration) {
@@ -120,22 +169,40 @@ func (i *input) pauseInput(duration time.Duration) {
 	for x := 0; x < KeyLast; x++ {
 		i.keysUp[x] = false
 		i.keysOnce[x] = false
+		i.keysRepeat[x] = false
 		i.currentKeys[x] = false
 	}
-	i.pauseStart = time.Now()
+	i.mouse.once = [mouseButtonCount]bool{}
+	i.mouse.pressed = [mouseButtonCount]bool{}
+	i.mouse.released = [mouseButtonCount]bool{}
+	i.mouse.up = [mouseButtonCount]bool{}
+	i.mouse.current = [mouseButtonCount]bool{}
+	i.pauseStart = Time.Uptime()
 	i.pauseDuration = duration
 }
 
+func (i *input) paused() bool {
+	return Time.Uptime()-i.pauseStart < i.pauseDuration
+}
+
 func (i *input) update() {
+	i.recordFrame()
+	i.updateRebind()
+	i.updateActions()
+
 	for x := 0; x < KeyLast; x++ {
 		i.keysUp[x] = false
 		i.keysOnce[x] = false
+		i.keysRepeat[x] = false
 		if i.keysDown[x] && !i.currentKeys[x] {
 			i.keysOnce[x] = true
 		}
 		i.currentKeys[x] = i.keysDown[x]
 	}
 	i.textInput = []rune{}
+	i.updateMouse()
+	i.playFrame()
+	i.pollGamepads()
 }
 
 // List of all keyboard buttons.
@@ -263,15 +330,25 @@ const (
 	KeyMenu         = int(glfw.KeyMenu)
 	KeyLast         = int(glfw.KeyLast)
 
-	MouseLeft   = int(glfw.MouseButtonLeft)
-	MouseRight  = int(glfw.MouseButtonRight)
-	MouseMiddle = int(glfw.MouseButtonMiddle)
+	MouseLeft   = MouseButton(glfw.MouseButtonLeft)
+	MouseRight  = MouseButton(glfw.MouseButtonRight)
+	MouseMiddle = MouseButton(glfw.MouseButtonMiddle)
+	Mouse4      = MouseButton(glfw.MouseButton4)
+	Mouse5      = MouseButton(glfw.MouseButton5)
+	Mouse6      = MouseButton(glfw.MouseButton6)
+	Mouse7      = MouseButton(glfw.MouseButton7)
+	Mouse8      = MouseButton(glfw.MouseButton8)
 )
 
-var mouseNames = map[int]string{
+var mouseNames = map[MouseButton]string{
 	MouseLeft:   "LMB",
 	MouseRight:  "RMB",
 	MouseMiddle: "MMB",
+	Mouse4:      "MB4",
+	Mouse5:      "MB5",
+	Mouse6:      "MB6",
+	Mouse7:      "MB7",
+	Mouse8:      "MB8",
 }
 
 var buttonNames = map[int]string{
@@ -0,0 +1,335 @@
+package engine
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Where an area sits on the screen, or in the panel it's started in
+type Anchor int
+
+const (
+	AnchorTopLeft Anchor = iota
+	AnchorTop
+	AnchorTopRight
+	AnchorLeft
+	AnchorCentre
+	AnchorRight
+	AnchorBottomLeft
+	AnchorBottom
+	AnchorBottomRight
+)
+
+// Where widgets narrower than a column, or shorter than a row, sit across it
+type Align int
+
+const (
+	AlignStart Align = iota
+	AlignCentre
+	AlignEnd
+)
+
+// Options for the layout containers
+type LayoutOptions struct {
+	Padding float32 // between the container's edge and its contents
+	Spacing float32 // between widgets
+	Margin  float32 // around the container, or from the edge it's anchored to for areas
+	Align   Align
+}
+
+type layoutKind int
+
+const (
+	layoutColumn layoutKind = iota
+	layoutRow
+	layoutGrid
+)
+
+// Places widgets one after another. Positions are in screen pixels, sizes are of the contents inside the padding
+type uiLayout struct {
+	kind   layoutKind
+	id     int
+	panel  int  // panel depth it was started at, panels started inside it lay out their own widgets
+	nested bool // takes up a slot in the layout it was started in
+	opts   LayoutOptions
+
+	x, y float32
+	w, h float32    // 0 when sized to fit the contents
+	fit  mgl32.Vec2 // contents size last pass, for aligning when sized to fit
+
+	columns int
+	cell    mgl32.Vec2 // grid cell size
+	lineH   float32    // tallest widget in the current grid row
+
+	cursor  mgl32.Vec2 // end of the last widget
+	size    mgl32.Vec2 // size of the contents so far
+	maxCell mgl32.Vec2 // largest grid widget so far
+	count   int
+}
+
+// Measured at EndLayout, so containers sized to fit can be positioned and aligned on the next pass
+type layoutMeasure struct {
+	size mgl32.Vec2
+	cell mgl32.Vec2
+}
+
+// Starts a column anchored to the screen, or to the contents of the panel it's started in. A w or h of 0
+// sizes the area to fit what was laid out in it last pass. End it with EndLayout
+func (ui *ui) BeginArea(anchor Anchor, w, h float32, options ...LayoutOptions) {
+	opts := layoutOptions(options)
+	id := ui.getID("##area")
+	m := ui.layoutSizes[id]
+	pad, margin := opts.Padding, opts.Margin
+
+	outerW, outerH := w, h
+	if outerW == 0 {
+		outerW = m.size[0] + 2*pad
+	}
+	if outerH == 0 {
+		outerH = m.size[1] + 2*pad
+	}
+
+	area := mgl32.Vec4{0, 0, ScreenW, ScreenH}
+	if len(ui.panels) > 0 {
+		if p := ui.panels[len(ui.panels)-1]; p.size[0] > 0 {
+			area = mgl32.Vec4{p.offset[0], p.offset[1], p.size[0], p.size[1]}
+		}
+	}
+	col, row := float32(anchor%3), float32(anchor/3)
+	x := area[0] + margin + float32(math.Floor(float64((area[2]-outerW-2*margin)*col/2)))
+	y := area[1] + margin + float32(math.Floor(float64((area[3]-outerH-2*margin)*row/2)))
+
+	ui.layouts = append(ui.layouts, uiLayout{
+		kind:  layoutColumn,
+		id:    id,
+		panel: len(ui.panels),
+		opts:  opts,
+		x:     x + pad,
+		y:     y + pad,
+		w:     inner(w, pad),
+		h:     inner(h, pad),
+		fit:   m.size,
+	})
+}
+
+// Starts a column in the current layout, or at the top left of the current panel. Columns, rows and grids
+// are sized to fit their contents, use an area for a fixed size. End it with EndLayout
+func (ui *ui) BeginColumn(options ...LayoutOptions) {
+	ui.beginLayout(layoutColumn, 0, mgl32.Vec2{}, layoutOptions(options))
+}
+
+// Starts a row in the current layout, or at the top left of the current panel. End it with EndLayout
+func (ui *ui) BeginRow(options ...LayoutOptions) {
+	ui.beginLayout(layoutRow, 0, mgl32.Vec2{}, layoutOptions(options))
+}
+
+// Starts a grid that fills columns left to right, then moves down a row. A cell size of 0 fits the
+// largest widget last pass. End it with EndLayout
+func (ui *ui) BeginGrid(columns int, cellW, cellH float32, options ...LayoutOptions) {
+	if columns < 1 {
+		columns = 1
+	}
+	ui.beginLayout(layoutGrid, columns, mgl32.Vec2{cellW, cellH}, layoutOptions(options))
+}
+
+func (ui *ui) beginLayout(kind layoutKind, columns int, cell mgl32.Vec2, opts LayoutOptions) {
+	id := ui.getID("##layout" + strconv.Itoa(int(kind)))
+	m := ui.layoutSizes[id]
+	pad, margin := opts.Padding, opts.Margin
+
+	l := uiLayout{
+		kind:    kind,
+		id:      id,
+		panel:   len(ui.panels),
+		opts:    opts,
+		fit:     m.size,
+		columns: columns,
+	}
+	if parent := ui.layout(); parent != nil {
+		// sized to fit, so it can be aligned like a widget
+		outer := m.size.Add(mgl32.Vec2{2 * (pad + margin), 2 * (pad + margin)})
+		x, y := parent.next(outer[0], outer[1])
+		l.x, l.y = x+margin+pad, y+margin+pad
+		l.nested = true
+	} else {
+		x, y := ui.translate(0, 0)
+		l.x, l.y = x+margin+pad, y+margin+pad
+	}
+
+	if kind == layoutGrid {
+		l.cell = mgl32.Vec2{orFloat(cell[0], m.cell[0]), orFloat(cell[1], m.cell[1])}
+	}
+	ui.layouts = append(ui.layouts, l)
+}
+
+// Ends the innermost area, column, row or grid
+func (ui *ui) EndLayout() {
+	l := ui.layouts[len(ui.layouts)-1]
+	ui.layouts = ui.layouts[:len(ui.layouts)-1]
+	if ui.layoutSizes == nil {
+		ui.layoutSizes = make(map[int]layoutMeasure)
+	}
+	ui.layoutSizes[l.id] = layoutMeasure{size: l.size, cell: l.maxCell}
+
+	if !l.nested {
+		return
+	}
+	extra := 2 * (l.opts.Padding + l.opts.Margin)
+	if parent := ui.layout(); parent != nil {
+		parent.advance(l.size[0]+extra, l.size[1]+extra)
+	}
+}
+
+// Leaves a gap in the current column or row, or an empty cell in a grid
+func (ui *ui) Space(size float32) {
+	l := ui.layout()
+	if l == nil {
+		return
+	}
+	switch l.kind {
+	case layoutColumn:
+		l.advance(0, size)
+	case layoutRow:
+		l.advance(size, 0)
+	default:
+		l.advance(0, 0)
+	}
+}
+
+// Returns the innermost layout started in the current panel, or nil
+func (ui *ui) layout() *uiLayout {
+	if len(ui.layouts) == 0 {
+		return nil
+	}
+	l := &ui.layouts[len(ui.layouts)-1]
+	if l.panel != len(ui.panels) {
+		return nil
+	}
+	return l
+}
+
+// Positions a widget and works out its size. Outside a layout x, y is relative to the current panel.
+// Inside one the layout picks the position, and x, y nudges the widget from there, so is usually 0.
+// Sizes of 0 stretch to fill a column's width, a row's height or a grid cell, otherwise fit natural
+func (ui *ui) place(x, y, w, h float32, natural mgl32.Vec2) (float32, float32, float32, float32) {
+	l := ui.layout()
+	if l == nil {
+		x, y = ui.translate(x, y)
+		return x, y, orFloat(w, natural[0]), orFloat(h, natural[1])
+	}
+	w, h = l.fill(w, h, natural)
+	sx, sy := l.next(w, h)
+	l.advance(w, h)
+	return sx + x, sy + y, w, h
+}
+
+// Fills in sizes of 0, stretching across the layout where its size is known
+func (l *uiLayout) fill(w, h float32, natural mgl32.Vec2) (float32, float32) {
+	var stretchW, stretchH float32
+	switch l.kind {
+	case layoutColumn:
+		stretchW = l.w
+	case layoutRow:
+		stretchH = l.h
+	case layoutGrid:
+		stretchW, stretchH = l.cell[0], l.cell[1]
+	}
+	return orFloat(w, orFloat(stretchW, natural[0])), orFloat(h, orFloat(stretchH, natural[1]))
+}
+
+// Returns where a widget of size w, h goes next, without moving past it
+func (l *uiLayout) next(w, h float32) (float32, float32) {
+	var gap float32
+	if l.count > 0 {
+		gap = l.opts.Spacing
+	}
+	x, y := l.cursor[0], l.cursor[1]
+	switch l.kind {
+	case layoutColumn:
+		x = align(l.opts.Align, orFloat(l.w, l.fit[0]), w)
+		y += gap
+	case layoutRow:
+		x += gap
+		y = align(l.opts.Align, orFloat(l.h, l.fit[1]), h)
+	case layoutGrid:
+		col := l.count % l.columns
+		if col == 0 && l.count > 0 {
+			y += l.lineH + gap
+		}
+		x = float32(col)*(l.cell[0]+l.opts.Spacing) + align(l.opts.Align, l.cell[0], w)
+	}
+	return l.x + x, l.y + y
+}
+
+// Moves past a widget of size w, h
+func (l *uiLayout) advance(w, h float32) {
+	var gap float32
+	if l.count > 0 {
+		gap = l.opts.Spacing
+	}
+	switch l.kind {
+	case layoutColumn:
+		l.cursor[1] += gap + h
+		l.size = mgl32.Vec2{max32(l.size[0], w), l.cursor[1]}
+	case layoutRow:
+		l.cursor[0] += gap + w
+		l.size = mgl32.Vec2{l.cursor[0], max32(l.size[1], h)}
+	case layoutGrid:
+		col := l.count % l.columns
+		if col == 0 && l.count > 0 {
+			l.cursor[1] += l.lineH + gap
+			l.lineH = 0
+		}
+		l.lineH = max32(l.lineH, h)
+		right := float32(col)*(l.cell[0]+l.opts.Spacing) + max32(l.cell[0], w)
+		l.size = mgl32.Vec2{max32(l.size[0], right), l.cursor[1] + l.lineH}
+		l.maxCell = mgl32.Vec2{max32(l.maxCell[0], w), max32(l.maxCell[1], h)}
+	}
+	l.count++
+}
+
+// Offset of something size long in space, for the alignment
+func align(a Align, space, size float32) float32 {
+	if space <= size {
+		return 0
+	}
+	switch a {
+	case AlignCentre:
+		return float32(math.Floor(float64(space-size) / 2))
+	case AlignEnd:
+		return space - size
+	}
+	return 0
+}
+
+func layoutOptions(options []LayoutOptions) LayoutOptions {
+	if len(options) > 0 {
+		return options[0]
+	}
+	return LayoutOptions{}
+}
+
+// Size left inside padding on both sides, where 0 means sized to fit
+func inner(size, padding float32) float32 {
+	if size == 0 {
+		return 0
+	}
+	return max32(size-2*padding, 0.001)
+}
+
+// Returns v, or fallback if v is 0
+func orFloat(v, fallback float32) float32 {
+	if v == 0 {
+		return fallback
+	}
+	return v
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -21,10 +21,11 @@ func NewLight(x, y, z, r, g, b, f1, f2, f3, intensity float32) Light {
 	}
 }
 
-func (l Light) position(view, projection mgl32.Mat4) mgl32.Vec3 {
+// height is the height of the render target, as lights are positioned in framebuffer space
+func (l Light) position(view, projection mgl32.Mat4, height float32) mgl32.Vec3 {
 	pos := projection.Mul4x1(l.transform.Pos.Vec4(1))
 	pos = view.Mul4x1(pos)
 	pos = GetMatrix(l.transform).Mul4x1(pos)
-	pos[1] = ScreenH - pos[1]
+	pos[1] = height - pos[1]
 	return pos.Vec3()
 }
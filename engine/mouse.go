@@ -0,0 +1,253 @@
+package engine
+
+import (
+	"image"
+	"math"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const mouseButtonCount = 8 // GLFW's mouse button limit
+
+// A mouse button, e.g. MouseLeft. It has its own type since GLFW's button numbers overlap key codes,
+// so passing one to KeyDown won't compile
+type MouseButton int
+
+type CursorMode int
+
+const (
+	CursorNormal   CursorMode = iota
+	CursorHidden              // hidden while over the window, but free to leave it
+	CursorLocked              // hidden and held in place, only MouseDelta changes. For mouse look and drag cameras
+	CursorConfined            // visible, but kept inside the area the game is drawn in
+)
+
+type StandardCursor int
+
+const (
+	CursorArrow StandardCursor = iota
+	CursorIBeam
+	CursorCrosshair
+	CursorHand
+	CursorHResize
+	CursorVResize
+)
+
+// Mouse buttons are kept separate from keys, since GLFW's button numbers overlap key codes
+type mouseState struct {
+	down    [mouseButtonCount]bool
+	current [mouseButtonCount]bool // state at the last update
+	once    [mouseButtonCount]bool
+	up      [mouseButtonCount]bool
+
+	// edges since the last update, so a click pressed and released between two updates still counts
+	pressed  [mouseButtonCount]bool
+	released [mouseButtonCount]bool
+
+	scroll    mgl32.Vec2 // wheel movement during the last tick
+	scrollAcc mgl32.Vec2 // wheel movement since the last update
+	delta     mgl32.Vec2 // cursor movement during the last tick, in screen pixels
+	deltaAcc  mgl32.Vec2 // cursor movement since the last update, in screen pixels
+
+	lastX, lastY float64
+	hasLast      bool
+
+	mode   CursorMode
+	cursor *glfw.Cursor
+}
+
+func (i *input) MouseDown(button MouseButton) bool {
+	if i.paused() || !validMouseButton(int(button)) {
+		return false
+	}
+	return i.mouse.down[button]
+}
+
+// True on the first update the button is held
+func (i *input) MouseOnce(button MouseButton) bool {
+	if i.paused() || !validMouseButton(int(button)) {
+		return false
+	}
+	return i.mouse.once[button]
+}
+
+// True on the first update after the button is released
+func (i *input) MouseUp(button MouseButton) bool {
+	if i.paused() || !validMouseButton(int(button)) {
+		return false
+	}
+	return i.mouse.up[button]
+}
+
+// How far the wheel moved during the last tick. Y is positive scrolling up, X is for horizontal wheels and trackpads
+func (i *input) MouseWheel() mgl32.Vec2 {
+	if i.paused() {
+		return mgl32.Vec2{}
+	}
+	return i.mouse.scroll
+}
+
+// How far the cursor moved during the last tick, in screen pixels. Keeps working while the cursor is locked
+func (i *input) MouseDelta() mgl32.Vec2 {
+	if i.paused() {
+		return mgl32.Vec2{}
+	}
+	return i.mouse.delta
+}
+
+// Mouse position in the world, as seen by the camera passed to BeginScene
+func (i *input) MouseWorldPosition() mgl32.Vec2 {
+	return ScreenToWorld(Renderer.camera(), i.MousePosition())
+}
+
+// Converts a point in screen pixels to world coordinates for a camera
+func ScreenToWorld(c Camera, p mgl32.Vec2) mgl32.Vec2 {
+	return c.ViewMatrix().Inv().Mul4x1(mgl32.Vec4{p[0], p[1], 0, 1}).Vec2()
+}
+
+// Converts a point in world coordinates to screen pixels for a camera
+func WorldToScreen(c Camera, p mgl32.Vec2) mgl32.Vec2 {
+	return c.ViewMatrix().Mul4x1(mgl32.Vec4{p[0], p[1], 0, 1}).Vec2()
+}
+
+func (i *input) SetCursorMode(mode CursorMode) {
+	i.mouse.mode = mode
+	i.mouse.hasLast = false // the cursor jumps when GLFW locks or unlocks it
+	if i.window == nil {
+		return
+	}
+	switch mode {
+	case CursorHidden:
+		i.window.win.SetInputMode(glfw.CursorMode, glfw.CursorHidden)
+	case CursorLocked:
+		i.window.win.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
+	default:
+		i.window.win.SetInputMode(glfw.CursorMode, glfw.CursorNormal)
+	}
+}
+
+func (i *input) CursorMode() CursorMode {
+	return i.mouse.mode
+}
+
+// Uses an image as the cursor while it's over the window. hotX and hotY are the pixel that points
+func (i *input) SetCursor(img image.Image, hotX, hotY int) {
+	if i.window == nil {
+		return
+	}
+	i.setCursor(glfw.CreateCursor(img, hotX, hotY))
+}
+
+// Uses one of the system's cursors, like the hand shown over links
+func (i *input) SetStandardCursor(shape StandardCursor) {
+	if i.window == nil {
+		return
+	}
+	shapes := map[StandardCursor]glfw.StandardCursor{
+		CursorArrow:     glfw.ArrowCursor,
+		CursorIBeam:     glfw.IBeamCursor,
+		CursorCrosshair: glfw.CrosshairCursor,
+		CursorHand:      glfw.HandCursor,
+		CursorHResize:   glfw.HResizeCursor,
+		CursorVResize:   glfw.VResizeCursor,
+	}
+	i.setCursor(glfw.CreateStandardCursor(shapes[shape]))
+}
+
+// Goes back to the default arrow cursor
+func (i *input) ResetCursor() {
+	if i.window == nil {
+		return
+	}
+	i.setCursor(nil)
+}
+
+func (i *input) setCursor(c *glfw.Cursor) {
+	i.window.win.SetCursor(c)
+	if i.mouse.cursor != nil {
+		i.mouse.cursor.Destroy()
+	}
+	i.mouse.cursor = c
+}
+
+func validMouseButton(button int) bool {
+	return button >= 0 && button < mouseButtonCount
+}
+
+func (i *input) mouseButtonCallback(button int, action glfw.Action) {
+	if i.playback != nil || !validMouseButton(button) {
+		return
+	}
+	switch action {
+	case glfw.Press:
+		i.mouse.down[button] = true
+		i.mouse.pressed[button] = true
+	case glfw.Release:
+		i.mouse.down[button] = false
+		i.mouse.released[button] = true
+	}
+}
+
+func (i *input) scrollCallback(x, y float64) {
+	if i.playback != nil {
+		return
+	}
+	i.mouse.scrollAcc = i.mouse.scrollAcc.Add(mgl32.Vec2{float32(x), float32(y)})
+}
+
+func (i *input) cursorPosCallback(x, y float64) {
+	if i.playback != nil {
+		return
+	}
+	m := &i.mouse
+	if m.mode == CursorConfined && i.window != nil {
+		cx, cy := i.confine(x, y)
+		if cx != x || cy != y {
+			i.window.win.SetCursorPos(cx, cy)
+			x, y = cx, cy
+		}
+	}
+	if m.hasLast {
+		m.deltaAcc = m.deltaAcc.Add(i.windowToScreenDelta(float32(x-m.lastX), float32(y-m.lastY)))
+	}
+	m.lastX, m.lastY, m.hasLast = x, y, true
+}
+
+// Clamps a cursor position in window coordinates to the area the game is drawn in.
+// GLFW only reports the cursor while it's over the window, so it can still leave through the window's edge
+func (i *input) confine(x, y float64) (float64, float64) {
+	winW, winH := i.window.getSize()
+	if winW == 0 || winH == 0 {
+		return x, y
+	}
+	toWinX, toWinY := float64(winW/dispW), float64(winH/dispH)
+	sx, sy, sw, sh := ScreenRect()
+	minX, minY := float64(sx)*toWinX, float64(sy)*toWinY
+	maxX, maxY := float64(sx+sw)*toWinX-1, float64(sy+sh)*toWinY-1
+	return math.Max(minX, math.Min(maxX, x)), math.Max(minY, math.Min(maxY, y))
+}
+
+// Moves the state gathered by callbacks since the last update into what the next tick sees
+func (i *input) updateMouse() {
+	m := &i.mouse
+	for b := 0; b < mouseButtonCount; b++ {
+		m.once[b] = m.pressed[b] || (m.down[b] && !m.current[b])
+		m.up[b] = m.released[b]
+		m.pressed[b], m.released[b] = false, false
+		m.current[b] = m.down[b]
+	}
+
+	m.scroll, m.scrollAcc = m.scrollAcc, mgl32.Vec2{}
+	m.delta, m.deltaAcc = m.deltaAcc, mgl32.Vec2{}
+}
+
+// Scales a movement in window coordinates to screen pixels
+func (i *input) windowToScreenDelta(x, y float32) mgl32.Vec2 {
+	winW, winH := i.window.getSize()
+	if winW == 0 || winH == 0 || screen.scaleX == 0 || screen.scaleY == 0 {
+		return mgl32.Vec2{}
+	}
+	// window coordinates to framebuffer pixels to screen pixels
+	return mgl32.Vec2{x * dispW / winW / screen.scaleX, y * dispH / winH / screen.scaleY}
+}
@@ -0,0 +1,202 @@
+package engine
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Keyboard and gamepad input that moves focus between widgets, read once per pass
+type navInput struct {
+	dir      [2]int // arrow keys or d-pad, -1, 0 or 1 along x and y
+	tab      int    // 1 for Tab, -1 for Shift-Tab
+	activate bool   // Enter or A
+	cancel   bool   // Escape or B
+}
+
+// A widget that can take focus, registered as it's declared
+type focusEntry struct {
+	id    int
+	rect  mgl32.Vec4
+	layer int
+	panel int        // id of the scroll panel it's in, 0 if none
+	clip  mgl32.Vec4 // of that scroll panel
+}
+
+// The id of the widget with keyboard and gamepad focus, 0 if none
+func (ui *ui) Focused() int {
+	return ui.focusItem
+}
+
+// Gives focus to the next widget declared that can take it, as if navigated to.
+// Useful for focusing the first button when a menu opens
+func (ui *ui) SetFocus() {
+	ui.focusNext = true
+}
+
+func (ui *ui) readNav() {
+	in := ui.input
+	nav := navInput{
+		activate: in.KeyOnce(KeyEnter) || in.KeyOnce(KeyKPEnter) || in.ButtonOnce(AnyGamepad, GamepadA),
+		cancel:   in.KeyOnce(KeyEscape) || in.ButtonOnce(AnyGamepad, GamepadB),
+	}
+	if in.KeyRepeat(KeyTab) {
+		nav.tab = 1
+		if in.KeyDown(KeyLeftShift) || in.KeyDown(KeyRightShift) {
+			nav.tab = -1
+		}
+	}
+	if in.KeyRepeat(KeyLeft) || in.ButtonOnce(AnyGamepad, GamepadDpadLeft) {
+		nav.dir[0] = -1
+	} else if in.KeyRepeat(KeyRight) || in.ButtonOnce(AnyGamepad, GamepadDpadRight) {
+		nav.dir[0] = 1
+	}
+	if in.KeyRepeat(KeyUp) || in.ButtonOnce(AnyGamepad, GamepadDpadUp) {
+		nav.dir[1] = -1
+	} else if in.KeyRepeat(KeyDown) || in.ButtonOnce(AnyGamepad, GamepadDpadDown) {
+		nav.dir[1] = 1
+	}
+	ui.nav = nav
+	ui.navCapture = [2]bool{}
+}
+
+// Registers a widget that can take focus, and draws the focus outline around it.
+// Returns true if it has focus
+func (ui *ui) focusable(id int, x, y, w, h float32) bool {
+	if ui.disabled > 0 {
+		return false
+	}
+	e := focusEntry{id: id, rect: mgl32.Vec4{x, y, w, h}, layer: ui.layer}
+	for p := len(ui.panels) - 1; p >= 0; p-- {
+		if ui.panels[p].id != 0 {
+			e.panel, e.clip = ui.panels[p].id, ui.panels[p].clip
+			break
+		}
+	}
+	ui.focusables = append(ui.focusables, e)
+
+	if ui.focusNext {
+		ui.focusItem, ui.focusNext = id, false
+		ui.showFocus = true
+	}
+	if ui.focusItem != id {
+		return false
+	}
+	if ui.showFocus {
+		c := ui.theme.Focus
+		ui.fill(x-2, y-2, w+4, 2, c)
+		ui.fill(x-2, y+h, w+4, 2, c)
+		ui.fill(x-2, y, 2, h, c)
+		ui.fill(x+w, y, 2, h, c)
+	}
+	return true
+}
+
+// True on the pass Enter or A is pressed while the widget has focus
+func (ui *ui) activated(id int) bool {
+	if ui.focusItem != id || ui.disabled > 0 || (ui.lastModal && ui.layer == uiLayerBase) {
+		return false
+	}
+	return ui.nav.activate
+}
+
+// Arrow keys or d-pad along one axis for a focused widget that uses them, like a slider.
+// Returns -1, 0 or 1, and stops them moving focus that way
+func (ui *ui) navAxis(vertical bool) int {
+	axis := 0
+	if vertical {
+		axis = 1
+	}
+	ui.navCapture[axis] = true
+	return ui.nav.dir[axis]
+}
+
+// Moves focus for last pass's input, once every widget it declared has been registered
+func (ui *ui) navigate() {
+	entries := ui.focusables
+	if ui.modal {
+		// only the dialog's widgets
+		entries = entries[:0:0]
+		for _, e := range ui.focusables {
+			if e.layer != uiLayerBase {
+				entries = append(entries, e)
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+	current := -1
+	for n, e := range entries {
+		if e.id == ui.focusItem {
+			current = n
+		}
+	}
+
+	dir := ui.nav.dir
+	for axis := range dir {
+		if ui.navCapture[axis] {
+			dir[axis] = 0
+		}
+	}
+	next := -1
+	switch {
+	case ui.nav.tab != 0 && current < 0:
+		next = 0
+		if ui.nav.tab < 0 {
+			next = len(entries) - 1
+		}
+	case ui.nav.tab != 0:
+		next = (current + ui.nav.tab + len(entries)) % len(entries)
+	case dir == [2]int{}:
+		return
+	case current < 0:
+		next = 0
+	default:
+		next = nearestInDirection(entries, current, dir)
+	}
+	if next < 0 {
+		ui.showFocus = true // nothing that way, but show where focus is
+		return
+	}
+
+	e := entries[next]
+	if ui.activeItem == ui.focusItem {
+		ui.activeItem = 0 // stop editing a text input
+	}
+	if ui.openDropdown == ui.focusItem {
+		ui.openDropdown = 0
+	}
+	ui.focusItem = e.id
+	ui.showFocus = true
+	if e.panel != 0 {
+		// scroll it into view, the panel clamps it
+		if e.rect[1] < e.clip[1] {
+			ui.scroll[e.panel] -= e.clip[1] - e.rect[1]
+		} else if bottom := e.rect[1] + e.rect[3]; bottom > e.clip[1]+e.clip[3] {
+			ui.scroll[e.panel] += bottom - (e.clip[1] + e.clip[3])
+		}
+	}
+}
+
+// Finds the closest widget in the direction, favouring ones in line with the current widget.
+// Returns -1 if there isn't one
+func nearestInDirection(entries []focusEntry, current int, dir [2]int) int {
+	from := entries[current].rect
+	fromCentre := mgl32.Vec2{from[0] + from[2]/2, from[1] + from[3]/2}
+	best, bestScore := -1, float32(math.MaxFloat32)
+	for n, e := range entries {
+		if n == current {
+			continue
+		}
+		d := mgl32.Vec2{e.rect[0] + e.rect[2]/2, e.rect[1] + e.rect[3]/2}.Sub(fromCentre)
+		along, across := d[0]*float32(dir[0])+d[1]*float32(dir[1]), d[0]*float32(dir[1])+d[1]*float32(dir[0])
+		if along <= 0 {
+			continue
+		}
+		if score := along + 2*float32(math.Abs(float64(across))); score < bestScore {
+			best, bestScore = n, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,69 @@
+package engine
+
+import "github.com/go-gl/gl/v4.1-core/gl"
+
+// Built in post effects. Parameters can be changed at any time with the effect's setters,
+// e.g. Renderer.PostProcess().Get("vignette").SetFloat("strength", 0.5)
+
+// Makes areas brighter than threshold (0 - 1 luminance) glow. radius is the blur spread in pixels
+func NewBloom(threshold, radius, intensity float32) *PostEffect {
+	blur := NewPostShader(blurSource)
+	e := NewPostEffect("bloom",
+		NewPostShader(bloomBrightSource),
+		blur,
+		blur,
+		NewPostShader(bloomCombineSource),
+	)
+	e.SetFloat("threshold", threshold)
+	e.SetFloat("radius", radius)
+	e.SetFloat("intensity", intensity)
+	e.setPassParam(1, "direction", 1, 0)
+	e.setPassParam(2, "direction", 0, 1)
+	return e
+}
+
+// Darkens the edges of the screen. radius is where darkening starts, with 0.5 at the screen edge
+func NewVignette(radius, softness, strength float32) *PostEffect {
+	e := NewPostEffect("vignette", NewPostShader(vignetteSource))
+	e.SetFloat("radius", radius)
+	e.SetFloat("softness", softness)
+	e.SetFloat("strength", strength)
+	return e
+}
+
+// Remaps colours through a lookup table. The LUT is a strip of size cells, each size x size,
+// e.g. 256x16, with red increasing across each cell, green down it and blue from cell to cell.
+// intensity blends between the original (0) and graded (1) colours
+func NewColourGrade(lut Texture, intensity float32) *PostEffect {
+	if !headless {
+		// the table is small, so it has to be interpolated to avoid banding
+		gl.BindTexture(gl.TEXTURE_2D, lut.image.id)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	}
+
+	e := NewPostEffect("colourGrade", NewPostShader(colourGradeSource))
+	e.SetTexture("u_lut", lut)
+	e.SetFloat("lutSize", lut.image.height)
+	e.SetFloat("intensity", intensity)
+	return e
+}
+
+// Old monitor look. curvature bends the screen, scanlines (0 - 1) darkens alternate lines
+// and aberration offsets the red and blue channels by that many pixels
+func NewCRT(curvature, scanlines, aberration float32) *PostEffect {
+	e := NewPostEffect("crt", NewPostShader(crtSource))
+	e.SetFloat("curvature", curvature)
+	e.SetFloat("scanlines", scanlines)
+	e.SetFloat("aberration", aberration)
+	return e
+}
+
+// Snaps the screen to blocks of pixelSize screen pixels
+func NewPixelate(pixelSize float32) *PostEffect {
+	e := NewPostEffect("pixelate", NewPostShader(pixelateSource))
+	e.SetFloat("pixelSize", pixelSize)
+	return e
+}
@@ -0,0 +1,284 @@
+package engine
+
+import (
+	"os"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// A full screen effect made of one or more shader passes, each reading the output of the one before.
+// Pass fragment shaders get these uniforms as well as the effect's own parameters:
+//
+//	u_texture    sampler2D, output of the previous pass
+//	u_input      sampler2D, image the effect started with
+//	u_resolution vec2, size of the scene in pixels
+//	u_time       float, game time in seconds
+type PostEffect struct {
+	Name    string
+	Enabled bool
+
+	passes   []postPass
+	params   map[string][]float32
+	textures map[string]Image
+	order    []string // texture names in the order they were added, so units are stable
+}
+
+type postPass struct {
+	shader Shader
+	params map[string][]float32 // override the effect's parameters for this pass only
+}
+
+// Creates an enabled effect from shaders made with the post process vertex shader, see NewPostShader
+func NewPostEffect(name string, shaders ...Shader) *PostEffect {
+	e := &PostEffect{
+		Name:     name,
+		Enabled:  true,
+		params:   make(map[string][]float32),
+		textures: make(map[string]Image),
+	}
+	for _, s := range shaders {
+		e.passes = append(e.passes, postPass{shader: s, params: make(map[string][]float32)})
+	}
+	return e
+}
+
+// Creates an effect with a pass for each fragment shader file
+func NewPostEffectFromFiles(name string, fragmentPaths ...string) *PostEffect {
+	shaders := make([]Shader, 0, len(fragmentPaths))
+	for _, path := range fragmentPaths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			panic(err)
+		}
+		shaders = append(shaders, NewPostShader(string(src)))
+	}
+	return NewPostEffect(name, shaders...)
+}
+
+// Compiles a fragment shader with the full screen quad vertex shader used for post processing
+func NewPostShader(fragmentSrc string) Shader {
+	return NewShaderFromString(ppVertexShaderSource, fragmentSrc)
+}
+
+func (e *PostEffect) SetFloat(name string, v float32) {
+	e.params[name] = []float32{v}
+}
+
+func (e *PostEffect) SetVec2(name string, v mgl32.Vec2) {
+	e.params[name] = v[:]
+}
+
+func (e *PostEffect) SetVec3(name string, v mgl32.Vec3) {
+	e.params[name] = v[:]
+}
+
+func (e *PostEffect) SetVec4(name string, v mgl32.Vec4) {
+	e.params[name] = v[:]
+}
+
+// Binds the texture to the named sampler in every pass
+func (e *PostEffect) SetTexture(name string, t Texture) {
+	if _, ok := e.textures[name]; !ok {
+		e.order = append(e.order, name)
+	}
+	e.textures[name] = t.image
+}
+
+// Returns the value of a float parameter, or 0 if it isn't set
+func (e *PostEffect) Float(name string) float32 {
+	if v := e.params[name]; len(v) > 0 {
+		return v[0]
+	}
+	return 0
+}
+
+func (e *PostEffect) setPassParam(pass int, name string, v ...float32) {
+	e.passes[pass].params[name] = v
+}
+
+func (e *PostEffect) loadUniforms(p postPass, resolution mgl32.Vec2) {
+	s := p.shader
+	s.SetInt("u_texture", 0)
+	s.SetInt("u_input", 1)
+	s.SetVec2("u_resolution", resolution)
+	s.SetFloat("u_time", float32(Time.Now().Seconds()))
+
+	for i, name := range e.order {
+		gl.ActiveTexture(gl.TEXTURE2 + uint32(i))
+		e.textures[name].Use()
+		s.SetInt(name, int32(2+i))
+	}
+	gl.ActiveTexture(gl.TEXTURE0)
+
+	for _, params := range []map[string][]float32{e.params, p.params} {
+		for name, v := range params {
+			switch len(v) {
+			case 1:
+				s.SetFloat(name, v[0])
+			case 2:
+				s.SetVec2(name, mgl32.Vec2{v[0], v[1]})
+			case 3:
+				s.SetVec3(name, mgl32.Vec3{v[0], v[1], v[2]})
+			case 4:
+				s.SetVec4(name, mgl32.Vec4{v[0], v[1], v[2], v[3]})
+			}
+		}
+	}
+}
+
+// Ordered effects run on the scene after the post shader, before the UI is drawn.
+// Intermediate results go through a small pool of framebuffers, so only enabled effects cost anything.
+type PostChain struct {
+	effects    []*PostEffect
+	transition *PostEffect // scene transition, always runs last
+	buffers    []frameBuffer
+}
+
+// Appends an effect to the end of the chain
+func (c *PostChain) Add(e *PostEffect) {
+	c.effects = append(c.effects, e)
+}
+
+// Inserts an effect at index i, clamped to the chain's length
+func (c *PostChain) Insert(i int, e *PostEffect) {
+	if i < 0 {
+		i = 0
+	}
+	if i > len(c.effects) {
+		i = len(c.effects)
+	}
+	c.effects = append(c.effects[:i], append([]*PostEffect{e}, c.effects[i:]...)...)
+}
+
+// Removes the first effect with the given name
+func (c *PostChain) Remove(name string) {
+	for i, e := range c.effects {
+		if e.Name == name {
+			c.effects = append(c.effects[:i], c.effects[i+1:]...)
+			return
+		}
+	}
+}
+
+// Returns the first effect with the given name, or nil
+func (c *PostChain) Get(name string) *PostEffect {
+	for _, e := range c.effects {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Enables or disables the named effect. Returns false if it isn't in the chain
+func (c *PostChain) SetEnabled(name string, enabled bool) bool {
+	e := c.Get(name)
+	if e == nil {
+		return false
+	}
+	e.Enabled = enabled
+	return true
+}
+
+// Effects in the order they run
+func (c *PostChain) Effects() []*PostEffect {
+	return c.effects
+}
+
+func (c *PostChain) Clear() {
+	c.effects = nil
+}
+
+func (c *PostChain) active() []*PostEffect {
+	active := []*PostEffect{}
+	for _, e := range c.effects {
+		if e.Enabled && len(e.passes) > 0 {
+			active = append(active, e)
+		}
+	}
+	if c.transition != nil {
+		active = append(active, c.transition)
+	}
+	return active
+}
+
+// Draws the scene to the default framebuffer through the post shader and the enabled effects
+func (c *PostChain) run(post Shader, scene frameBuffer) {
+	active := c.active()
+	resolution := mgl32.Vec2{float32(scene.width), float32(scene.height)}
+	if len(active) == 0 {
+		useScreen()
+		post.Use()
+		drawPostPass(scene.tex.image, scene.tex.image)
+		return
+	}
+
+	c.allocate(scene.width, scene.height)
+	src := c.buffers[0]
+	src.use()
+	post.Use()
+	drawPostPass(scene.tex.image, scene.tex.image)
+
+	for i, e := range active {
+		input := src
+		for j, p := range e.passes {
+			last := i == len(active)-1 && j == len(e.passes)-1
+			dst := c.free(src, input)
+			if last {
+				useScreen()
+			} else {
+				dst.use()
+			}
+
+			p.shader.Use()
+			e.loadUniforms(p, resolution)
+			drawPostPass(src.tex.image, input.tex.image)
+			src = dst
+		}
+	}
+}
+
+// Returns a pool buffer that isn't being read from
+func (c *PostChain) free(reading ...frameBuffer) frameBuffer {
+	for _, b := range c.buffers {
+		used := false
+		for _, r := range reading {
+			used = used || r.id == b.id
+		}
+		if !used {
+			return b
+		}
+	}
+	return c.buffers[0]
+}
+
+// Three buffers are enough for an effect to read both its input and the previous pass while writing
+func (c *PostChain) allocate(width, height int32) {
+	if len(c.buffers) > 0 && c.buffers[0].width == width && c.buffers[0].height == height {
+		return
+	}
+	for i := range c.buffers {
+		c.buffers[i].resize(width, height)
+	}
+	for len(c.buffers) < 3 {
+		c.buffers = append(c.buffers, newFrameBuffer(width, height))
+	}
+}
+
+// Binds the default framebuffer, drawing into the part of the window the game is scaled to
+func useScreen() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	x, y, w, h := ScreenRect()
+	gl.Viewport(int32(x), int32(dispH-y-h), int32(w), int32(h)) // GL viewports start from the bottom
+}
+
+// Draws a full screen quad with source bound to unit 0 and input to unit 1, using the current shader
+func drawPostPass(source, input Image) {
+	gl.ActiveTexture(gl.TEXTURE1)
+	input.Use()
+	gl.ActiveTexture(gl.TEXTURE0)
+	source.Use()
+	gl.BindVertexArray(screenVAO)
+	gl.DrawElements(gl.TRIANGLES, screenInd, gl.UNSIGNED_INT, nil)
+}
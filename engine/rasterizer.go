@@ -0,0 +1,288 @@
+package engine
+
+import (
+	"image"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Software implementation of the default shaders, used by the HeadlessRenderer.
+// It follows the GL pipeline closely enough for golden image tests: nearest sampling,
+// a LESS depth test for the scene, alpha blending and the default exposure post pass.
+type rasterizer struct {
+	width   int
+	height  int
+	colour  []mgl32.Vec4
+	depth   []float32
+	lights  []softLight
+	ambient mgl32.Vec3
+}
+
+type softLight struct {
+	pos     mgl32.Vec3
+	colour  mgl32.Vec4
+	falloff mgl32.Vec3
+}
+
+type softVertex struct {
+	x, y, z float32
+	uv      mgl32.Vec2
+}
+
+func newRasterizer(width, height int) *rasterizer {
+	return &rasterizer{
+		width:  width,
+		height: height,
+		colour: make([]mgl32.Vec4, width*height),
+		depth:  make([]float32, width*height),
+	}
+}
+
+func (r *rasterizer) clear() {
+	for i := range r.colour {
+		r.colour[i] = mgl32.Vec4{}
+	}
+	r.clearDepth()
+}
+
+func (r *rasterizer) clearDepth() {
+	for i := range r.depth {
+		r.depth[i] = 1
+	}
+}
+
+func (r *rasterizer) setLights(lights []Light, view, projection mgl32.Mat4, ambient mgl32.Vec3) {
+	r.ambient = ambient
+	r.lights = r.lights[:0]
+	for i := 0; i < MAX_LIGHTS && i < len(lights); i++ {
+		r.lights = append(r.lights, softLight{
+			pos:     lights[i].position(view, projection, float32(r.height)),
+			colour:  lights[i].Colour,
+			falloff: lights[i].Falloffs,
+		})
+	}
+}
+
+// Mirrors fragmentShader.glsl
+func (r *rasterizer) drawScene(ri renderItem, mvp mgl32.Mat4, depthWrite bool) {
+	r.drawMesh(ri, mvp, func(x, y int, uv mgl32.Vec2, z float32) {
+		diffuse := sample(ri.image, uv)
+		if diffuse[3] < 0.1 {
+			return
+		}
+
+		i := y*r.width + x
+		if z >= r.depth[i] {
+			return
+		}
+		if depthWrite {
+			r.depth[i] = z
+		}
+
+		normal := mgl32.Vec3{0.5, 0.5, 1}
+		if ri.useNormals {
+			normal = sample(ri.normals, uv).Vec3()
+		}
+		n := normal.Mul(2).Sub(mgl32.Vec3{1, 1, 1}).Normalize()
+
+		fragX, fragY := float32(x)+0.5, float32(r.height-y)-0.5
+		var light mgl32.Vec3
+		for _, l := range r.lights {
+			if l.colour[3] == 0 {
+				continue
+			}
+			dir := mgl32.Vec3{l.pos[0] - fragX, l.pos[1] - fragY, l.pos[2]}
+			d := dir.Len()
+			f := l.falloff.Mul(1.0 / 1000)
+			attenuation := 1 / (f[0] + f[1]*d + f[2]*d*d)
+			lambert := float32(math.Max(float64(n.Dot(dir.Normalize())), 0))
+			light = light.Add(l.colour.Vec3().Mul(l.colour[3] * attenuation * lambert))
+		}
+
+		intensity := r.ambient.Add(light)
+		for c := range intensity {
+			intensity[c] = float32(math.Min(1, float64(intensity[c])))
+		}
+		r.blend(i, mgl32.Vec4{
+			diffuse[0] * intensity[0],
+			diffuse[1] * intensity[1],
+			diffuse[2] * intensity[2],
+			diffuse[3],
+		})
+	})
+}
+
+// Mirrors uiFragment.glsl. UI is drawn without a depth test, in submission order
+func (r *rasterizer) drawUI(ri renderItem, mvp mgl32.Mat4) {
+	clip := ri.clip
+	r.drawMesh(ri, mvp, func(x, y int, uv mgl32.Vec2, z float32) {
+		if clip[2] > 0 && (float32(x) < clip[0] || float32(y) < clip[1] || float32(x) >= clip[0]+clip[2] || float32(y) >= clip[1]+clip[3]) {
+			return
+		}
+		if ri.sdf != nil {
+			r.blend(y*r.width+x, ri.sdf.shade(ri.image, uv, ri.colour))
+			return
+		}
+		texel := sample(ri.image, uv)
+		r.blend(y*r.width+x, mgl32.Vec4{
+			texel[0] * ri.colour[0],
+			texel[1] * ri.colour[1],
+			texel[2] * ri.colour[2],
+			texel[3] * ri.colour[3],
+		})
+	})
+}
+
+// Mirrors postprocessFragment.glsl
+func (r *rasterizer) toneMap(exposure float32) {
+	for i, c := range r.colour {
+		for j := 0; j < 3; j++ {
+			c[j] = 1 - float32(math.Exp(float64(-c[j]*exposure)))
+		}
+		r.colour[i] = c
+	}
+}
+
+// glBlendFunc(GL_SRC_ALPHA, GL_ONE_MINUS_SRC_ALPHA)
+func (r *rasterizer) blend(i int, src mgl32.Vec4) {
+	a := src[3]
+	r.colour[i] = src.Mul(a).Add(r.colour[i].Mul(1 - a))
+}
+
+func (r *rasterizer) image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, r.width, r.height))
+	for i, c := range r.colour {
+		for j := 0; j < 4; j++ {
+			img.Pix[i*4+j] = uint8(mgl32.Clamp(c[j], 0, 1)*255 + 0.5)
+		}
+	}
+	return img
+}
+
+func (r *rasterizer) drawMesh(ri renderItem, mvp mgl32.Mat4, frag func(x, y int, uv mgl32.Vec2, z float32)) {
+	mesh, ok := softMeshes[ri.vao]
+	if ri.vertices != nil {
+		mesh, ok = &softMesh{vertices: ri.vertices, indices: []uint32{0, 1, 3, 1, 2, 3}}, true
+		if ri.elements != nil {
+			mesh.indices = ri.elements
+		}
+	}
+	if !ok {
+		return
+	}
+
+	count := int(ri.indices)
+	if ri.vertices != nil {
+		count = len(mesh.indices)
+	}
+	if count > len(mesh.indices) {
+		count = len(mesh.indices)
+	}
+	for t := 0; t+2 < count; t += 3 {
+		var tri [3]softVertex
+		for k := 0; k < 3; k++ {
+			v := mesh.vertices[mesh.indices[t+k]*5:]
+			ndc := mvp.Mul4x1(mgl32.Vec4{v[0], v[1], v[2], 1})
+			tri[k] = softVertex{
+				x:  (ndc[0]/ndc[3] + 1) / 2 * float32(r.width),
+				y:  (1 - ndc[1]/ndc[3]) / 2 * float32(r.height),
+				z:  (ndc[2]/ndc[3] + 1) / 2,
+				uv: mgl32.Vec2{v[3], v[4]},
+			}
+		}
+		r.triangle(tri, frag)
+	}
+}
+
+// Rasterizes a triangle using edge functions, sampling at pixel centres with a top-left fill rule
+func (r *rasterizer) triangle(t [3]softVertex, frag func(x, y int, uv mgl32.Vec2, z float32)) {
+	area := edge(t[0], t[1], t[2].x, t[2].y)
+	if area == 0 {
+		return
+	}
+	if area < 0 {
+		t[1], t[2] = t[2], t[1]
+		area = -area
+	}
+
+	minX := int(math.Max(0, math.Floor(float64(min3(t[0].x, t[1].x, t[2].x)))))
+	maxX := int(math.Min(float64(r.width-1), math.Ceil(float64(max3(t[0].x, t[1].x, t[2].x)))))
+	minY := int(math.Max(0, math.Floor(float64(min3(t[0].y, t[1].y, t[2].y)))))
+	maxY := int(math.Min(float64(r.height-1), math.Ceil(float64(max3(t[0].y, t[1].y, t[2].y)))))
+
+	for y := minY; y <= maxY; y++ {
+		py := float32(y) + 0.5
+		for x := minX; x <= maxX; x++ {
+			px := float32(x) + 0.5
+			w0 := edge(t[1], t[2], px, py)
+			w1 := edge(t[2], t[0], px, py)
+			w2 := edge(t[0], t[1], px, py)
+			if !inside(w0, t[1], t[2]) || !inside(w1, t[2], t[0]) || !inside(w2, t[0], t[1]) {
+				continue
+			}
+
+			w0, w1, w2 = w0/area, w1/area, w2/area
+			uv := t[0].uv.Mul(w0).Add(t[1].uv.Mul(w1)).Add(t[2].uv.Mul(w2))
+			z := t[0].z*w0 + t[1].z*w1 + t[2].z*w2
+			frag(x, y, uv, z)
+		}
+	}
+}
+
+func edge(a, b softVertex, x, y float32) float32 {
+	return (b.x-a.x)*(y-a.y) - (b.y-a.y)*(x-a.x)
+}
+
+// Pixels exactly on an edge are only drawn for top and left edges, so shared edges aren't drawn twice
+func inside(w float32, a, b softVertex) bool {
+	if w != 0 {
+		return w > 0
+	}
+	dx, dy := b.x-a.x, b.y-a.y
+	return (dy == 0 && dx < 0) || dy > 0
+}
+
+// Nearest neighbour lookup with repeat wrapping
+func sample(img Image, uv mgl32.Vec2) mgl32.Vec4 {
+	if img.pixels == nil || img.pixels.Bounds().Empty() {
+		return mgl32.Vec4{}
+	}
+	b := img.pixels.Bounds()
+	x := int(math.Floor(float64(uv[0] * float32(b.Dx()))))
+	y := int(math.Floor(float64(uv[1] * float32(b.Dy()))))
+	x = ((x % b.Dx()) + b.Dx()) % b.Dx()
+	y = ((y % b.Dy()) + b.Dy()) % b.Dy()
+
+	p := img.pixels.Pix[img.pixels.PixOffset(x, y):]
+	return mgl32.Vec4{float32(p[0]) / 255, float32(p[1]) / 255, float32(p[2]) / 255, float32(p[3]) / 255}
+}
+
+// Bilinear filtering, clamped to the edges like the glyph atlas
+func sampleLinear(img Image, uv mgl32.Vec2) mgl32.Vec4 {
+	if img.pixels == nil || img.pixels.Bounds().Empty() {
+		return mgl32.Vec4{}
+	}
+	b := img.pixels.Bounds()
+	fx := uv[0]*float32(b.Dx()) - 0.5
+	fy := uv[1]*float32(b.Dy()) - 0.5
+	x0, y0 := int(math.Floor(float64(fx))), int(math.Floor(float64(fy)))
+	tx, ty := fx-float32(x0), fy-float32(y0)
+	texel := func(x, y int) mgl32.Vec4 {
+		x = clampInt(x, 0, b.Dx()-1)
+		y = clampInt(y, 0, b.Dy()-1)
+		p := img.pixels.Pix[img.pixels.PixOffset(x, y):]
+		return mgl32.Vec4{float32(p[0]) / 255, float32(p[1]) / 255, float32(p[2]) / 255, float32(p[3]) / 255}
+	}
+	top := texel(x0, y0).Mul(1 - tx).Add(texel(x0+1, y0).Mul(tx))
+	bottom := texel(x0, y0+1).Mul(1 - tx).Add(texel(x0+1, y0+1).Mul(tx))
+	return top.Mul(1 - ty).Add(bottom.Mul(ty))
+}
+
+func min3(a, b, c float32) float32 {
+	return float32(math.Min(float64(a), math.Min(float64(b), float64(c))))
+}
+
+func max3(a, b, c float32) float32 {
+	return float32(math.Max(float64(a), math.Max(float64(b), float64(c))))
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"image"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// An offscreen canvas. Sprites, tilemaps, lights and text pushed to it are drawn into its texture
+// each frame it is pushed to the renderer with Renderer.PushTarget, before the main scene.
+// The texture can then be used by sprites like any other, e.g. for minimaps or split screen.
+type RenderTarget struct {
+	ClearColour  mgl32.Vec4
+	fb           frameBuffer
+	projection   mgl32.Mat4
+	camera       Camera
+	ambientLight mgl32.Vec3
+	items        []renderItem
+	lights       []Light
+	overlay      []renderItem // text, drawn on top of the scene without lighting
+
+	raster *rasterizer // headless only
+}
+
+func NewRenderTarget(width, height int) *RenderTarget {
+	t := &RenderTarget{
+		fb:           newFrameBuffer(int32(width), int32(height)),
+		projection:   mgl32.Ortho(0, float32(width), float32(height), 0, -0.1, 10.1),
+		camera:       Camera2D{},
+		ambientLight: mgl32.Vec3{1, 1, 1},
+	}
+	if headless {
+		t.raster = newRasterizer(width, height)
+	}
+	return t
+}
+
+func (t *RenderTarget) Width() int {
+	return int(t.fb.width)
+}
+
+func (t *RenderTarget) Height() int {
+	return int(t.fb.height)
+}
+
+// The target's contents. Framebuffers are stored bottom up, so the texture is flipped to display upright
+func (t *RenderTarget) Texture() Texture {
+	return Texture{
+		image:     t.fb.tex.image,
+		texCoords: mgl32.Vec4{0, 1, 1, 0},
+	}
+}
+
+// Resizes the target in place, so textures and sprites already using it stay valid.
+// The contents are cleared.
+func (t *RenderTarget) Resize(width, height int) {
+	if width == int(t.fb.width) && height == int(t.fb.height) {
+		return
+	}
+	t.fb.resize(int32(width), int32(height))
+	t.projection = mgl32.Ortho(0, float32(width), float32(height), 0, -0.1, 10.1)
+	if headless {
+		t.raster = newRasterizer(width, height)
+	}
+}
+
+// Clears everything pushed to the target last frame, and sets the camera and ambient light to draw it with
+func (t *RenderTarget) Begin(c Camera, ambientLight mgl32.Vec3) {
+	t.items = []renderItem{}
+	t.lights = []Light{}
+	t.overlay = []renderItem{}
+	t.camera = c
+	t.ambientLight = ambientLight
+}
+
+func (t *RenderTarget) Push(renderable renderable) {
+	t.items = append(t.items, renderable.renderItem()...)
+}
+
+func (t *RenderTarget) PushLight(light Light) {
+	t.lights = append(t.lights, light)
+}
+
+// Draws text on top of the target's scene, in target pixel coordinates. Returns the size of the text
+func (t *RenderTarget) PushText(font *Font, text string, x, y float32, fontSize int, colour mgl32.Vec4) mgl32.Vec2 {
+	printData := font.renderItem(x, y, fontSize, text)
+	t.overlay = append(t.overlay, printData.coloured(colour)...)
+	return printData.size
+}
+
+// Reads the target's contents back from the GPU, top row first.
+// This stalls until rendering has finished, so avoid calling it every frame.
+func (t *RenderTarget) Pixels() *image.RGBA {
+	if headless {
+		return t.raster.image()
+	}
+
+	w, h := int(t.fb.width), int(t.fb.height)
+	pixels := make([]byte, w*h*4)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, t.fb.id)
+	gl.ReadPixels(0, 0, t.fb.width, t.fb.height, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(pixels))
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	stride := w * 4
+	for y := 0; y < h; y++ {
+		copy(img.Pix[y*stride:(y+1)*stride], pixels[(h-1-y)*stride:(h-y)*stride])
+	}
+	return img
+}
+
+func (r *renderer) renderTarget(t *RenderTarget) {
+	t.fb.use()
+	gl.Enable(gl.DEPTH_TEST)
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	gl.ClearColor(t.ClearColour[0], t.ClearColour[1], t.ClearColour[2], t.ClearColour[3])
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	view := t.camera.ViewMatrix()
+	objectShader.Use()
+	objectShader.SetVec4("ambientLight", t.ambientLight.Vec4(1))
+	pushLightUniforms(t.lights, view, t.projection, float32(t.fb.height))
+	r.drawScene(t.items, view, t.projection)
+
+	gl.Clear(gl.DEPTH_BUFFER_BIT)
+	drawUI(t.overlay, t.projection, func(clip mgl32.Vec4) (int32, int32, int32, int32) {
+		return int32(clip[0]), int32(float32(t.fb.height) - clip[1] - clip[3]), int32(clip[2]), int32(clip[3])
+	})
+}
+
+func (r *HeadlessRenderer) renderTarget(t *RenderTarget) {
+	raster := t.raster
+	raster.clear()
+	for i := range raster.colour {
+		raster.colour[i] = t.ClearColour
+	}
+
+	r.drawScene(raster, t.items, t.lights, t.camera.ViewMatrix(), t.projection, t.ambientLight)
+	drawSoftUI(raster, t.overlay, t.projection)
+
+	// stored bottom up like a GL framebuffer, so sampling with the flipped texture coords matches
+	img := raster.image()
+	dst := t.fb.tex.image.pixels
+	stride := img.Stride
+	h := img.Bounds().Dy()
+	for y := 0; y < h; y++ {
+		copy(dst.Pix[y*stride:(y+1)*stride], img.Pix[(h-1-y)*stride:(h-y)*stride])
+	}
+}
@@ -1,6 +1,8 @@
 package engine
 
 import (
+	"image"
+
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
 )
@@ -8,7 +10,8 @@ import (
 const MAX_LIGHTS = 15
 
 type renderer struct {
-	renderBuffer    map[Image][]renderItem
+	renderBuffer    []renderItem
+	ySort           map[int]bool // layers sorted by y
 	uiBuffer        []renderItem
 	ambientLight    mgl32.Vec3
 	activeCam       Camera
@@ -17,10 +20,16 @@ type renderer struct {
 	lights          []Light
 	postFB          frameBuffer
 	screenTransform Transform
+	batch           *spriteBatch
+	stats           RenderStats
+	targets         []*RenderTarget
+	post            *PostChain
 }
 
 type renderItem struct {
 	vao        uint32
+	vertices   []float32 // local space quad for sprites, drawn through the batcher instead of vao
+	elements   []uint32  // UI only, indices into vertices for meshes streamed through uiStream instead of vao
 	indices    int32
 	shader     Shader
 	image      Image
@@ -28,6 +37,10 @@ type renderItem struct {
 	normals    Image
 	transform  Transform
 	colour     mgl32.Vec4
+	layer      int
+	sortY      float32    // used to order items on y-sorted layers, usually the bottom edge
+	clip       mgl32.Vec4 // UI only, {x, y, w, h} in screen pixels to clip to. Zero width doesn't clip
+	sdf        *sdfText   // UI only, drawn with the signed distance field text shader if set
 }
 
 type Renderer2D interface {
@@ -35,9 +48,15 @@ type Renderer2D interface {
 	PushItem(renderable)
 	PushLight(Light)
 	PushUI(renderItem)
+	PushTarget(*RenderTarget)
 	SetPostShader(string)
+	PostProcess() *PostChain
+	SetYSort(layer int, enabled bool)
+	Stats() RenderStats
 	render()
 	beginUI()
+	resize(width, height float32)
+	camera() Camera
 }
 
 type renderable interface {
@@ -49,6 +68,7 @@ var screenInd int32
 
 var objectShader defaultShader
 var uiShader defaultShader
+var sdfShader defaultShader
 var postShader postprocessShader
 
 type defaultShader struct {
@@ -70,6 +90,7 @@ func Renderer2DInit(width, height float32) Renderer2D {
 	shaderMap = make(map[string]Shader)
 	objectShader = defaultShader{NewShaderFromString(vertexShaderSource, fragmentShaderSource)}
 	uiShader = defaultShader{NewShaderFromString(vertexShaderSource, uiFragmentSource)}
+	sdfShader = defaultShader{NewShaderFromString(vertexShaderSource, sdfTextFragmentSource)}
 	postShader = postprocessShader{NewShaderFromString(ppVertexShaderSource, ppFragmentShaderSource)}
 
 	screenVAO, _, screenInd = screenQuadVAO()
@@ -77,19 +98,23 @@ func Renderer2DInit(width, height float32) Renderer2D {
 	fb := newFrameBuffer(int32(width), int32(height))
 
 	orthoProjection := mgl32.Ortho(0, width, height, 0, -0.1, 10.1)
-	return &renderer{
-		renderBuffer: make(map[Image][]renderItem),
+	r := &renderer{
+		renderBuffer: []renderItem{},
+		ySort:        make(map[int]bool),
 		uiBuffer:     []renderItem{},
 		lights:       []Light{},
 		projection:   orthoProjection,
 		activeCam:    Camera2D{},
 		postShader:   postShader.Shader,
 		postFB:       fb,
+		post:         &PostChain{},
 		ambientLight: mgl32.Vec3{1, 1, 1},
 	}
+	r.batch = newSpriteBatch(&r.stats)
+	return r
 }
 
-func pushLightUniforms(lights []Light, view, projection mgl32.Mat4) {
+func pushLightUniforms(lights []Light, view, projection mgl32.Mat4, height float32) {
 	positions := make([]float32, 0, MAX_LIGHTS*3) // vec3
 	falloffs := make([]float32, 0, MAX_LIGHTS*3)  // vec3
 	colours := make([]float32, 0, MAX_LIGHTS*4)   // vec4
@@ -97,7 +122,7 @@ func pushLightUniforms(lights []Light, view, projection mgl32.Mat4) {
 	for i := 0; i < MAX_LIGHTS; i++ {
 		if i < len(lights) {
 			light := lights[i]
-			position := light.position(view, projection)
+			position := light.position(view, projection, height)
 
 			positions = append(positions, position[0], position[1], position[2])
 			falloffs = append(falloffs, light.Falloffs[0], light.Falloffs[1], light.Falloffs[2])
@@ -115,16 +140,16 @@ func pushLightUniforms(lights []Light, view, projection mgl32.Mat4) {
 }
 
 func (r *renderer) BeginScene(c Camera, ambientLight mgl32.Vec3, exposure float32) {
-	r.renderBuffer = make(map[Image][]renderItem)
+	r.renderBuffer = []renderItem{}
 	r.lights = []Light{}
 	r.uiBuffer = []renderItem{}
+	r.targets = []*RenderTarget{}
 	r.activeCam = c
 	r.ambientLight = ambientLight
 
 	objectShader.Use()
 	objectShader.SetInt("u_texture", 0) //GL_TEXTURE0
 	objectShader.SetInt("u_normals", 1) //GL_TEXTURE1
-	objectShader.SetVec4("ambientLight", r.ambientLight.Vec4(1))
 
 	r.postShader.Use()
 	r.postShader.SetFloat("exposure", exposure)
@@ -135,9 +160,7 @@ func (r *renderer) beginUI() {
 }
 
 func (r *renderer) PushItem(renderable renderable) {
-	for _, ri := range renderable.renderItem() {
-		r.renderBuffer[ri.image] = append(r.renderBuffer[ri.image], ri)
-	}
+	r.renderBuffer = append(r.renderBuffer, renderable.renderItem()...)
 }
 
 func (r *renderer) PushLight(light Light) {
@@ -149,6 +172,11 @@ func (r *renderer) PushUI(ri renderItem) {
 	r.uiBuffer = append(r.uiBuffer, ri)
 }
 
+// Renders the target's contents this frame, before the scene is drawn
+func (r *renderer) PushTarget(t *RenderTarget) {
+	r.targets = append(r.targets, t)
+}
+
 func (r *renderer) SetPostShader(name string) {
 	shader, ok := shaderMap[name]
 	if !ok {
@@ -158,8 +186,26 @@ func (r *renderer) SetPostShader(name string) {
 	r.postShader = shader
 }
 
+// Rebuilds the projection and scene framebuffer for a new logical resolution
+func (r *renderer) resize(width, height float32) {
+	r.projection = mgl32.Ortho(0, width, height, 0, -0.1, 10.1)
+	r.postFB.resize(int32(width), int32(height))
+}
+
+// Effects applied to the scene after the post shader
+func (r *renderer) PostProcess() *PostChain {
+	return r.post
+}
+
 // TODO (Ross): Filter by shaders, types etc
 func (r *renderer) render() {
+	r.stats = RenderStats{}
+
+	// Offscreen targets first, so the scene can use them as textures
+	for _, t := range r.targets {
+		r.renderTarget(t)
+	}
+
 	// Bind scene framebuffer, render to texture
 	r.postFB.use()
 	gl.Enable(gl.DEPTH_TEST)
@@ -168,12 +214,55 @@ func (r *renderer) render() {
 	gl.ClearColor(0, 0, 0, 0)
 	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 
+	view := r.activeCam.ViewMatrix()
 	objectShader.Use()
-	pushLightUniforms(r.lights, r.activeCam.ViewMatrix(), r.projection)
-	for _, v := range r.renderBuffer {
-		gl.ActiveTexture(gl.TEXTURE0)
-		v[0].image.Use()
-		for _, ri := range v {
+	objectShader.SetVec4("ambientLight", r.ambientLight.Vec4(1))
+	pushLightUniforms(r.lights, view, r.projection, ScreenH)
+	r.drawScene(r.renderBuffer, view, r.projection)
+
+	// now bind back to default framebuffer and draw a quad plane with the attached framebuffer color texture,
+	// through any post effects
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.Disable(gl.DEPTH_TEST) // disable depth test so screen-space quad isn't discarded due to depth test.
+	gl.Disable(gl.BLEND)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	r.postShader.Use()
+	r.postShader.SetInt("u_texture", 0) //GL_TEXTURE0
+	r.post.run(r.postShader, r.postFB)
+
+	// Render UI on top
+	gl.Clear(gl.DEPTH_BUFFER_BIT)
+	gl.Enable(gl.BLEND)
+	drawUI(r.uiBuffer, r.projection, screenScissor)
+}
+
+// Draws scene items into the bound framebuffer with the object shader, which must already be in use
+func (r *renderer) drawScene(items []renderItem, view, projection mgl32.Mat4) {
+	r.stats.Items += len(items)
+
+	sortRenderItems(items, r.ySort)
+	layerRuns(items, func(layer []renderItem) {
+		gl.DepthMask(true)
+		gl.Clear(gl.DEPTH_BUFFER_BIT)
+
+		translucentPass := false
+		for _, ri := range layer {
+			if ri.image.translucent && !translucentPass {
+				// Opaque items come first, switch to the translucent pass once we reach them
+				r.batch.flush(view, projection)
+				gl.DepthMask(false)
+				translucentPass = true
+			}
+
+			if ri.vertices != nil {
+				r.batch.add(ri, view, projection)
+				continue
+			}
+			r.batch.flush(view, projection)
+
+			gl.ActiveTexture(gl.TEXTURE0)
+			ri.image.Use()
 			if ri.useNormals {
 				// load normal uniforms
 				objectShader.SetBool("useNormals", true)
@@ -183,43 +272,97 @@ func (r *renderer) render() {
 				objectShader.SetBool("useNormals", false)
 			}
 
-			objectShader.loadUniforms(GetMatrix(ri.transform), r.activeCam.ViewMatrix(), r.projection)
+			objectShader.loadUniforms(GetMatrix(ri.transform), view, projection)
 			gl.BindVertexArray(ri.vao)
 			gl.DrawElements(gl.TRIANGLES, ri.indices, gl.UNSIGNED_INT, nil)
 			gl.ActiveTexture(gl.TEXTURE0)
+			r.stats.DrawCalls++
 		}
-	}
-
-	// now bind back to default framebuffer and draw a quad plane with the attached framebuffer color texture
-	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
-	gl.Disable(gl.DEPTH_TEST) // disable depth test so screen-space quad isn't discarded due to depth test.
-	gl.Disable(gl.BLEND)
-	gl.Clear(gl.COLOR_BUFFER_BIT)
-	gl.Viewport(0, 0, int32(dispW), int32(dispH))
-
-	r.postShader.Use()
-	r.postShader.SetInt("u_texture", 0) //GL_TEXTURE0
-	r.postFB.tex.image.Use()
-	gl.BindVertexArray(screenVAO)
-	gl.BindTexture(gl.TEXTURE_2D, r.postFB.tex.image.id)
-	gl.DrawElements(gl.TRIANGLES, screenInd, gl.UNSIGNED_INT, nil)
-
-	// Render UI on top
-	// Create UI batch
+		r.batch.flush(view, projection)
+	})
+	gl.DepthMask(true)
+}
 
-	gl.Clear(gl.DEPTH_BUFFER_BIT)
-	gl.Enable(gl.BLEND)
-	uiShader.Use()
+// Draws UI items in order with the UI shader, without depth testing.
+// scissor converts an item's clip rectangle to the bound framebuffer's pixels
+func drawUI(items []renderItem, projection mgl32.Mat4, scissor func(clip mgl32.Vec4) (int32, int32, int32, int32)) {
 	gl.ActiveTexture(gl.TEXTURE0)
-	for _, v := range r.uiBuffer {
+	defer gl.Disable(gl.SCISSOR_TEST)
+	for _, v := range items {
+		if v.clip[2] > 0 {
+			gl.Enable(gl.SCISSOR_TEST)
+			gl.Scissor(scissor(v.clip))
+		} else {
+			gl.Disable(gl.SCISSOR_TEST)
+		}
+		shader := uiShader
+		if v.sdf != nil {
+			shader = sdfShader
+		}
+		shader.Use()
 		v.image.Use()
-		uiShader.loadUniforms(GetMatrix(v.transform), mgl32.Translate3D(0, 0, -10), r.projection)
-		uiShader.SetVec4("u_colour", v.colour)
+		shader.loadUniforms(GetMatrix(v.transform), mgl32.Translate3D(0, 0, -10), projection)
+		shader.SetVec4("u_colour", v.colour)
+		if v.sdf != nil {
+			v.sdf.setUniforms(shader.Shader)
+		}
+		if v.elements != nil {
+			uiStream.draw(v.vertices, v.elements)
+			continue
+		}
 		gl.BindVertexArray(v.vao)
 		gl.DrawElements(gl.TRIANGLES, v.indices, gl.UNSIGNED_INT, nil)
 	}
 }
 
+// Widget quads change every pass, so they share one buffer that is refilled for each draw
+// rather than each getting a vao of their own
+var uiStream streamBuffer
+
+type streamBuffer struct {
+	vao, vbo, ebo uint32
+}
+
+func (b *streamBuffer) draw(p []float32, i []uint32) {
+	if b.vao == 0 {
+		gl.GenVertexArrays(1, &b.vao)
+		gl.GenBuffers(1, &b.vbo)
+		gl.GenBuffers(1, &b.ebo)
+
+		gl.BindVertexArray(b.vao)
+		gl.BindBuffer(gl.ARRAY_BUFFER, b.vbo)
+		gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, b.ebo)
+		gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 5*4, nil)
+		gl.EnableVertexAttribArray(0)
+		gl.VertexAttribPointerWithOffset(1, 2, gl.FLOAT, false, 5*4, 3*4)
+		gl.EnableVertexAttribArray(1)
+	}
+
+	gl.BindVertexArray(b.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, b.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, 4*len(p), gl.Ptr(p), gl.STREAM_DRAW) // orphans the last draw's data
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, 4*len(i), gl.Ptr(i), gl.STREAM_DRAW)
+	gl.DrawElements(gl.TRIANGLES, int32(len(i)), gl.UNSIGNED_INT, nil)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+}
+
+// Maps a clip rectangle in screen pixels to the window's framebuffer, which GL counts from the bottom
+func screenScissor(clip mgl32.Vec4) (int32, int32, int32, int32) {
+	x, y := screen.x+clip[0]*screen.scaleX, screen.y+clip[1]*screen.scaleY
+	w, h := clip[2]*screen.scaleX, clip[3]*screen.scaleY
+	return int32(x), int32(dispH - y - h), int32(w), int32(h)
+}
+
+// Items on a y-sorted layer that share a z are drawn in order of their bottom edge,
+// so things lower on screen appear in front. Used for top down games
+func (r *renderer) SetYSort(layer int, enabled bool) {
+	r.ySort[layer] = enabled
+}
+
+func (r *renderer) Stats() RenderStats {
+	return r.stats
+}
+
 type frameBuffer struct {
 	id     uint32
 	rbo    uint32
@@ -230,6 +373,10 @@ type frameBuffer struct {
 }
 
 func newFrameBuffer(w, h int32) frameBuffer {
+	if headless {
+		return frameBuffer{tex: NewBlankTexture(float32(w), float32(h)), width: w, height: h}
+	}
+
 	var fbo uint32
 	gl.GenFramebuffers(1, &fbo)
 	gl.BindFramebuffer(gl.FRAMEBUFFER, fbo)
@@ -257,6 +404,21 @@ func newFrameBuffer(w, h int32) frameBuffer {
 	}
 }
 
+// Reallocates the colour and depth storage, keeping the same GL objects
+func (f *frameBuffer) resize(w, h int32) {
+	f.width, f.height = w, h
+	f.tex.image.width, f.tex.image.height = float32(w), float32(h)
+	if headless {
+		*f.tex.image.pixels = *image.NewRGBA(image.Rect(0, 0, int(w), int(h)))
+		return
+	}
+
+	gl.BindTexture(gl.TEXTURE_2D, f.tex.image.id)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA16F, w, h, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	gl.BindRenderbuffer(gl.RENDERBUFFER, f.rbo)
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH24_STENCIL8, w, h)
+}
+
 func (f frameBuffer) use() {
 	gl.BindFramebuffer(gl.FRAMEBUFFER, f.id)
 	gl.Viewport(0, 0, f.width, f.height)
@@ -278,14 +440,66 @@ func screenQuadVAO() (uint32, uint32, int32) {
 func quad(width, height float32, uv mgl32.Vec4) ([]float32, []uint32) {
 	w2, h2 := width/2, height/2
 	return []float32{ // vertices
-			-w2, -h2, 0.0, uv[0], uv[2],
-			w2, -h2, 0.0, uv[1], uv[2],
-			w2, h2, 0.0, uv[1], uv[3],
-			-w2, h2, 0.0, uv[0], uv[3],
-		}, []uint32{ // indices
-			0, 1, 3,
-			1, 2, 3,
+		-w2, -h2, 0.0, uv[0], uv[2],
+		w2, -h2, 0.0, uv[1], uv[2],
+		w2, h2, 0.0, uv[1], uv[3],
+		-w2, h2, 0.0, uv[0], uv[3],
+	}, []uint32{ // indices
+		0, 1, 3,
+		1, 2, 3,
+	}
+}
+
+// a width x height quad split into a 3x3 grid, so the border {left, top, right, bottom} keeps its size
+// in pixels while the edges and middle stretch. texSize is the size in pixels of the uv region
+func nineSliceQuad(width, height float32, uv, border mgl32.Vec4, texSize mgl32.Vec2) ([]float32, []uint32) {
+	// shrink the border if the quad is too small for it
+	kx, ky := float32(1), float32(1)
+	if border[0]+border[2] > width {
+		kx = width / (border[0] + border[2])
+	}
+	if border[1]+border[3] > height {
+		ky = height / (border[1] + border[3])
+	}
+	w2, h2 := width/2, height/2
+	xs := [4]float32{-w2, -w2 + border[0]*kx, w2 - border[2]*kx, w2}
+	ys := [4]float32{-h2, -h2 + border[1]*ky, h2 - border[3]*ky, h2}
+	du, dv := (uv[1]-uv[0])/texSize[0], (uv[3]-uv[2])/texSize[1]
+	us := [4]float32{uv[0], uv[0] + border[0]*du, uv[1] - border[2]*du, uv[1]}
+	vs := [4]float32{uv[2], uv[2] + border[1]*dv, uv[3] - border[3]*dv, uv[3]}
+
+	vertices := make([]float32, 0, 16*5)
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			vertices = append(vertices, xs[col], ys[row], 0.0, us[col], vs[row])
 		}
+	}
+	indices := make([]uint32, 0, 9*6)
+	for row := uint32(0); row < 3; row++ {
+		for col := uint32(0); col < 3; col++ {
+			tl := row*4 + col
+			indices = append(indices,
+				tl, tl+1, tl+4,
+				tl+1, tl+5, tl+4,
+			)
+		}
+	}
+	return vertices, indices
+}
+
+// a quad covering only the trim {x, y, w, h} region of a width x height quad
+func trimmedQuad(width, height float32, trim, uv mgl32.Vec4) ([]float32, []uint32) {
+	l, t := -width/2+trim[0]*width, -height/2+trim[1]*height
+	r, b := l+trim[2]*width, t+trim[3]*height
+	return []float32{ // vertices
+		l, t, 0.0, uv[0], uv[2],
+		r, t, 0.0, uv[1], uv[2],
+		r, b, 0.0, uv[1], uv[3],
+		l, b, 0.0, uv[0], uv[3],
+	}, []uint32{ // indices
+		0, 1, 3,
+		1, 2, 3,
+	}
 }
 
 // returns vao, vbo, indices
@@ -296,6 +510,10 @@ func newQuadVAO(width, height float32, uv mgl32.Vec4) (uint32, uint32, int32) {
 
 // returns vao, vbo, indices
 func genVAO(p []float32, i []uint32) (uint32, uint32, int32) {
+	if headless {
+		return genSoftVAO(p, i)
+	}
+
 	var vbo, vao, ebo uint32
 
 	// Create GL objects
@@ -319,3 +537,37 @@ func genVAO(p []float32, i []uint32) (uint32, uint32, int32) {
 	gl.BindVertexArray(0)
 	return vao, vbo, int32(len(i))
 }
+
+// deletes a vao made by genVAO, with its buffers
+func deleteVAO(vao, vbo uint32) {
+	if headless {
+		delete(softMeshes, vao)
+		delete(softVBOs, vbo)
+		return
+	}
+
+	var ebo int32
+	gl.BindVertexArray(vao)
+	gl.GetIntegerv(gl.ELEMENT_ARRAY_BUFFER_BINDING, &ebo)
+	gl.BindVertexArray(0)
+	buffers := []uint32{vbo, uint32(ebo)}
+	gl.DeleteBuffers(2, &buffers[0])
+	gl.DeleteVertexArrays(1, &vao)
+}
+
+// replaces the vertex data held by vbo
+func updateVBO(vbo uint32, p []float32) {
+	if headless {
+		updateSoftVBO(vbo, p)
+		return
+	}
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, 4*len(p), gl.Ptr(p), gl.STATIC_DRAW)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+}
+
+// Camera passed to the last BeginScene
+func (r *renderer) camera() Camera {
+	return r.activeCam
+}
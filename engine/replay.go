@@ -0,0 +1,245 @@
+package engine
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"log"
+	"os"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Input recorded each fixed update. Playing it back with the same starting state gives the same game,
+// so testers can send replays of bugs and tests can script scenes without a window
+type InputRecording struct {
+	TickDelta time.Duration // fixed update length when recorded
+	Frames    []InputFrame
+}
+
+// Raw input seen during one fixed update, before Once and Up states are worked out
+type InputFrame struct {
+	Keys          []int // held keys
+	KeysReleased  []int
+	KeysRepeated  []int // pressed or repeated by the OS, see KeyRepeat
+	Mouse         []int // held mouse buttons
+	MouseReleased []int
+	MousePressed  []int      // pressed since the last update, even if released again before it
+	Cursor        mgl32.Vec2 // screen pixels
+	Scroll        mgl32.Vec2
+	Motion        mgl32.Vec2 // cursor movement in screen pixels, see MouseDelta
+	Text          string
+	Gamepads      []GamepadFrame // connected gamepads
+}
+
+type GamepadFrame struct {
+	ID    int
+	Name  string
+	State GamepadState
+}
+
+type replay struct {
+	rec    *InputRecording
+	next   int
+	cursor mgl32.Vec2
+	done   func()
+}
+
+// Saves the recording as gzipped gob
+func (r *InputRecording) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zw := gzip.NewWriter(file)
+	if err := gob.NewEncoder(zw).Encode(r); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func LoadRecording(path string) (*InputRecording, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	r := &InputRecording{}
+	if err := gob.NewDecoder(zr).Decode(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Starts recording input from the current update. Start recording and playback at the same
+// point, e.g. before Run or in a scene's OnEnter, so the frames line up
+func (i *input) StartRecording() {
+	i.recorder = &InputRecording{TickDelta: Time.TickDelta()}
+}
+
+// Stops recording and returns what was recorded, or nil if nothing was being recorded
+func (i *input) StopRecording() *InputRecording {
+	r := i.recorder
+	i.recorder = nil
+	return r
+}
+
+// True between StartRecording and StopRecording
+func (i *input) Recording() bool {
+	return i.recorder != nil
+}
+
+// Replaces real input with the recording, one frame per fixed update. Real input is ignored until
+// the recording ends or StopPlayback is called. done, which can be nil, is called when it ends
+func (i *input) Play(r *InputRecording, done func()) {
+	if r.TickDelta != 0 && r.TickDelta != Time.TickDelta() {
+		log.Println("replaying input recorded at a different tick rate: ", r.TickDelta)
+	}
+	i.playback = &replay{rec: r, done: done}
+	i.playFrame()
+}
+
+// Stops playback and goes back to real input, with every key and button released
+func (i *input) StopPlayback() {
+	p := i.playback
+	if p == nil {
+		return
+	}
+	i.playback = nil
+	i.applyFrame(InputFrame{})
+	if i.window != nil {
+		i.scanGamepads()
+	}
+	if p.done != nil {
+		p.done()
+	}
+}
+
+// True while a recording is being played
+func (i *input) Playing() bool {
+	return i.playback != nil
+}
+
+// Records the raw input this update saw
+func (i *input) recordFrame() {
+	if i.recorder == nil {
+		return
+	}
+	f := InputFrame{
+		Cursor: i.MousePosition(),
+		Scroll: i.mouse.scrollAcc,
+		Motion: i.mouse.deltaAcc,
+		Text:   string(i.textInput),
+	}
+	for code := 0; code < KeyLast; code++ {
+		if i.keysDown[code] {
+			f.Keys = append(f.Keys, code)
+		}
+		if i.keysUp[code] {
+			f.KeysReleased = append(f.KeysReleased, code)
+		}
+		if i.keysRepeat[code] {
+			f.KeysRepeated = append(f.KeysRepeated, code)
+		}
+	}
+	for b := 0; b < mouseButtonCount; b++ {
+		if i.mouse.down[b] {
+			f.Mouse = append(f.Mouse, b)
+		}
+		if i.mouse.released[b] {
+			f.MouseReleased = append(f.MouseReleased, b)
+		}
+		if i.mouse.pressed[b] {
+			f.MousePressed = append(f.MousePressed, b)
+		}
+	}
+	for _, p := range i.Gamepads() {
+		f.Gamepads = append(f.Gamepads, GamepadFrame{ID: p.ID, Name: p.Name, State: p.state})
+	}
+	i.recorder.Frames = append(i.recorder.Frames, f)
+}
+
+// Loads the next frame of the recording being played, where the window's callbacks would have put it
+func (i *input) playFrame() {
+	p := i.playback
+	if p == nil {
+		return
+	}
+	if p.next >= len(p.rec.Frames) {
+		i.StopPlayback()
+		return
+	}
+	i.applyFrame(p.rec.Frames[p.next])
+	p.next++
+}
+
+func (i *input) applyFrame(f InputFrame) {
+	i.keysDown = [KeyLast]bool{}
+	for _, code := range f.Keys {
+		if code >= 0 && code < KeyLast {
+			i.keysDown[code] = true
+		}
+	}
+	for _, code := range f.KeysReleased {
+		if code >= 0 && code < KeyLast {
+			i.keysUp[code] = true
+		}
+	}
+	for _, code := range f.KeysRepeated {
+		if code >= 0 && code < KeyLast {
+			i.keysRepeat[code] = true
+		}
+	}
+
+	i.mouse.down = [mouseButtonCount]bool{}
+	for _, b := range f.Mouse {
+		if validMouseButton(b) {
+			i.mouse.down[b] = true
+		}
+	}
+	for _, b := range f.MouseReleased {
+		if validMouseButton(b) {
+			i.mouse.released[b] = true
+		}
+	}
+	for _, b := range f.MousePressed {
+		if validMouseButton(b) {
+			i.mouse.pressed[b] = true
+		}
+	}
+	i.mouse.scrollAcc = f.Scroll
+	i.mouse.deltaAcc = f.Motion
+	i.textInput = []rune(f.Text)
+	if i.playback != nil {
+		i.playback.cursor = f.Cursor
+	}
+
+	// recorded gamepads are played through synthetic state, everything else is disconnected
+	var recorded [maxGamepads]*GamepadFrame
+	for n := range f.Gamepads {
+		if pad := f.Gamepads[n].ID; pad >= 0 && pad < maxGamepads {
+			recorded[pad] = &f.Gamepads[n]
+		}
+	}
+	for pad := 0; pad < maxGamepads; pad++ {
+		r := recorded[pad]
+		if r == nil {
+			i.pads.synthetic[pad] = nil
+			i.disconnectGamepad(pad)
+			continue
+		}
+		state := r.State
+		i.pads.synthetic[pad] = &state
+		if p := i.pads.pads[pad]; p == nil || p.Name != r.Name {
+			i.disconnectGamepad(pad)
+			i.connectGamepad(pad, r.Name, true)
+		}
+	}
+}
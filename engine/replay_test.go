@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// What a scene could see of the input during one tick
+type inputSnapshot struct {
+	keyDown, keyOnce, keyUp       bool
+	mouseDown, mouseOnce, mouseUp bool
+	scroll                        mgl32.Vec2
+}
+
+func snapshot(i *input) inputSnapshot {
+	return inputSnapshot{
+		keyDown:   i.KeyDown(KeySpace),
+		keyOnce:   i.KeyOnce(KeySpace),
+		keyUp:     i.KeyUp(KeySpace),
+		mouseDown: i.MouseDown(MouseLeft),
+		mouseOnce: i.MouseOnce(MouseLeft),
+		mouseUp:   i.MouseUp(MouseLeft),
+		scroll:    i.MouseWheel(),
+	}
+}
+
+// Window events arriving before a tick, as the key and mouse callbacks would report them
+type inputEvent func(i *input)
+
+func keyPress(i *input)   { i.keyCallback(KeySpace, glfw.Press) }
+func keyRelease(i *input) { i.keyCallback(KeySpace, glfw.Release) }
+
+func mousePress(i *input)   { i.mouseButtonCallback(int(MouseLeft), glfw.Press) }
+func mouseRelease(i *input) { i.mouseButtonCallback(int(MouseLeft), glfw.Release) }
+func scrollUp(i *input)     { i.scrollCallback(0, 1) }
+
+// Runs a tick per entry in ticks, firing its events first, and returns what each tick saw
+func runTicks(i *input, ticks [][]inputEvent) []inputSnapshot {
+	seen := make([]inputSnapshot, 0, len(ticks))
+	for _, events := range ticks {
+		for _, e := range events {
+			e(i)
+		}
+		seen = append(seen, snapshot(i))
+		i.update()
+	}
+	return seen
+}
+
+func TestReplayTickAlignment(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []inputEvent // fired before recording starts
+		ticks  [][]inputEvent
+	}{
+		{"key held for a few ticks", nil, [][]inputEvent{{keyPress}, {}, {}, {keyRelease}, {}}},
+		{"key tapped every other tick", nil, [][]inputEvent{{keyPress}, {keyRelease}, {keyPress}, {keyRelease}, {}}},
+		{"key already held when recording starts", []inputEvent{keyPress}, [][]inputEvent{{}, {}, {keyRelease}, {}}},
+		{"click", nil, [][]inputEvent{{}, {mousePress}, {}, {mouseRelease}, {}}},
+		{"click inside one tick", nil, [][]inputEvent{{mousePress, mouseRelease}, {}, {}}},
+		{"scrolling", nil, [][]inputEvent{{scrollUp}, {scrollUp, scrollUp}, {}, {scrollUp}}},
+		{"everything at once", nil, [][]inputEvent{{keyPress, mousePress}, {scrollUp}, {keyRelease, mouseRelease}, {}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			live := initInput()
+			for _, e := range tt.before {
+				e(live)
+			}
+			live.update()
+			live.StartRecording()
+			want := runTicks(live, tt.ticks)
+			rec := live.StopRecording()
+			if len(rec.Frames) != len(tt.ticks) {
+				t.Fatalf("recorded %d frames for %d ticks", len(rec.Frames), len(tt.ticks))
+			}
+
+			replayed := initInput()
+			for _, e := range tt.before {
+				e(replayed)
+			}
+			replayed.update()
+			ended := -1
+			tick := 0
+			replayed.Play(rec, func() { ended = tick })
+
+			// events during playback are ignored, so playing the same ticks again must see the same input
+			got := make([]inputSnapshot, 0, len(tt.ticks))
+			for ; tick < len(tt.ticks); tick++ {
+				for _, e := range tt.ticks[tick] {
+					e(replayed)
+				}
+				got = append(got, snapshot(replayed))
+				replayed.update()
+			}
+			for n := range want {
+				if got[n] != want[n] {
+					t.Errorf("tick %d: replay saw %+v, recording saw %+v", n, got[n], want[n])
+				}
+			}
+			if ended != len(tt.ticks)-1 || replayed.Playing() {
+				t.Errorf("playback ended in the update of tick %d, want %d", ended, len(tt.ticks)-1)
+			}
+		})
+	}
+}
+
+func TestClickInsideOneTick(t *testing.T) {
+	i := initInput()
+	seen := runTicks(i, [][]inputEvent{{mousePress, mouseRelease}, {}, {}})
+	want := []inputSnapshot{{}, {mouseOnce: true, mouseUp: true}, {}}
+	for n := range want {
+		if seen[n] != want[n] {
+			t.Errorf("tick %d saw %+v, want %+v", n, seen[n], want[n])
+		}
+	}
+}
+
+func TestKeyCallbackIgnoresUnknownKeys(t *testing.T) {
+	i := initInput()
+	for _, key := range []int{-1, KeyLast} { // KeyUnknown, and past the last key
+		i.keyCallback(key, glfw.Press)
+	}
+	if i.keysDown != ([KeyLast]bool{}) || i.keysRepeat != ([KeyLast]bool{}) {
+		t.Error("keys outside the key range were recorded")
+	}
+}
@@ -0,0 +1,282 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Scenes are kept on a stack. Only the top scene is updated, once per fixed update.
+type Scene interface {
+	Update()
+}
+
+// Scenes can also implement any of these optional interfaces.
+
+// Draw is called once per rendered frame, after any updates, for the top scene and the scenes
+// visible beneath it. Scenes that submit everything in Update don't need it.
+type SceneDrawer interface {
+	Draw()
+}
+
+// Called when the scene is added to the stack
+type SceneEnterer interface {
+	OnEnter()
+}
+
+// Called when the scene is removed from the stack
+type SceneExiter interface {
+	OnExit()
+}
+
+// Called when another scene is pushed on top of this one
+type ScenePauser interface {
+	OnPause()
+}
+
+// Called when this scene is back on top after the scene above was popped
+type SceneResumer interface {
+	OnResume()
+}
+
+// An overlay scene, like a pause menu, is drawn over the scenes beneath it instead of hiding them.
+// Those scenes still aren't updated.
+type SceneOverlay interface {
+	Overlay() bool
+}
+
+type TransitionKind int
+
+const (
+	TransitionFade TransitionKind = iota // fades out to Colour, then in to the new scene
+	TransitionWipe                       // covers the screen with Colour along Direction, then uncovers it
+)
+
+// Animates a change of scene. The first half covers the old scene, the stack is changed at the
+// halfway point, then the second half uncovers the new scene. The zero value changes instantly
+type Transition struct {
+	Kind      TransitionKind
+	Duration  time.Duration
+	Colour    mgl32.Vec4
+	Direction mgl32.Vec2 // wipe direction in screen space, e.g. {1, 0} for left to right
+	// Input is ignored for this long once the stack changes, so a click or key held
+	// from the old scene doesn't carry into the new one
+	InputPause time.Duration
+}
+
+// How long Fade and Wipe ignore input after the scene changes
+const defaultInputPause = 300 * time.Millisecond
+
+func Fade(duration time.Duration, colour mgl32.Vec4) Transition {
+	return Transition{Kind: TransitionFade, Duration: duration, Colour: colour, InputPause: defaultInputPause}
+}
+
+func Wipe(duration time.Duration, colour mgl32.Vec4, direction mgl32.Vec2) Transition {
+	return Transition{Kind: TransitionWipe, Duration: duration, Colour: colour, Direction: direction, InputPause: defaultInputPause}
+}
+
+type sceneOpKind int
+
+const (
+	opSet sceneOpKind = iota
+	opPush
+	opPop
+	opReplace
+)
+
+type sceneOp struct {
+	kind       sceneOpKind
+	scene      Scene
+	transition Transition
+}
+
+type sceneTransition struct {
+	op      sceneOp
+	elapsed time.Duration
+	applied bool // the stack has been changed, now uncovering
+	effect  *PostEffect
+}
+
+// Incremented before each update and draw, so scenes sharing one can share the UI buffer
+var uiPass uint64
+
+// Clears the stack and makes s the only scene
+func (g *Game) SetScene(s Scene, transition ...Transition) {
+	g.queueScene(sceneOp{kind: opSet, scene: s}, transition)
+}
+
+// Puts s on top of the stack, pausing the current top scene
+func (g *Game) PushScene(s Scene, transition ...Transition) {
+	g.queueScene(sceneOp{kind: opPush, scene: s}, transition)
+}
+
+// Removes the top scene, resuming the one beneath it
+func (g *Game) PopScene(transition ...Transition) {
+	g.queueScene(sceneOp{kind: opPop}, transition)
+}
+
+// Swaps the top scene for s
+func (g *Game) ReplaceScene(s Scene, transition ...Transition) {
+	g.queueScene(sceneOp{kind: opReplace, scene: s}, transition)
+}
+
+// The top scene, or nil if the stack is empty
+func (g *Game) Scene() Scene {
+	if len(g.scenes) == 0 {
+		return nil
+	}
+	return g.scenes[len(g.scenes)-1]
+}
+
+// Number of scenes on the stack
+func (g *Game) SceneCount() int {
+	return len(g.scenes)
+}
+
+// True while a transition is playing
+func (g *Game) Transitioning() bool {
+	return g.transition != nil
+}
+
+// Changes are applied at the start of the next update, in the order they were made,
+// so a scene can safely push or pop from inside its own Update
+func (g *Game) queueScene(op sceneOp, transition []Transition) {
+	if len(transition) > 0 {
+		op.transition = transition[0]
+	}
+	g.pending = append(g.pending, op)
+}
+
+// Advances the current transition, or starts the next queued change
+func (g *Game) updateScenes() {
+	if g.transition == nil {
+		for len(g.pending) > 0 && g.transition == nil {
+			op := g.pending[0]
+			g.pending = g.pending[1:]
+			if op.transition.Duration <= 0 {
+				g.applyScene(op)
+				continue
+			}
+			g.startTransition(op)
+		}
+		if g.transition == nil {
+			return
+		}
+	}
+
+	t := g.transition
+	t.elapsed += Time.TickDelta() // transitions run in real time, even when the clock is paused
+	half := t.op.transition.Duration / 2
+	if !t.applied && t.elapsed >= half {
+		g.applyScene(t.op)
+		t.applied = true
+	}
+
+	progress := float32(t.elapsed) / float32(half)
+	if t.applied {
+		progress = 2 - progress
+	}
+	setTransitionProgress(t.effect, t.op.transition, mgl32.Clamp(progress, 0, 1), t.applied)
+
+	if t.elapsed >= t.op.transition.Duration {
+		Renderer.PostProcess().transition = nil
+		g.transition = nil
+	}
+}
+
+// Compiled on the first transition and shared by every one after it
+var transitionShader Shader
+
+func (g *Game) startTransition(op sceneOp) {
+	if transitionShader.uniforms == nil {
+		transitionShader = NewPostShader(transitionSource)
+	}
+	effect := NewPostEffect("transition", transitionShader)
+	effect.SetVec4("colour", op.transition.Colour)
+	if op.transition.Kind == TransitionWipe {
+		effect.SetFloat("wipe", 1)
+		direction := op.transition.Direction
+		if direction.Len() == 0 {
+			direction = mgl32.Vec2{1, 0}
+		}
+		effect.SetVec2("direction", direction)
+	}
+	setTransitionProgress(effect, op.transition, 0, false)
+
+	g.transition = &sceneTransition{op: op, effect: effect}
+	Renderer.PostProcess().transition = effect
+}
+
+// progress is how much of the screen is covered, from 0 to 1
+func setTransitionProgress(effect *PostEffect, t Transition, progress float32, uncovering bool) {
+	effect.SetFloat("fade", progress)
+	if uncovering {
+		// uncover from the same edge the cover started from
+		effect.SetVec2("range", mgl32.Vec2{1 - progress, 1})
+	} else {
+		effect.SetVec2("range", mgl32.Vec2{0, progress})
+	}
+}
+
+func (g *Game) applyScene(op sceneOp) {
+	if op.transition.InputPause > 0 {
+		Input.pauseInput(op.transition.InputPause)
+	}
+
+	switch op.kind {
+	case opSet:
+		for len(g.scenes) > 0 {
+			g.popScene()
+		}
+		g.pushScene(op.scene)
+	case opPush:
+		if top, ok := g.Scene().(ScenePauser); ok {
+			top.OnPause()
+		}
+		g.pushScene(op.scene)
+	case opPop:
+		g.popScene()
+		if top, ok := g.Scene().(SceneResumer); ok {
+			top.OnResume()
+		}
+	case opReplace:
+		g.popScene()
+		g.pushScene(op.scene)
+	}
+}
+
+func (g *Game) pushScene(s Scene) {
+	g.scenes = append(g.scenes, s)
+	if e, ok := s.(SceneEnterer); ok {
+		e.OnEnter()
+	}
+}
+
+func (g *Game) popScene() {
+	top := g.Scene()
+	if top == nil {
+		return
+	}
+	g.scenes = g.scenes[:len(g.scenes)-1]
+	if e, ok := top.(SceneExiter); ok {
+		e.OnExit()
+	}
+}
+
+// Draws the top scene and any scenes visible beneath overlays, bottom first
+func (g *Game) draw() {
+	uiPass++
+	first := len(g.scenes) - 1
+	for first > 0 {
+		overlay, ok := g.scenes[first].(SceneOverlay)
+		if !ok || !overlay.Overlay() {
+			break
+		}
+		first--
+	}
+	for i := first; i >= 0 && i < len(g.scenes); i++ {
+		if d, ok := g.scenes[i].(SceneDrawer); ok {
+			d.Draw()
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type emptyScene struct{}
+
+func (emptyScene) Update() {}
+
+func TestSceneChangesOnlyPauseInputWhenAsked(t *testing.T) {
+	in := Input
+	defer func() { Input = in }()
+
+	tests := []struct {
+		name   string
+		op     sceneOp
+		paused bool
+	}{
+		{"push", sceneOp{kind: opPush, scene: emptyScene{}}, false},
+		{"pop", sceneOp{kind: opPop}, false},
+		{"set", sceneOp{kind: opSet, scene: emptyScene{}}, false},
+		{"replace", sceneOp{kind: opReplace, scene: emptyScene{}}, false},
+		{"set with a fade", sceneOp{kind: opSet, scene: emptyScene{}, transition: Fade(time.Second, mgl32.Vec4{})}, true},
+		{"push with a pause", sceneOp{kind: opPush, scene: emptyScene{}, transition: Transition{InputPause: time.Second}}, true},
+		{"fade without a pause", sceneOp{kind: opReplace, scene: emptyScene{}, transition: Transition{Duration: time.Second}}, false},
+	}
+	for _, tt := range tests {
+		Input = initInput()
+		g := &Game{scenes: []Scene{emptyScene{}}}
+		g.applyScene(tt.op)
+		if Input.paused() != tt.paused {
+			t.Errorf("%s: input paused %v, want %v", tt.name, Input.paused(), tt.paused)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package engine
+
+import (
+	"image"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	sdfSize    = 32 // size glyphs are rasterized at in SDF mode, then scaled to the size drawn
+	sdfSpread  = 8  // distance in texels at sdfSize the field covers either side of a glyph's edge
+	sdfUpscale = 4  // glyphs are rasterized this many times larger to measure distances from
+)
+
+// Effects for SDF text. Widths and offsets are in screen pixels, and reach at most sdfSpread
+// scaled to the size drawn, so 4 pixels at size 16. Bitmap fonts ignore them
+type TextStyle struct {
+	Outline        float32
+	OutlineColour  mgl32.Vec4
+	Shadow         mgl32.Vec2 // offset of the drop shadow, drawn if ShadowColour isn't transparent
+	ShadowColour   mgl32.Vec4
+	ShadowSoftness float32
+	Glow           float32 // how far the glow fades out over
+	GlowColour     mgl32.Vec4
+}
+
+// Uniforms for drawing a renderItem of SDF text
+type sdfText struct {
+	distanceRange float32    // screen pixels across the field's 0 to 1 range
+	pixelUV       mgl32.Vec2 // size of a screen pixel in texture coordinates
+	style         TextStyle
+}
+
+// Switches between bitmap glyphs, rasterized for each size they're drawn at, and signed distance field
+// glyphs. SDF glyphs are rasterized once and scaled smoothly to any size, and can be drawn with a TextStyle
+// Bitmap fonts are always drawn from their bitmaps
+func (f *Font) SetSDF(enabled bool) {
+	if f.sdf == enabled || f.bitmap != nil {
+		return
+	}
+	f.sdf = enabled
+	f.clearGlyphs()
+}
+
+func (s *sdfText) setUniforms(shader Shader) {
+	shader.SetFloat("u_distanceRange", s.distanceRange)
+	shader.SetVec2("u_pixelUV", s.pixelUV)
+	shader.SetFloat("u_outline", s.style.Outline)
+	shader.SetVec4("u_outlineColour", s.style.OutlineColour)
+	shader.SetVec2("u_shadowOffset", s.style.Shadow)
+	shader.SetVec4("u_shadowColour", s.style.ShadowColour)
+	shader.SetFloat("u_shadowSoftness", s.style.ShadowSoftness)
+	shader.SetFloat("u_glow", s.style.Glow)
+	shader.SetVec4("u_glowColour", s.style.GlowColour)
+}
+
+// Mirrors sdfTextFragment.glsl, for headless rendering
+func (s *sdfText) shade(img Image, uv mgl32.Vec2, colour mgl32.Vec4) mgl32.Vec4 {
+	clamp := func(v float32) float32 { return mgl32.Clamp(v, 0, 1) }
+	dist := func(uv mgl32.Vec2) float32 { return (sampleLinear(img, uv)[3] - 0.5) * s.distanceRange }
+	over := func(under mgl32.Vec4, c mgl32.Vec4, a float32) mgl32.Vec4 {
+		return c.Vec3().Mul(a).Add(under.Vec3().Mul(1 - a)).Vec4(a + under[3]*(1-a))
+	}
+	st := s.style
+	d := dist(uv)
+	var result mgl32.Vec4
+	if st.ShadowColour[3] > 0 {
+		offset := mgl32.Vec2{st.Shadow[0] * s.pixelUV[0], st.Shadow[1] * s.pixelUV[1]}
+		shadow := dist(uv.Sub(offset)) + st.Outline
+		result = over(result, st.ShadowColour, clamp(shadow/max32(st.ShadowSoftness, 1)+0.5)*st.ShadowColour[3])
+	}
+	if st.Glow > 0 {
+		result = over(result, st.GlowColour, (1-clamp(-(d+st.Outline)/st.Glow))*st.GlowColour[3])
+	}
+	if st.Outline > 0 {
+		result = over(result, st.OutlineColour, clamp(d+st.Outline+0.5)*st.OutlineColour[3])
+	}
+	result = over(result, colour, clamp(d+0.5))
+	if result[3] <= 0 {
+		return mgl32.Vec4{}
+	}
+	return result.Vec3().Mul(1 / result[3]).Vec4(result[3] * colour[3])
+}
+
+// Rasterizes char large and measures the distance from each texel to the glyph's edge.
+// Returns the field, 0.5 on the edge and increasing inwards, and its offset from the pen position in texels
+func (f *Font) rasterizeSDF(src *Font, char rune) (*image.RGBA, image.Point) {
+	dr, mask, maskp, _, ok := src.atSize(sdfSize*sdfUpscale).face.Glyph(fixed.Point26_6{}, char)
+	if !ok || dr.Empty() {
+		return nil, image.Point{}
+	}
+
+	// texels of the field, covering the glyph and the spread around it
+	lo := image.Pt(floorDiv(dr.Min.X, sdfUpscale)-sdfSpread, floorDiv(dr.Min.Y, sdfUpscale)-sdfSpread)
+	hi := image.Pt(-floorDiv(-dr.Max.X, sdfUpscale)+sdfSpread, -floorDiv(-dr.Max.Y, sdfUpscale)+sdfSpread)
+
+	// the large glyph, padded out to the field's edges
+	w, h := (hi.X-lo.X)*sdfUpscale, (hi.Y-lo.Y)*sdfUpscale
+	origin := lo.Mul(sdfUpscale)
+	inside := make([]bool, w*h)
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		for x := dr.Min.X; x < dr.Max.X; x++ {
+			_, _, _, a := mask.At(maskp.X+x-dr.Min.X, maskp.Y+y-dr.Min.Y).RGBA()
+			inside[(y-origin.Y)*w+x-origin.X] = a >= 0x8000
+		}
+	}
+	toInside := distanceTransform(inside, w, h, true)
+	toOutside := distanceTransform(inside, w, h, false)
+
+	img := image.NewRGBA(image.Rect(0, 0, hi.X-lo.X, hi.Y-lo.Y))
+	for ty := 0; ty < img.Rect.Dy(); ty++ {
+		for tx := 0; tx < img.Rect.Dx(); tx++ {
+			// the large pixel at the texel's centre
+			i := (ty*sdfUpscale+sdfUpscale/2)*w + tx*sdfUpscale + sdfUpscale/2
+			var d float64
+			if inside[i] {
+				d = math.Sqrt(toOutside[i]) - 0.5
+			} else {
+				d = 0.5 - math.Sqrt(toInside[i])
+			}
+			v := mgl32.Clamp(float32(0.5+d/sdfUpscale/(2*sdfSpread)), 0, 1)
+			c := uint8(v*255 + 0.5)
+			p := img.Pix[img.PixOffset(tx, ty):]
+			p[0], p[1], p[2], p[3] = c, c, c, c
+		}
+	}
+	return img, lo
+}
+
+// Squared distance from each pixel to the nearest pixel where inside is want, using the
+// separable transform from Felzenszwalb and Huttenlocher's "Distance Transforms of Sampled Functions"
+func distanceTransform(inside []bool, w, h int, want bool) []float64 {
+	const far = 1e20
+	d := make([]float64, w*h)
+	for i, in := range inside {
+		if in != want {
+			d[i] = far
+		}
+	}
+	n := w
+	if h > n {
+		n = h
+	}
+	line, out := make([]float64, n), make([]float64, n)
+	v, z := make([]int, n), make([]float64, n+1)
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			line[y] = d[y*w+x]
+		}
+		distanceTransform1D(line[:h], out[:h], v, z)
+		for y := 0; y < h; y++ {
+			d[y*w+x] = out[y]
+		}
+	}
+	for y := 0; y < h; y++ {
+		copy(line, d[y*w:(y+1)*w])
+		distanceTransform1D(line[:w], out[:w], v, z)
+		copy(d[y*w:(y+1)*w], out[:w])
+	}
+	return d
+}
+
+// The lower envelope of the parabolas rooted at each sample
+func distanceTransform1D(f, d []float64, v []int, z []float64) {
+	k := 0
+	v[0] = 0
+	z[0], z[1] = math.Inf(-1), math.Inf(1)
+	for q := 1; q < len(f); q++ {
+		s := ((f[q] + float64(q*q)) - (f[v[k]] + float64(v[k]*v[k]))) / float64(2*q-2*v[k])
+		for s <= z[k] {
+			k--
+			s = ((f[q] + float64(q*q)) - (f[v[k]] + float64(v[k]*v[k]))) / float64(2*q-2*v[k])
+		}
+		k++
+		v[k] = q
+		z[k], z[k+1] = s, math.Inf(1)
+	}
+	k = 0
+	for q := range f {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		d[q] = float64((q-v[k])*(q-v[k])) + f[v[k]]
+	}
+}
+
+// Rounds towards negative infinity
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -25,6 +25,33 @@ var ppVertexShaderSource string
 //go:embed shaders/uiFragment.glsl
 var uiFragmentSource string
 
+//go:embed shaders/sdfTextFragment.glsl
+var sdfTextFragmentSource string
+
+//go:embed shaders/post/bloomBright.glsl
+var bloomBrightSource string
+
+//go:embed shaders/post/blur.glsl
+var blurSource string
+
+//go:embed shaders/post/bloomCombine.glsl
+var bloomCombineSource string
+
+//go:embed shaders/post/vignette.glsl
+var vignetteSource string
+
+//go:embed shaders/post/colourGrade.glsl
+var colourGradeSource string
+
+//go:embed shaders/post/crt.glsl
+var crtSource string
+
+//go:embed shaders/post/pixelate.glsl
+var pixelateSource string
+
+//go:embed shaders/post/transition.glsl
+var transitionSource string
+
 type Shader struct {
 	id       uint32
 	uniforms map[string]int32
@@ -66,6 +93,9 @@ func createGLShader(vShader, fShader uint32) (Shader, error) {
 }
 
 func NewShaderFromString(vertexSrc, fragmentSrc string) Shader {
+	if headless {
+		return Shader{0, make(map[string]int32)}
+	}
 	vShader, err := compileShader(vertexSrc, gl.VERTEX_SHADER)
 	if err != nil {
 		panic(err)
@@ -82,6 +112,9 @@ func NewShaderFromString(vertexSrc, fragmentSrc string) Shader {
 }
 
 func NewShaderFromFile(vertexPath, fragmentPath string) Shader {
+	if headless {
+		return Shader{0, make(map[string]int32)}
+	}
 	// Compile shader src
 	vShader := loadShaderFile(vertexPath, gl.VERTEX_SHADER)
 	fShader := loadShaderFile(fragmentPath, gl.FRAGMENT_SHADER)
@@ -149,7 +182,8 @@ func (s *Shader) UniformLoc(name string) int32 {
 	n := gl.Str(name + "\x00") // OpgenGL requires null termination character
 	loc, ok := s.uniforms[name]
 	if !ok {
-		s.uniforms[name] = gl.GetUniformLocation(s.id, n)
+		loc = gl.GetUniformLocation(s.id, n)
+		s.uniforms[name] = loc
 	}
 
 	return loc
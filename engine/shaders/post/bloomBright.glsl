@@ -0,0 +1,15 @@
+#version 410
+
+in vec2 texCoord;
+out vec4 frag_colour;
+
+uniform sampler2D u_texture;
+uniform float threshold;
+
+// keeps only the parts of the image brighter than the threshold
+void main()
+{
+	vec3 colour = texture(u_texture, texCoord).rgb;
+	float brightness = dot(colour, vec3(0.2126, 0.7152, 0.0722));
+	frag_colour = vec4(colour * max(brightness - threshold, 0.0) / max(brightness, 0.0001), 1.0);
+}
@@ -0,0 +1,15 @@
+#version 410
+
+in vec2 texCoord;
+out vec4 frag_colour;
+
+uniform sampler2D u_texture; // blurred bright parts
+uniform sampler2D u_input;   // image before bloom
+uniform float intensity;
+
+void main()
+{
+	vec4 base = texture(u_input, texCoord);
+	vec3 bloom = texture(u_texture, texCoord).rgb;
+	frag_colour = vec4(base.rgb + bloom * intensity, base.a);
+}
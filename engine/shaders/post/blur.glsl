@@ -0,0 +1,23 @@
+#version 410
+
+in vec2 texCoord;
+out vec4 frag_colour;
+
+uniform sampler2D u_texture;
+uniform vec2 u_resolution;
+uniform vec2 direction; // (1, 0) for horizontal, (0, 1) for vertical
+uniform float radius;
+
+const float weights[5] = float[](0.227027, 0.1945946, 0.1216216, 0.054054, 0.016216);
+
+// one axis of a separable 9 tap gaussian blur
+void main()
+{
+	vec2 offset = direction * radius / u_resolution;
+	vec3 result = texture(u_texture, texCoord).rgb * weights[0];
+	for (int i = 1; i < 5; i++) {
+		result += texture(u_texture, texCoord + offset * float(i)).rgb * weights[i];
+		result += texture(u_texture, texCoord - offset * float(i)).rgb * weights[i];
+	}
+	frag_colour = vec4(result, 1.0);
+}
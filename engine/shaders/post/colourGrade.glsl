@@ -0,0 +1,29 @@
+#version 410
+
+in vec2 texCoord;
+out vec4 frag_colour;
+
+uniform sampler2D u_texture;
+uniform sampler2D u_lut; // size*size x size strip, blue selects the cell, red runs across and green down each cell
+uniform float lutSize;
+uniform float intensity;
+
+vec3 lookup(vec3 colour)
+{
+	colour = clamp(colour, 0.0, 1.0);
+	float blue = colour.b * (lutSize - 1.0);
+	float cell0 = floor(blue);
+	float cell1 = min(cell0 + 1.0, lutSize - 1.0);
+
+	float x = (colour.r * (lutSize - 1.0) + 0.5) / (lutSize * lutSize);
+	float y = (colour.g * (lutSize - 1.0) + 0.5) / lutSize;
+	vec3 graded0 = texture(u_lut, vec2(x + cell0 / lutSize, y)).rgb;
+	vec3 graded1 = texture(u_lut, vec2(x + cell1 / lutSize, y)).rgb;
+	return mix(graded0, graded1, blue - cell0);
+}
+
+void main()
+{
+	vec4 colour = texture(u_texture, texCoord);
+	frag_colour = vec4(mix(colour.rgb, lookup(colour.rgb), intensity), colour.a);
+}
@@ -0,0 +1,33 @@
+#version 410
+
+in vec2 texCoord;
+out vec4 frag_colour;
+
+uniform sampler2D u_texture;
+uniform vec2 u_resolution;
+uniform float curvature;  // 0 for a flat screen
+uniform float scanlines;  // darkness of the scanlines, 0 - 1
+uniform float aberration; // colour fringing in pixels
+
+void main()
+{
+	// bend the screen outwards from the centre
+	vec2 uv = texCoord * 2.0 - 1.0;
+	uv *= 1.0 + curvature * dot(uv.yx, uv.yx);
+	uv = uv * 0.5 + 0.5;
+	if (uv.x < 0.0 || uv.x > 1.0 || uv.y < 0.0 || uv.y > 1.0) {
+		frag_colour = vec4(0.0, 0.0, 0.0, 1.0);
+		return;
+	}
+
+	vec2 shift = vec2(aberration / u_resolution.x, 0.0);
+	vec3 colour = vec3(
+		texture(u_texture, uv + shift).r,
+		texture(u_texture, uv).g,
+		texture(u_texture, uv - shift).b
+	);
+
+	float line = sin(uv.y * u_resolution.y * 3.14159);
+	colour *= 1.0 - scanlines * (1.0 - line * line);
+	frag_colour = vec4(colour, 1.0);
+}
@@ -0,0 +1,15 @@
+#version 410
+
+in vec2 texCoord;
+out vec4 frag_colour;
+
+uniform sampler2D u_texture;
+uniform vec2 u_resolution;
+uniform float pixelSize; // in screen pixels
+
+void main()
+{
+	vec2 cell = pixelSize / u_resolution;
+	vec2 uv = (floor(texCoord / cell) + 0.5) * cell;
+	frag_colour = texture(u_texture, uv);
+}
@@ -0,0 +1,24 @@
+#version 410
+
+in vec2 texCoord;
+out vec4 frag_colour;
+
+uniform sampler2D u_texture;
+uniform vec4 colour;
+uniform float wipe;     // 1 for a wipe, 0 for a fade
+uniform float fade;     // amount of colour covering the screen when fading
+uniform vec2 direction; // wipe direction, in screen space with y down
+uniform vec2 range;     // part of the screen covered by the wipe, 0 - 1 along direction
+
+void main()
+{
+	vec4 scene = texture(u_texture, texCoord);
+	float amount = fade;
+	if (wipe > 0.5) {
+		vec2 dir = normalize(direction);
+		vec2 pos = vec2(texCoord.x, 1.0 - texCoord.y) - 0.5;
+		float along = dot(pos, dir) / (abs(dir.x) + abs(dir.y)) + 0.5;
+		amount = step(range.x, along) * step(along, range.y);
+	}
+	frag_colour = vec4(mix(scene.rgb, colour.rgb, amount * colour.a), scene.a);
+}
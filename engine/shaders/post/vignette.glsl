@@ -0,0 +1,19 @@
+#version 410
+
+in vec2 texCoord;
+out vec4 frag_colour;
+
+uniform sampler2D u_texture;
+uniform vec2 u_resolution;
+uniform float radius;   // distance from the centre where darkening starts, 0.5 is the edge
+uniform float softness; // width of the falloff
+uniform float strength; // 0 - 1
+
+void main()
+{
+	vec4 colour = texture(u_texture, texCoord);
+	vec2 pos = texCoord - 0.5;
+	pos.x *= u_resolution.x / u_resolution.y;
+	float vignette = 1.0 - smoothstep(radius, radius + softness, length(pos));
+	frag_colour = vec4(colour.rgb * mix(1.0, vignette, strength), colour.a);
+}
@@ -0,0 +1,51 @@
+#version 410
+
+//attributes from vertex shader
+in vec2 texCoord;
+
+out vec4 frag_colour;
+
+uniform sampler2D u_texture;    //signed distance field, 0.5 on the glyph's edge
+uniform vec4 u_colour;
+uniform float u_distanceRange;  //screen pixels across the field's 0 to 1 range
+uniform vec2 u_pixelUV;         //size of a screen pixel in texture coordinates
+
+uniform float u_outline;        //widths in screen pixels
+uniform vec4 u_outlineColour;
+uniform vec2 u_shadowOffset;
+uniform vec4 u_shadowColour;
+uniform float u_shadowSoftness;
+uniform float u_glow;
+uniform vec4 u_glowColour;
+
+// Signed distance in screen pixels from the glyph's edge, positive inside
+float dist(vec2 uv) {
+	return (texture(u_texture, uv).a - 0.5) * u_distanceRange;
+}
+
+// Puts colour c with coverage a over a premultiplied colour
+vec4 over(vec4 under, vec3 c, float a) {
+	return vec4(c * a + under.rgb * (1.0 - a), a + under.a * (1.0 - a));
+}
+
+void main() {
+	float d = dist(texCoord);
+	vec4 result = vec4(0.0);
+
+	if (u_shadowColour.a > 0.0) {
+		float s = dist(texCoord - u_shadowOffset * u_pixelUV) + u_outline;
+		result = over(result, u_shadowColour.rgb, clamp(s / max(u_shadowSoftness, 1.0) + 0.5, 0.0, 1.0) * u_shadowColour.a);
+	}
+	if (u_glow > 0.0) {
+		result = over(result, u_glowColour.rgb, (1.0 - clamp(-(d + u_outline) / u_glow, 0.0, 1.0)) * u_glowColour.a);
+	}
+	if (u_outline > 0.0) {
+		result = over(result, u_outlineColour.rgb, clamp(d + u_outline + 0.5, 0.0, 1.0) * u_outlineColour.a);
+	}
+	result = over(result, u_colour.rgb, clamp(d + 0.5, 0.0, 1.0));
+
+	if (result.a <= 0.0) {
+		discard;
+	}
+	frag_colour = vec4(result.rgb / result.a, result.a * u_colour.a);
+}
@@ -0,0 +1,58 @@
+package engine
+
+import "sort"
+
+// Scene items are drawn layer by layer, with the depth buffer cleared between layers, so a higher
+// layer is always in front no matter its z. Within a layer, opaque items are drawn first, front to back,
+// then translucent items back to front with depth writes off so they blend over everything behind them.
+// Items on y-sorted layers with equal z are ordered by their bottom edge, so lower items are in front.
+func sortRenderItems(items []renderItem, ySort map[int]bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.layer != b.layer {
+			return a.layer < b.layer
+		}
+		if a.image.translucent != b.image.translucent {
+			return !a.image.translucent
+		}
+
+		az, bz := a.transform.Pos[2], b.transform.Pos[2]
+		ay, by := a.sortY, b.sortY
+		if !ySort[a.layer] {
+			ay, by = 0, 0
+		}
+
+		// opaque front to back, translucent back to front
+		frontFirst := !a.image.translucent
+		if az != bz {
+			return (az > bz) == frontFirst
+		}
+		if ay != by {
+			return (ay > by) == frontFirst
+		}
+		// group textures together so they can be batched
+		return a.image.id < b.image.id
+	})
+}
+
+// Splits sorted items into runs that share a layer
+func layerRuns(items []renderItem, draw func(layer []renderItem)) {
+	start := 0
+	for i := 1; i <= len(items); i++ {
+		if i == len(items) || items[i].layer != items[start].layer {
+			draw(items[start:i])
+			start = i
+		}
+	}
+}
+
+// Returns true if any pixel is partially transparent. Fully transparent pixels don't count,
+// the shaders discard those.
+func hasTranslucency(pixels []byte) bool {
+	for i := 3; i < len(pixels); i += 4 {
+		if pixels[i] >= 26 && pixels[i] < 255 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+// A scene item identified by its x, which sorting ignores
+func sortItem(id float32, layer int, z, y float32, tex uint32, translucent bool) renderItem {
+	return renderItem{
+		transform: NewTransform(id, 0, z),
+		layer:     layer,
+		sortY:     y,
+		image:     Image{id: tex, translucent: translucent},
+	}
+}
+
+func sortedIDs(items []renderItem) []float32 {
+	ids := make([]float32, len(items))
+	for i, ri := range items {
+		ids[i] = ri.transform.Pos[0]
+	}
+	return ids
+}
+
+func TestSortRenderItems(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []renderItem
+		ySort map[int]bool
+		want  []float32
+	}{
+		{
+			name:  "layers first, whatever the z",
+			items: []renderItem{sortItem(1, 2, -5, 0, 1, false), sortItem(2, 0, 5, 0, 1, false), sortItem(3, 1, 0, 0, 1, false)},
+			want:  []float32{2, 3, 1},
+		},
+		{
+			name:  "opaque before translucent in a layer",
+			items: []renderItem{sortItem(1, 0, 0, 0, 1, true), sortItem(2, 0, -9, 0, 1, false)},
+			want:  []float32{2, 1},
+		},
+		{
+			name:  "opaque front to back",
+			items: []renderItem{sortItem(1, 0, 1, 0, 1, false), sortItem(2, 0, 3, 0, 1, false), sortItem(3, 0, 2, 0, 1, false)},
+			want:  []float32{2, 3, 1},
+		},
+		{
+			name:  "translucent back to front",
+			items: []renderItem{sortItem(1, 0, 1, 0, 1, true), sortItem(2, 0, 3, 0, 1, true), sortItem(3, 0, 2, 0, 1, true)},
+			want:  []float32{1, 3, 2},
+		},
+		{
+			name:  "y sorted layer puts lower items in front",
+			items: []renderItem{sortItem(1, 0, 0, 10, 1, true), sortItem(2, 0, 0, 30, 1, true), sortItem(3, 0, 0, 20, 1, true)},
+			ySort: map[int]bool{0: true},
+			want:  []float32{1, 3, 2},
+		},
+		{
+			name:  "y ignored on other layers",
+			items: []renderItem{sortItem(1, 0, 0, 30, 2, true), sortItem(2, 0, 0, 10, 1, true), sortItem(3, 1, 0, 30, 1, true), sortItem(4, 1, 0, 10, 1, true)},
+			ySort: map[int]bool{1: true},
+			want:  []float32{2, 1, 4, 3},
+		},
+		{
+			name:  "z beats y",
+			items: []renderItem{sortItem(1, 0, 1, 0, 1, true), sortItem(2, 0, 0, 50, 1, true)},
+			ySort: map[int]bool{0: true},
+			want:  []float32{2, 1},
+		},
+		{
+			name:  "ties grouped by texture",
+			items: []renderItem{sortItem(1, 0, 0, 0, 2, false), sortItem(2, 0, 0, 0, 1, false), sortItem(3, 0, 0, 0, 2, false), sortItem(4, 0, 0, 0, 1, false)},
+			want:  []float32{2, 4, 1, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sortRenderItems(tt.items, tt.ySort)
+			if got := sortedIDs(tt.items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sorted to %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayerRuns(t *testing.T) {
+	tests := []struct {
+		layers []int
+		want   [][]float32
+	}{
+		{nil, nil},
+		{[]int{0}, [][]float32{{0}}},
+		{[]int{0, 0, 1, 3, 3}, [][]float32{{0, 1}, {2}, {3, 4}}},
+	}
+	for _, tt := range tests {
+		items := make([]renderItem, len(tt.layers))
+		for i, l := range tt.layers {
+			items[i] = sortItem(float32(i), l, 0, 0, 1, false)
+		}
+		var got [][]float32
+		layerRuns(items, func(layer []renderItem) { got = append(got, sortedIDs(layer)) })
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("layers %v ran as %v, want %v", tt.layers, got, tt.want)
+		}
+	}
+}
+
+func TestHasTranslucency(t *testing.T) {
+	tests := []struct {
+		name   string
+		alphas []byte
+		want   bool
+	}{
+		{"opaque", []byte{255, 255}, false},
+		{"cut out", []byte{0, 255, 25}, false},
+		{"partly transparent", []byte{255, 128}, true},
+		{"just above the discard threshold", []byte{26}, true},
+		{"empty", nil, false},
+	}
+	for _, tt := range tests {
+		pixels := make([]byte, 0, len(tt.alphas)*4)
+		for _, a := range tt.alphas {
+			pixels = append(pixels, 10, 20, 30, a)
+		}
+		if got := hasTranslucency(pixels); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
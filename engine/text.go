@@ -0,0 +1,363 @@
+package engine
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// How lines of text sit in the width they're wrapped to, or the widest line
+type TextAlign int
+
+const (
+	TextAlignLeft TextAlign = iota
+	TextAlignCentre
+	TextAlignRight
+	TextAlignJustify // wrapped lines are stretched to fill the width, the last line of a paragraph is left aligned
+)
+
+// Options for laying out text. The zero value is a left aligned block broken only at newlines
+type TextOptions struct {
+	MaxWidth    float32 // lines are wrapped between words to fit, 0 doesn't wrap
+	Align       TextAlign
+	LineSpacing float32 // multiplies the height of each line, 0 is the same as 1
+
+	Style TextStyle // outline, shadow and glow for SDF fonts
+
+	// Reads [color=#f00]...[/color] and [size=24]...[/size] tags. Colours are #rgb, #rrggbb or #rrggbbaa,
+	// and tags nest. [[ is a literal [, and tags that aren't understood are drawn as they're written
+	Markup bool
+}
+
+// Where every character of laid out text goes. Positions are in pixels from the top left of the text
+type TextLayout struct {
+	Glyphs []TextGlyph
+	Lines  []TextLine
+	Size   mgl32.Vec2
+}
+
+// A character in a TextLayout. Newlines and markup tags don't have one
+type TextGlyph struct {
+	Char     rune
+	Index    int        // byte offset of the character in the text
+	Line     int        // index in Lines
+	Rect     mgl32.Vec4 // x, y, advance and line height, for hit testing
+	Size     int
+	Colour   mgl32.Vec4 // set by markup, when Coloured
+	Coloured bool       // false for the colour the text is drawn with
+
+	glyph *glyph
+}
+
+type TextLine struct {
+	Start, End int     // range of Glyphs on the line
+	Y          float32 // top of the line
+	Width      float32 // not counting spaces at the end
+	Height     float32
+	Baseline   float32 // from the top of the line
+}
+
+// A character and its style, before it's placed
+type textItem struct {
+	char     rune
+	index    int
+	size     int
+	colour   mgl32.Vec4
+	coloured bool // markup set colour, which can be any colour, even zero
+	glyph    *glyph
+}
+
+// Lays out str at size, breaking lines at newlines and, with a MaxWidth, between words.
+// Glyphs are kerned, and lines mixing sizes share a baseline
+func (f *Font) Layout(size int, str string, opts TextOptions) TextLayout {
+	items := parseText(str, size, opts.Markup)
+	for i := range items {
+		if items[i].char != '\n' {
+			items[i].glyph = f.glyph(items[i].size, items[i].char)
+		}
+	}
+
+	var layout TextLayout
+	spacing := orFloat(opts.LineSpacing, 1)
+	var y float32
+	for start := 0; ; {
+		end, next := f.breakLine(items, start, opts.MaxWidth)
+		line := f.placeLine(&layout, items[start:end], size)
+		line.Y = y
+		for i := line.Start; i < line.End; i++ {
+			layout.Glyphs[i].Rect[1] = y
+			layout.Glyphs[i].Rect[3] = line.Height
+			layout.Glyphs[i].Line = len(layout.Lines)
+		}
+
+		if wrapped := end < len(items) && items[end].char != '\n'; wrapped {
+			// spaces where it wrapped hang past the edge
+			line.Width = trimmedWidth(layout.Glyphs[line.Start:line.End])
+			if opts.Align == TextAlignJustify {
+				justify(layout.Glyphs[line.Start:line.End], opts.MaxWidth-line.Width)
+			}
+		}
+		layout.Lines = append(layout.Lines, line)
+		layout.Size = mgl32.Vec2{max32(layout.Size[0], line.Width), y + line.Height}
+
+		if end == len(items) {
+			break
+		}
+		start = next
+		y += float32(math.Round(float64(line.Height * spacing)))
+	}
+
+	width := layout.Size[0]
+	if opts.MaxWidth > 0 && opts.Align != TextAlignLeft {
+		width = opts.MaxWidth
+	}
+	for _, line := range layout.Lines {
+		var offset float32
+		switch opts.Align {
+		case TextAlignCentre:
+			offset = float32(math.Floor(float64(width-line.Width) / 2))
+		case TextAlignRight:
+			offset = width - line.Width
+		}
+		for i := line.Start; i < line.End; i++ {
+			layout.Glyphs[i].Rect[0] += offset
+		}
+	}
+	layout.Size[0] = width
+	return layout
+}
+
+// Finds where the line starting at start ends, and where the next line starts
+func (f *Font) breakLine(items []textItem, start int, maxWidth float32) (int, int) {
+	var x float32
+	lastBreak := -1
+	i := start
+	for ; i < len(items) && items[i].char != '\n'; i++ {
+		it := items[i]
+		advance := float32(it.glyph.advance)
+		if i > start {
+			advance += f.kerning(items[i-1], it)
+		}
+		// spaces can hang past the edge, anything else starts a new line
+		if maxWidth > 0 && i > start && x+advance > maxWidth && !unicode.IsSpace(it.char) {
+			if lastBreak > start {
+				return lastBreak, lastBreak
+			}
+			return i, i
+		}
+		x += advance
+		if unicode.IsSpace(it.char) || isIdeograph(it.char) || (i+1 < len(items) && isIdeograph(items[i+1].char)) {
+			lastBreak = i + 1
+		}
+	}
+	if i < len(items) {
+		return i, i + 1 // past the newline
+	}
+	return i, i
+}
+
+// Adds a line's glyphs to the layout, positioned along x with kerning
+func (f *Font) placeLine(layout *TextLayout, items []textItem, size int) TextLine {
+	line := TextLine{Start: len(layout.Glyphs)}
+	var x, below float32
+	fit := func(size int) {
+		m := f.atSize(size)
+		line.Baseline = max32(line.Baseline, float32(m.baseline))
+		below = max32(below, float32(m.height-m.baseline))
+	}
+	if len(items) == 0 {
+		fit(size)
+	}
+	for i, it := range items {
+		if i > 0 {
+			x += f.kerning(items[i-1], it)
+		}
+		fit(it.size)
+		advance := float32(it.glyph.advance)
+		layout.Glyphs = append(layout.Glyphs, TextGlyph{
+			Char:     it.char,
+			Index:    it.index,
+			Rect:     mgl32.Vec4{x, 0, advance, 0},
+			Size:     it.size,
+			Colour:   it.colour,
+			Coloured: it.coloured,
+			glyph:    it.glyph,
+		})
+		x += advance
+	}
+	line.Width = x
+	line.End = len(layout.Glyphs)
+	line.Height = line.Baseline + below
+	return line
+}
+
+// Width of a line up to the end of its last word
+func trimmedWidth(glyphs []TextGlyph) float32 {
+	for i := len(glyphs) - 1; i >= 0; i-- {
+		if !unicode.IsSpace(glyphs[i].Char) {
+			return glyphs[i].Rect[0] + glyphs[i].Rect[2] - glyphs[0].Rect[0]
+		}
+	}
+	return 0
+}
+
+// Spreads extra space between the words of a line
+func justify(glyphs []TextGlyph, extra float32) {
+	last := len(glyphs) - 1
+	for last >= 0 && unicode.IsSpace(glyphs[last].Char) {
+		last--
+	}
+	gaps := 0
+	for _, g := range glyphs[:last+1] {
+		if unicode.IsSpace(g.Char) {
+			gaps++
+		}
+	}
+	if gaps == 0 || extra <= 0 {
+		return
+	}
+	var shift float32
+	gap := 0
+	for i := range glyphs[:last+1] {
+		glyphs[i].Rect[0] += shift
+		if unicode.IsSpace(glyphs[i].Char) {
+			gap++
+			// whole pixels, spread evenly
+			next := float32(math.Floor(float64(extra * float32(gap) / float32(gaps))))
+			glyphs[i].Rect[2] += next - shift
+			shift = next
+		}
+	}
+}
+
+// Kerning between two characters in pixels, if they come from the same font at the same size
+func (f *Font) kerning(a, b textItem) float32 {
+	if a.glyph == nil || b.glyph == nil || a.size != b.size || a.glyph.font != b.glyph.font {
+		return 0
+	}
+	return float32(a.glyph.font.kern(a.size, a.glyph, b.glyph))
+}
+
+// Lines can break either side of CJK characters, which don't use spaces between words
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Returns the index of the glyph under x, y, or -1
+func (l TextLayout) GlyphAt(x, y float32) int {
+	for _, line := range l.Lines {
+		if y < line.Y || y >= line.Y+line.Height {
+			continue
+		}
+		for i := line.Start; i < line.End; i++ {
+			r := l.Glyphs[i].Rect
+			if x >= r[0] && x < r[0]+r[2] {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Moves every glyph and line by x, y
+func (l TextLayout) offset(x, y float32) TextLayout {
+	glyphs := make([]TextGlyph, len(l.Glyphs))
+	for i, g := range l.Glyphs {
+		g.Rect[0] += x
+		g.Rect[1] += y
+		glyphs[i] = g
+	}
+	lines := make([]TextLine, len(l.Lines))
+	for i, line := range l.Lines {
+		line.Y += y
+		lines[i] = line
+	}
+	l.Glyphs, l.Lines = glyphs, lines
+	return l
+}
+
+// Splits text into characters, applying markup tags if markup is true
+func parseText(str string, size int, markup bool) []textItem {
+	items := make([]textItem, 0, len(str))
+	colours := []mgl32.Vec4{{}} // the first isn't set by markup
+	sizes := []int{size}
+	for i := 0; i < len(str); {
+		if markup && str[i] == '[' {
+			if strings.HasPrefix(str[i:], "[[") {
+				items = append(items, textItem{char: '[', index: i, size: sizes[len(sizes)-1], colour: colours[len(colours)-1], coloured: len(colours) > 1})
+				i += 2
+				continue
+			}
+			if end := strings.IndexByte(str[i:], ']'); end > 0 {
+				tag := str[i+1 : i+end]
+				if applyTag(tag, &colours, &sizes) {
+					i += end + 1
+					continue
+				}
+			}
+		}
+		char, n := utf8.DecodeRuneInString(str[i:])
+		items = append(items, textItem{char: char, index: i, size: sizes[len(sizes)-1], colour: colours[len(colours)-1], coloured: len(colours) > 1})
+		i += n
+	}
+	return items
+}
+
+// Largest [size=N] markup accepts. Every size gets its own rasterized glyphs, so bigger ones are drawn as text instead
+const maxMarkupSize = 256
+
+// Pushes or pops a style for a markup tag. Returns false if the tag isn't understood
+func applyTag(tag string, colours *[]mgl32.Vec4, sizes *[]int) bool {
+	switch {
+	case tag == "/color" && len(*colours) > 1:
+		*colours = (*colours)[:len(*colours)-1]
+	case tag == "/size" && len(*sizes) > 1:
+		*sizes = (*sizes)[:len(*sizes)-1]
+	case strings.HasPrefix(tag, "color="):
+		colour, ok := parseHexColour(tag[len("color="):])
+		if !ok {
+			return false
+		}
+		*colours = append(*colours, colour)
+	case strings.HasPrefix(tag, "size="):
+		size, err := strconv.Atoi(tag[len("size="):])
+		if err != nil || size <= 0 || size > maxMarkupSize {
+			return false
+		}
+		*sizes = append(*sizes, size)
+	default:
+		return false
+	}
+	return true
+}
+
+// Parses #rgb, #rrggbb or #rrggbbaa
+func parseHexColour(s string) (mgl32.Vec4, bool) {
+	if !strings.HasPrefix(s, "#") {
+		return mgl32.Vec4{}, false
+	}
+	s = s[1:]
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) == 6 {
+		s += "ff"
+	}
+	if len(s) != 8 {
+		return mgl32.Vec4{}, false
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return mgl32.Vec4{}, false
+	}
+	return mgl32.Vec4{
+		float32(v>>24&0xff) / 255,
+		float32(v>>16&0xff) / 255,
+		float32(v>>8&0xff) / 255,
+		float32(v&0xff) / 255,
+	}, true
+}
@@ -0,0 +1,125 @@
+package engine
+
+import "sort"
+
+// Texts whose renderItems are kept for reuse. Beyond this the least recently drawn are deleted,
+// so text that changes every frame, like a timer, doesn't build up
+const textCacheMax = 256
+
+// Text and how it was laid out, for caching its renderItems
+type textKey struct {
+	size int
+	str  string
+	opts TextOptions
+}
+
+// Glyph atlas and text cache usage. Counts are since the font was loaded
+type FontStats struct {
+	Pages            int
+	AtlasPixels      int // area of every page
+	GlyphPixels      int // area of the pages taken by glyphs
+	Glyphs           int // in the atlas now
+	GlyphsRasterized int
+	PagesCleared     int // to make room, often means more different text is drawn at once than fits
+
+	CachedTexts  int
+	TextHits     int // drawn with cached renderItems
+	TextMisses   int // laid out and built
+	TextsEvicted int
+}
+
+// GPU resources that text drawn this pass may still be using, released on a later pass
+type retired struct {
+	pass    uint64
+	release func()
+}
+
+func (f *Font) Stats() FontStats {
+	s := f.stats
+	s.Pages = len(f.pages)
+	for _, p := range f.pages {
+		b := p.img.Bounds()
+		s.AtlasPixels += b.Dx() * b.Dy()
+	}
+	for _, g := range f.glyphs {
+		// scaled glyphs share the rect of the size they're scaled from
+		if g.page >= 0 && g.scale == 1 {
+			s.GlyphPixels += g.rect.Dx() * g.rect.Dy()
+			s.Glyphs++
+		}
+	}
+	s.CachedTexts = len(f.renderDatas)
+	return s
+}
+
+// Returns cached renderItems for text, marking them and their pages as drawn this pass
+func (f *Font) cachedText(key textKey) (*stringRenderItemSize, bool) {
+	rd, ok := f.renderDatas[key]
+	if !ok {
+		return nil, false
+	}
+	rd.used = uiPass
+	for _, p := range rd.pages {
+		f.pages[p].used = uiPass
+	}
+	f.stats.TextHits++
+	return rd, true
+}
+
+// Adds text to the cache, deleting the least recently drawn if it's full
+func (f *Font) cacheText(key textKey, rd *stringRenderItemSize) {
+	f.stats.TextMisses++
+	rd.used = uiPass
+	f.renderDatas[key] = rd
+	if len(f.renderDatas) <= textCacheMax {
+		return
+	}
+
+	// trim to three quarters, so it isn't sorted on every new text
+	keys := make([]textKey, 0, len(f.renderDatas))
+	for k := range f.renderDatas {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return f.renderDatas[keys[i]].used < f.renderDatas[keys[j]].used
+	})
+	for _, k := range keys[:len(keys)-textCacheMax*3/4] {
+		f.deleteText(f.renderDatas[k])
+		delete(f.renderDatas, k)
+		f.stats.TextsEvicted++
+	}
+}
+
+// Drops cached text, whose texture coordinates are out of date once pages grow or are cleared
+func (f *Font) clearRenderDatas() {
+	for _, rd := range f.renderDatas {
+		f.deleteText(rd)
+	}
+	f.renderDatas = make(map[textKey]*stringRenderItemSize)
+}
+
+func (f *Font) deleteText(rd *stringRenderItemSize) {
+	f.retire(func() {
+		for i, ri := range rd.items {
+			deleteVAO(ri.vao, rd.vbos[i])
+		}
+	})
+}
+
+// Releases a resource once nothing drawn this pass can be using it
+func (f *Font) retire(release func()) {
+	f.retired = append(f.retired, retired{pass: uiPass, release: release})
+}
+
+// Releases resources retired before this pass
+func (f *Font) releaseRetired() {
+	kept := f.retired[:0]
+	for _, r := range f.retired {
+		if r.pass == uiPass {
+			kept = append(kept, r)
+		} else {
+			r.release()
+		}
+	}
+	f.retired = kept
+}
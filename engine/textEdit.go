@@ -0,0 +1,256 @@
+package engine
+
+import (
+	"time"
+	"unicode"
+)
+
+// Options for UI.TextInput
+type TextInputOptions struct {
+	MaxLength int  // in characters, 0 for no limit
+	Password  bool // show Mask instead of the text, and disable copy and cut
+	Mask      rune // defaults to '*'
+}
+
+// Single line editing state, kept between updates for each text input.
+// Positions are in runes, so multi-byte characters are edited as one character
+type textEditor struct {
+	text   []rune
+	caret  int
+	anchor int // other end of the selection, equal to caret when nothing is selected
+
+	scroll    int           // first visible rune
+	dragging  bool          // mouse went down inside the input and is still held
+	lastInput string        // buffer contents after the last edit
+	blink     time.Duration // uptime the caret last moved, so it stays visible while typing
+}
+
+// Keeps the editor in sync with the buffer, in case the game changed it
+func (e *textEditor) sync(buf string) {
+	if buf == e.lastInput {
+		return
+	}
+	e.text = []rune(buf)
+	e.lastInput = buf
+	e.caret = len(e.text)
+	e.anchor = e.caret
+}
+
+func (e *textEditor) String() string {
+	return string(e.text)
+}
+
+func (e *textEditor) selection() (int, int) {
+	if e.anchor < e.caret {
+		return e.anchor, e.caret
+	}
+	return e.caret, e.anchor
+}
+
+func (e *textEditor) hasSelection() bool {
+	return e.anchor != e.caret
+}
+
+func (e *textEditor) selected() string {
+	start, end := e.selection()
+	return string(e.text[start:end])
+}
+
+func (e *textEditor) selectAll() {
+	e.anchor = 0
+	e.caret = len(e.text)
+}
+
+// Moves the caret, extending the selection if extend is set
+func (e *textEditor) moveTo(pos int, extend bool) {
+	e.caret = clampInt(pos, 0, len(e.text))
+	if !extend {
+		e.anchor = e.caret
+	}
+}
+
+// Moves one character, or collapses the selection towards dir
+func (e *textEditor) move(dir int, word, extend bool) {
+	if e.hasSelection() && !extend {
+		start, end := e.selection()
+		if dir < 0 {
+			e.moveTo(start, false)
+		} else {
+			e.moveTo(end, false)
+		}
+		return
+	}
+	if word {
+		e.moveTo(e.wordBoundary(e.caret, dir), extend)
+	} else {
+		e.moveTo(e.caret+dir, extend)
+	}
+}
+
+// Finds the next word boundary from pos, skipping any spaces first
+func (e *textEditor) wordBoundary(pos, dir int) int {
+	at := func(p int) rune {
+		if dir < 0 {
+			return e.text[p-1]
+		}
+		return e.text[p]
+	}
+	inside := func(p int) bool {
+		return (dir < 0 && p > 0) || (dir > 0 && p < len(e.text))
+	}
+	for inside(pos) && unicode.IsSpace(at(pos)) {
+		pos += dir
+	}
+	if inside(pos) && !isWordRune(at(pos)) {
+		return pos + dir // punctuation is a word of its own
+	}
+	for inside(pos) && isWordRune(at(pos)) {
+		pos += dir
+	}
+	return pos
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// Replaces the selection with str, dropping characters that can't be typed and any past maxLength.
+// Returns true if the text changed
+func (e *textEditor) insert(str string, maxLength int) bool {
+	runes := []rune{}
+	for _, r := range str {
+		if unicode.IsPrint(r) {
+			runes = append(runes, r)
+		}
+	}
+	start, end := e.selection()
+	if maxLength > 0 {
+		room := maxLength - (len(e.text) - (end - start))
+		if room < 0 {
+			room = 0
+		}
+		if len(runes) > room {
+			runes = runes[:room]
+		}
+	}
+	if len(runes) == 0 && start == end {
+		return false
+	}
+
+	text := make([]rune, 0, len(e.text)-(end-start)+len(runes))
+	text = append(text, e.text[:start]...)
+	text = append(text, runes...)
+	text = append(text, e.text[end:]...)
+	e.text = text
+	e.moveTo(start+len(runes), false)
+	return true
+}
+
+// Deletes the selection, or the character or word in direction dir. Returns true if the text changed
+func (e *textEditor) delete(dir int, word bool) bool {
+	if !e.hasSelection() {
+		if word {
+			e.moveTo(e.wordBoundary(e.caret, dir), true)
+		} else {
+			e.moveTo(e.caret+dir, true)
+		}
+	}
+	if !e.hasSelection() {
+		return false
+	}
+	return e.insert("", 0)
+}
+
+// Applies this update's keyboard input. Returns whether the text changed and whether Enter was pressed
+func (e *textEditor) handleKeys(in *input, opts TextInputOptions) (changed, submitted bool) {
+	shift := in.KeyDown(KeyLeftShift) || in.KeyDown(KeyRightShift)
+	// Ctrl on most platforms, Cmd on macOS
+	ctrl := in.KeyDown(KeyLeftControl) || in.KeyDown(KeyRightControl) || in.KeyDown(KeyLeftSuper) || in.KeyDown(KeyRightSuper)
+
+	if !in.paused() && len(in.textInput) > 0 {
+		changed = e.insert(string(in.textInput), opts.MaxLength) || changed
+	}
+
+	switch {
+	case in.KeyRepeat(KeyLeft):
+		e.move(-1, ctrl, shift)
+	case in.KeyRepeat(KeyRight):
+		e.move(1, ctrl, shift)
+	case in.KeyRepeat(KeyHome):
+		e.moveTo(0, shift)
+	case in.KeyRepeat(KeyEnd):
+		e.moveTo(len(e.text), shift)
+	case in.KeyRepeat(KeyBackspace):
+		changed = e.delete(-1, ctrl) || changed
+	case in.KeyRepeat(KeyDelete):
+		changed = e.delete(1, ctrl) || changed
+	case ctrl && in.KeyOnce(KeyA):
+		e.selectAll()
+	case ctrl && in.KeyOnce(KeyC) && !opts.Password:
+		if e.hasSelection() {
+			in.SetClipboard(e.selected())
+		}
+	case ctrl && in.KeyOnce(KeyX) && !opts.Password:
+		if e.hasSelection() {
+			in.SetClipboard(e.selected())
+			changed = e.delete(0, false) || changed
+		}
+	case ctrl && in.KeyRepeat(KeyV):
+		changed = e.insert(in.Clipboard(), opts.MaxLength) || changed
+	case in.KeyOnce(KeyEnter) || in.KeyOnce(KeyKPEnter):
+		submitted = true
+	}
+	return changed, submitted
+}
+
+// Text as it's drawn, masked for passwords
+func (e *textEditor) display(opts TextInputOptions) []rune {
+	if !opts.Password {
+		return e.text
+	}
+	mask := opts.Mask
+	if mask == 0 {
+		mask = '*'
+	}
+	masked := make([]rune, len(e.text))
+	for i := range masked {
+		masked[i] = mask
+	}
+	return masked
+}
+
+// Scrolls so the caret is visible when the text is wider than the input
+func (e *textEditor) scrollToCaret(positions []float32, width float32) {
+	e.scroll = clampInt(e.scroll, 0, len(e.text))
+	if e.caret < e.scroll {
+		e.scroll = e.caret
+	}
+	for e.scroll < e.caret && positions[e.caret]-positions[e.scroll] > width {
+		e.scroll++
+	}
+	// scroll back if text was deleted and there's room
+	for e.scroll > 0 && positions[len(e.text)]-positions[e.scroll-1] <= width {
+		e.scroll--
+	}
+}
+
+// Returns the caret position closest to x, measured from the start of the visible text
+func (e *textEditor) caretAt(positions []float32, x float32) int {
+	x += positions[e.scroll]
+	for i := e.scroll; i < len(e.text); i++ {
+		if x < (positions[i]+positions[i+1])/2 {
+			return i
+		}
+	}
+	return len(e.text)
+}
+
+func clampInt(v, low, high int) int {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
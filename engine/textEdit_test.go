@@ -0,0 +1,210 @@
+package engine
+
+import "testing"
+
+// Editor states are written as text with | for the caret and ^ for the anchor, when there's a selection
+func parseEditor(s string) *textEditor {
+	e := &textEditor{}
+	anchor := -1
+	for _, r := range s {
+		switch r {
+		case '|':
+			e.caret = len(e.text)
+		case '^':
+			anchor = len(e.text)
+		default:
+			e.text = append(e.text, r)
+		}
+	}
+	e.anchor = e.caret
+	if anchor >= 0 {
+		e.anchor = anchor
+	}
+	return e
+}
+
+func formatEditor(e *textEditor) string {
+	s := []rune{}
+	for i := 0; i <= len(e.text); i++ {
+		if i == e.anchor && e.hasSelection() {
+			s = append(s, '^')
+		}
+		if i == e.caret {
+			s = append(s, '|')
+		}
+		if i < len(e.text) {
+			s = append(s, e.text[i])
+		}
+	}
+	return string(s)
+}
+
+func TestTextEditMove(t *testing.T) {
+	tests := []struct {
+		start  string
+		dir    int
+		word   bool
+		extend bool
+		want   string
+	}{
+		{"hel|lo", 1, false, false, "hell|o"},
+		{"hel|lo", -1, false, false, "he|llo"},
+		{"|hello", -1, false, false, "|hello"},
+		{"hello|", 1, false, false, "hello|"},
+		{"hel|lo", 1, false, true, "hel^l|o"},
+		{"hel^l|o", -1, false, true, "hel|lo"},
+		{"h^ell|o", -1, false, false, "h|ello"},
+		{"h^ell|o", 1, false, false, "hell|o"},
+		{"h|ell^o", 1, false, false, "hell|o"},
+		{"|hello world", 1, true, false, "hello| world"},
+		{"hello| world", 1, true, false, "hello world|"},
+		{"hello world|", -1, true, false, "hello |world"},
+		{"hello |world", -1, true, false, "|hello world"},
+		{"|foo, bar", 1, true, false, "foo|, bar"},
+		{"foo|, bar", 1, true, false, "foo,| bar"},
+		{"foo_bar2 |baz", -1, true, true, "|foo_bar2 ^baz"},
+		{"|   ", 1, true, false, "   |"},
+	}
+	for _, tt := range tests {
+		e := parseEditor(tt.start)
+		e.move(tt.dir, tt.word, tt.extend)
+		if got := formatEditor(e); got != tt.want {
+			t.Errorf("move(%d, word %v, extend %v) from %q gave %q, want %q", tt.dir, tt.word, tt.extend, tt.start, got, tt.want)
+		}
+	}
+}
+
+func TestTextEditInsert(t *testing.T) {
+	tests := []struct {
+		start     string
+		str       string
+		maxLength int
+		want      string
+		changed   bool
+	}{
+		{"|", "abc", 0, "abc|", true},
+		{"he|o", "ll", 0, "hell|o", true},
+		{"h^ell|o", "EY", 0, "hEY|o", true},
+		{"h^ell|o", "", 0, "h|o", true},
+		{"ab|", "cdef", 4, "abcd|", true},
+		{"ab|", "cdef", 2, "ab|", false},
+		{"a^b|", "cdef", 2, "ac|", true},
+		{"a|", "b\nc\t", 0, "abc|", true},
+		{"a|", "\n", 0, "a|", false},
+		{"é|", "ü", 0, "éü|", true},
+	}
+	for _, tt := range tests {
+		e := parseEditor(tt.start)
+		changed := e.insert(tt.str, tt.maxLength)
+		if got := formatEditor(e); got != tt.want || changed != tt.changed {
+			t.Errorf("insert(%q, %d) into %q gave %q changed %v, want %q changed %v", tt.str, tt.maxLength, tt.start, got, changed, tt.want, tt.changed)
+		}
+	}
+}
+
+func TestTextEditDelete(t *testing.T) {
+	tests := []struct {
+		start   string
+		dir     int
+		word    bool
+		want    string
+		changed bool
+	}{
+		{"hel|lo", -1, false, "he|lo", true},
+		{"hel|lo", 1, false, "hel|o", true},
+		{"|hello", -1, false, "|hello", false},
+		{"hello|", 1, false, "hello|", false},
+		{"h^ell|o", -1, false, "h|o", true},
+		{"h|ell^o", 1, false, "h|o", true},
+		{"hello world|", -1, true, "hello |", true},
+		{"hello |world", 1, true, "hello |", true},
+		{"hello| world", 1, true, "hello|", true},
+		{"h^el|lo", 0, false, "h|lo", true},
+	}
+	for _, tt := range tests {
+		e := parseEditor(tt.start)
+		changed := e.delete(tt.dir, tt.word)
+		if got := formatEditor(e); got != tt.want || changed != tt.changed {
+			t.Errorf("delete(%d, word %v) from %q gave %q changed %v, want %q changed %v", tt.dir, tt.word, tt.start, got, changed, tt.want, tt.changed)
+		}
+	}
+}
+
+func TestTextEditSelection(t *testing.T) {
+	tests := []struct {
+		start    string
+		selected string
+	}{
+		{"hel|lo", ""},
+		{"h^ell|o", "ell"},
+		{"h|ell^o", "ell"},
+		{"^hello|", "hello"},
+	}
+	for _, tt := range tests {
+		if got := parseEditor(tt.start).selected(); got != tt.selected {
+			t.Errorf("%q selected %q, want %q", tt.start, got, tt.selected)
+		}
+	}
+
+	e := parseEditor("he|llo")
+	e.selectAll()
+	if got := formatEditor(e); got != "^hello|" {
+		t.Errorf("selectAll gave %q", got)
+	}
+	e.moveTo(99, false)
+	if got := formatEditor(e); got != "hello|" {
+		t.Errorf("moving past the end gave %q", got)
+	}
+}
+
+func TestTextEditSync(t *testing.T) {
+	e := parseEditor("he|llo")
+	e.lastInput = "hello"
+	e.sync("hello")
+	if got := formatEditor(e); got != "he|llo" {
+		t.Errorf("syncing the same text moved the caret: %q", got)
+	}
+	e.sync("bye")
+	if got := formatEditor(e); got != "bye|" {
+		t.Errorf("syncing new text gave %q, want the caret at the end", got)
+	}
+}
+
+func TestTextEditScrollAndCaretAt(t *testing.T) {
+	// every character is 10 pixels wide
+	positions := func(n int) []float32 {
+		p := make([]float32, n+1)
+		for i := range p {
+			p[i] = float32(i * 10)
+		}
+		return p
+	}
+	tests := []struct {
+		start      string
+		scroll     int
+		width      float32
+		wantScroll int
+		x          float32 // clicked, from the start of the visible text
+		wantCaret  int
+	}{
+		{"hello|", 0, 100, 0, 24, 2},
+		{"hello|", 0, 100, 0, 26, 3},
+		{"hello|", 0, 100, 0, 500, 5},
+		{"abcdefghij|", 0, 50, 5, 0, 5},
+		{"abcdefghij|", 0, 50, 5, 16, 7},
+		{"|abcdefghij", 5, 50, 0, 0, 0},
+		{"abc|", 2, 50, 0, 11, 1}, // text was deleted, so it scrolls back
+	}
+	for _, tt := range tests {
+		e := parseEditor(tt.start)
+		e.scroll = tt.scroll
+		p := positions(len(e.text))
+		e.scrollToCaret(p, tt.width)
+		if e.scroll != tt.wantScroll {
+			t.Errorf("%q scrolled from %d in %v pixels to %d, want %d", tt.start, tt.scroll, tt.width, e.scroll, tt.wantScroll)
+		}
+		if got := e.caretAt(p, tt.x); got != tt.wantCaret {
+			t.Errorf("%q clicked at %v gave caret %d, want %d", tt.start, tt.x, got, tt.wantCaret)
+		}
+	}
+}
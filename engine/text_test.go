@@ -0,0 +1,266 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// ProggyClean is monospaced, so at size 8 every character is 4 pixels wide and lines are 12 high
+func loadTestFont(t *testing.T) *Font {
+	t.Helper()
+	headless = true // glyphs are rasterized without a GL context
+	f, err := LoadFont("../res/ProggyClean.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func layoutLines(l TextLayout) []string {
+	lines := []string{}
+	for _, line := range l.Lines {
+		s := []rune{}
+		for _, g := range l.Glyphs[line.Start:line.End] {
+			s = append(s, g.Char)
+		}
+		lines = append(lines, string(s))
+	}
+	return lines
+}
+
+func TestTextLayoutWrapping(t *testing.T) {
+	f := loadTestFont(t)
+	tests := []struct {
+		name     string
+		str      string
+		maxWidth float32
+		want     []string
+		widths   []float32
+	}{
+		{"no wrapping", "hello world", 0, []string{"hello world"}, []float32{44}},
+		{"empty", "", 0, []string{""}, []float32{0}},
+		{"newlines", "ab\n\ncd", 0, []string{"ab", "", "cd"}, []float32{8, 0, 8}},
+		{"between words", "aaa bbb ccc", 32, []string{"aaa bbb ", "ccc"}, []float32{28, 12}},
+		{"spaces hang past the edge", "ab    cd", 16, []string{"ab    ", "cd"}, []float32{8, 8}},
+		{"words longer than a line are split", "aaaaaaaaaa", 16, []string{"aaaa", "aaaa", "aa"}, []float32{16, 16, 8}},
+		{"wrapping and newlines", "aa bb\ncc", 12, []string{"aa ", "bb", "cc"}, []float32{8, 8, 8}},
+		{"between CJK characters", "中文字符", 8, []string{"中文", "字符"}, []float32{8, 8}},
+		{"after a CJK character", "ab中cd", 12, []string{"ab中", "cd"}, []float32{12, 8}},
+		{"before a CJK character", "abc中", 12, []string{"abc", "中"}, []float32{12, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := f.Layout(8, tt.str, TextOptions{MaxWidth: tt.maxWidth})
+			if got := layoutLines(l); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("lines are %q, want %q", got, tt.want)
+			}
+			for n, line := range l.Lines {
+				if line.Width != tt.widths[n] || line.Y != float32(n*12) || line.Height != 12 {
+					t.Errorf("line %d is %v wide at y %v and %v high, want %v wide at %v and 12 high", n, line.Width, line.Y, line.Height, tt.widths[n], n*12)
+				}
+			}
+		})
+	}
+}
+
+func TestTextLayoutAlignment(t *testing.T) {
+	f := loadTestFont(t)
+	tests := []struct {
+		name  string
+		str   string
+		opts  TextOptions
+		xs    [][]float32 // of each glyph, by line
+		width float32
+	}{
+		{"left", "ab cdef", TextOptions{MaxWidth: 20}, [][]float32{{0, 4, 8}, {0, 4, 8, 12}}, 16},
+		{"centre", "ab cdef", TextOptions{MaxWidth: 20, Align: TextAlignCentre}, [][]float32{{6, 10, 14}, {2, 6, 10, 14}}, 20},
+		{"right", "ab cdef", TextOptions{MaxWidth: 20, Align: TextAlignRight}, [][]float32{{12, 16, 20}, {4, 8, 12, 16}}, 20},
+		{"centre without wrapping uses the widest line", "ab\ncdef", TextOptions{Align: TextAlignCentre}, [][]float32{{4, 8}, {0, 4, 8, 12}}, 16},
+		{"justify spreads the gaps", "a b c dd", TextOptions{MaxWidth: 24, Align: TextAlignJustify}, [][]float32{{0, 4, 10, 14, 20, 20}, {0, 4}}, 24},
+		{"justify leaves a line without gaps", "abcdefgh", TextOptions{MaxWidth: 24, Align: TextAlignJustify}, [][]float32{{0, 4, 8, 12, 16, 20}, {0, 4}}, 24},
+		{"justify leaves the last line of a paragraph", "a b\nc d", TextOptions{MaxWidth: 24, Align: TextAlignJustify}, [][]float32{{0, 4, 8}, {0, 4, 8}}, 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := f.Layout(8, tt.str, tt.opts)
+			xs := [][]float32{}
+			for _, line := range l.Lines {
+				x := []float32{}
+				for _, g := range l.Glyphs[line.Start:line.End] {
+					x = append(x, g.Rect[0])
+				}
+				xs = append(xs, x)
+			}
+			if !reflect.DeepEqual(xs, tt.xs) {
+				t.Errorf("glyphs are at %v, want %v", xs, tt.xs)
+			}
+			if l.Size[0] != tt.width {
+				t.Errorf("layout is %v wide, want %v", l.Size[0], tt.width)
+			}
+		})
+	}
+}
+
+func TestTextLayoutJustifiedGapWidths(t *testing.T) {
+	f := loadTestFont(t)
+	l := f.Layout(8, "a b c dd", TextOptions{MaxWidth: 24, Align: TextAlignJustify})
+	// the gaps are widened too, so clicks between words still land on a glyph
+	for i, want := range []float32{4, 6, 4, 6, 4} {
+		if w := l.Glyphs[i].Rect[2]; w != want {
+			t.Errorf("glyph %d is %v wide, want %v", i, w, want)
+		}
+	}
+}
+
+func TestTextLayoutSizesAndSpacing(t *testing.T) {
+	f := loadTestFont(t)
+	l := f.Layout(8, "a[size=16]b[/size]\nc", TextOptions{Markup: true, LineSpacing: 2})
+	if len(l.Lines) != 2 {
+		t.Fatalf("%d lines, want 2", len(l.Lines))
+	}
+	first := l.Lines[0]
+	if first.Height != 18 || first.Baseline != 12 {
+		t.Errorf("mixed size line is %v high with baseline %v, want the bigger size's 18 and 12", first.Height, first.Baseline)
+	}
+	if g := l.Glyphs[1]; g.Size != 16 || g.Rect[0] != 4 || g.Rect[2] != 7 {
+		t.Errorf("b is size %d at %v, want size 16 at x 4, 7 wide", g.Size, g.Rect)
+	}
+	if second := l.Lines[1]; second.Y != 36 || second.Height != 12 {
+		t.Errorf("second line is at %v and %v high, want double spaced at 36 and 12 high", second.Y, second.Height)
+	}
+	if l.Size != (mgl32.Vec2{11, 48}) {
+		t.Errorf("layout is %v, want [11 48]", l.Size)
+	}
+}
+
+func TestTextLayoutGlyphAt(t *testing.T) {
+	f := loadTestFont(t)
+	l := f.Layout(8, "ab\ncd", TextOptions{})
+	tests := []struct {
+		x, y float32
+		want int
+	}{
+		{0, 0, 0},
+		{5, 11, 1},
+		{5, 12, 3},
+		{8, 0, -1},
+		{0, 24, -1},
+		{-1, 0, -1},
+	}
+	for _, tt := range tests {
+		if got := l.GlyphAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("GlyphAt(%v, %v) = %d, want %d", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestParseTextMarkup(t *testing.T) {
+	red := mgl32.Vec4{1, 0, 0, 1}
+	blue := mgl32.Vec4{0, 0, 1, 1}
+	tests := []struct {
+		name    string
+		str     string
+		markup  bool
+		text    string
+		sizes   []int
+		colours []mgl32.Vec4
+	}{
+		{"plain", "ab", true, "ab", []int{8, 8}, []mgl32.Vec4{{}, {}}},
+		{"markup off", "[color=#f00]a", false, "[color=#f00]a", nil, nil},
+		{"colour", "a[color=#f00]b[/color]c", true, "abc", nil, []mgl32.Vec4{{}, red, {}}},
+		{"size", "[size=12]a[/size]b", true, "ab", []int{12, 8}, nil},
+		{"nested", "[color=#f00]a[color=#00f]b[/color]c[/color]d", true, "abcd", nil, []mgl32.Vec4{red, blue, red, {}}},
+		{"nested sizes and colours", "[size=12][color=#f00]a[/size]b[/color]", true, "ab", []int{12, 8}, []mgl32.Vec4{red, red}},
+		{"unclosed", "[color=#f00][size=20]ab", true, "ab", []int{20, 20}, []mgl32.Vec4{red, red}},
+		{"close without open", "[/color]a[/size]", true, "[/color]a[/size]", nil, nil},
+		{"escaped bracket", "[[color=#f00]a", true, "[color=#f00]a", nil, nil},
+		{"escaped bracket in colour", "[color=#f00][[x][/color]", true, "[x]", nil, []mgl32.Vec4{red, red, red}},
+		{"unknown tag", "[b]a[/b]", true, "[b]a[/b]", nil, nil},
+		{"bad colour", "[color=red]a", true, "[color=red]a", nil, nil},
+		{"bad size", "[size=0]a[size=x]", true, "[size=0]a[size=x]", nil, nil},
+		{"largest size", "[size=256]a", true, "a", []int{256}, nil},
+		{"size past the limit", "[size=257]a", true, "[size=257]a", nil, nil},
+		{"unterminated tag", "a[color=#f00", true, "a[color=#f00", nil, nil},
+		{"empty tag", "[]a", true, "[]a", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := parseText(tt.str, 8, tt.markup)
+			text := []rune{}
+			sizes := []int{}
+			colours := []mgl32.Vec4{}
+			for _, it := range items {
+				text = append(text, it.char)
+				sizes = append(sizes, it.size)
+				colours = append(colours, it.colour)
+			}
+			if string(text) != tt.text {
+				t.Fatalf("text is %q, want %q", string(text), tt.text)
+			}
+			if tt.sizes != nil && !reflect.DeepEqual(sizes, tt.sizes) {
+				t.Errorf("sizes are %v, want %v", sizes, tt.sizes)
+			}
+			if tt.colours != nil && !reflect.DeepEqual(colours, tt.colours) {
+				t.Errorf("colours are %v, want %v", colours, tt.colours)
+			}
+		})
+	}
+}
+
+func TestParseTextTransparentColour(t *testing.T) {
+	items := parseText("a[color=#00000000]b[/color]c", 8, true)
+	for n, want := range []bool{false, true, false} {
+		if items[n].coloured != want || items[n].colour != (mgl32.Vec4{}) {
+			t.Errorf("%q is coloured %v with %v, want %v", items[n].char, items[n].coloured, items[n].colour, want)
+		}
+	}
+}
+
+func TestTextColouredKeepsMarkupColours(t *testing.T) {
+	f := loadTestFont(t)
+	white := mgl32.Vec4{1, 1, 1, 0.5}
+	items := f.renderText(0, 0, 8, "a[color=#00000000]b[/color][color=#f00]c", TextOptions{Markup: true}).coloured(white)
+	want := []mgl32.Vec4{white, {}, {1, 0, 0, 0.5}}
+	if len(items) != len(want) {
+		t.Fatalf("%d renderItems, want one for each colour", len(items))
+	}
+	for n, ri := range items {
+		if ri.colour != want[n] {
+			t.Errorf("item %d is %v, want %v", n, ri.colour, want[n])
+		}
+	}
+}
+
+func TestParseTextIndices(t *testing.T) {
+	items := parseText("[size=12]é[[b", 8, true)
+	want := []int{9, 11, 13}
+	for n, it := range items {
+		if it.index != want[n] {
+			t.Errorf("item %d (%q) is at byte %d, want %d", n, it.char, it.index, want[n])
+		}
+	}
+}
+
+func TestParseHexColour(t *testing.T) {
+	tests := []struct {
+		s    string
+		want mgl32.Vec4
+		ok   bool
+	}{
+		{"#f00", mgl32.Vec4{1, 0, 0, 1}, true},
+		{"#00ff00", mgl32.Vec4{0, 1, 0, 1}, true},
+		{"#0000ff00", mgl32.Vec4{0, 0, 1, 0}, true},
+		{"#FFF", mgl32.Vec4{1, 1, 1, 1}, true},
+		{"f00", mgl32.Vec4{}, false},
+		{"#ff", mgl32.Vec4{}, false},
+		{"#ggg", mgl32.Vec4{}, false},
+		{"#", mgl32.Vec4{}, false},
+	}
+	for _, tt := range tests {
+		if got, ok := parseHexColour(tt.s); got != tt.want || ok != tt.ok {
+			t.Errorf("parseHexColour(%q) = %v %v, want %v %v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package engine
 
 import (
 	"image"
-	"os"
 
 	_ "image/png"
 
@@ -14,10 +13,13 @@ type Image struct {
 	id     uint32
 	width  float32
 	height float32
+	pixels *image.RGBA // CPU copy of the image, only kept when running headless
+
+	translucent bool // has partially transparent pixels, so needs sorting and blending back to front
 }
 
 func NewImage(filepath string) (Image, error) {
-	file, err := os.Open(filepath)
+	file, err := openAsset(filepath)
 	if err != nil {
 		return Image{}, err
 	}
@@ -28,6 +30,10 @@ func NewImage(filepath string) (Image, error) {
 		return Image{}, err
 	}
 
+	if headless {
+		return newSoftImage(toRGBA(img)), nil
+	}
+
 	w := img.Bounds().Max.X
 	h := img.Bounds().Max.Y
 
@@ -56,13 +62,20 @@ func NewImage(filepath string) (Image, error) {
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
 	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA16F, int32(w), int32(h), 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(pixels))
 	return Image{
-		id:     tex,
-		width:  float32(w),
-		height: float32(h),
+		id:          tex,
+		width:       float32(w),
+		height:      float32(h),
+		translucent: hasTranslucency(pixels),
 	}, nil
 }
 
 func NewBlankImage(width, height float32) Image {
+	if headless {
+		img := newSoftImage(image.NewRGBA(image.Rect(0, 0, int(width), int(height))))
+		img.translucent = true
+		return img
+	}
+
 	var tex uint32
 	gl.GenTextures(1, &tex)
 	gl.BindTexture(gl.TEXTURE_2D, tex)
@@ -72,15 +85,20 @@ func NewBlankImage(width, height float32) Image {
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
 	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA16F, int32(width), int32(height), 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
 
+	// contents aren't known yet, so assume the worst
 	return Image{
-		id:     tex,
-		width:  width,
-		height: height,
+		id:          tex,
+		width:       width,
+		height:      height,
+		translucent: true,
 	}
 
 }
 
 func (t Image) Use() {
+	if headless {
+		return
+	}
 	gl.BindTexture(gl.TEXTURE_2D, t.id)
 }
 
@@ -0,0 +1,295 @@
+package engine
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Fonts, colours and skins used to draw the UI. Themes can be written to and read from JSON,
+// where colours are arrays of 4 numbers from 0 to 1
+type Theme struct {
+	Font           *Font     `json:"-"`
+	FontPath       string    // loaded into Font by LoadTheme
+	Fallbacks      []string  // paths of fonts for characters Font doesn't have, tried in order
+	SDF            bool      // draws text with signed distance fields, so it scales smoothly and can be styled
+	TextStyle      TextStyle // outline, shadow and glow of widget text, when SDF is set
+	FontSize       int       // widget text
+	ButtonFontSize int
+	Padding        float32 // between a widget's edge and its text
+	Skin           Skin    // for styles without their own
+
+	Label     mgl32.Vec4 // text beside widgets
+	Hint      mgl32.Vec4 // text inputs' hint text
+	Accent    mgl32.Vec4 // fills, checks and selected items
+	Selection mgl32.Vec4 // selected text
+	Track     mgl32.Vec4 // behind sliders, progress bars and scrollbars
+	Dim       mgl32.Vec4 // over the screen behind modals
+	Focus     mgl32.Vec4 // outline of the widget with keyboard or gamepad focus
+
+	Button      WidgetStyle // tinted by the colour passed to Button
+	Checkbox    WidgetStyle // and radio buttons
+	Slider      WidgetStyle // the thumb, also used for scrollbars
+	Dropdown    WidgetStyle
+	TextInput   WidgetStyle
+	ScrollPanel WidgetStyle // and lists, where Hot is drawn over the row under the mouse
+	Modal       WidgetStyle
+	Tooltip     WidgetStyle
+}
+
+// Colours for each state of a widget, and the skin drawn with them
+type WidgetStyle struct {
+	Normal   mgl32.Vec4
+	Hot      mgl32.Vec4 // under the mouse
+	Active   mgl32.Vec4 // held down, or focused for text inputs
+	Disabled mgl32.Vec4 // between BeginDisabled and EndDisabled
+	Text     mgl32.Vec4
+	Skin     Skin // the theme's skin if it has no Image
+}
+
+// Texture drawn as a nine-slice. The corners keep their size, the edges stretch along
+// their length and the middle stretches both ways, so borders don't distort
+type Skin struct {
+	Image  string     // path, loaded by LoadTheme
+	Border [4]float32 // insets of the left, top, right and bottom edges in texture pixels
+
+	tex    Texture
+	source string // Image that tex was loaded from
+}
+
+// Makes a skin from a texture that's already loaded, like part of an atlas
+func NewSkin(tex Texture, left, top, right, bottom float32) Skin {
+	return Skin{
+		Border: [4]float32{left, top, right, bottom},
+		tex:    tex,
+		source: "-",
+	}
+}
+
+func (s Skin) loaded() bool {
+	return s.source != ""
+}
+
+func defaultTheme() *Theme {
+	white := mgl32.Vec4{1, 1, 1, 1}
+	box := WidgetStyle{
+		Normal:   white,
+		Hot:      mgl32.Vec4{0.9, 0.9, 0.9, 1},
+		Active:   mgl32.Vec4{0.8, 0.8, 0.8, 1},
+		Disabled: mgl32.Vec4{0.6, 0.6, 0.6, 1},
+		Text:     mgl32.Vec4{0, 0, 0, 1},
+	}
+	button := box
+	button.Hot = mgl32.Vec4{0.75, 0.75, 0.75, 1}
+	button.Active = mgl32.Vec4{0.6, 0.6, 0.6, 1}
+	input := box
+	input.Active = box.Hot
+
+	return &Theme{
+		FontPath:       embeddedPrefix + "ProggyClean.ttf",
+		FontSize:       16,
+		ButtonFontSize: 32,
+		Padding:        4,
+		Skin:           Skin{Image: embeddedPrefix + "ui9slice.png", Border: [4]float32{4, 4, 4, 4}},
+
+		Label:     white,
+		Hint:      mgl32.Vec4{0.5, 0.5, 0.5, 1},
+		Accent:    mgl32.Vec4{0.3, 0.5, 0.9, 1},
+		Selection: mgl32.Vec4{0.4, 0.6, 1, 0.6},
+		Track:     mgl32.Vec4{0.2, 0.2, 0.2, 0.8},
+		Dim:       mgl32.Vec4{0, 0, 0, 0.5},
+		Focus:     mgl32.Vec4{1, 0.8, 0.2, 1},
+
+		Button:    button,
+		Checkbox:  box,
+		Slider:    box,
+		Dropdown:  box,
+		TextInput: input,
+		ScrollPanel: WidgetStyle{
+			Normal:   mgl32.Vec4{0.2, 0.2, 0.2, 0.8},
+			Hot:      mgl32.Vec4{1, 1, 1, 0.2},
+			Active:   mgl32.Vec4{1, 1, 1, 0.3},
+			Disabled: mgl32.Vec4{0.2, 0.2, 0.2, 0.5},
+			Text:     white,
+		},
+		Modal: box,
+		Tooltip: WidgetStyle{
+			Normal: mgl32.Vec4{0.1, 0.1, 0.1, 0.9},
+			Text:   white,
+		},
+	}
+}
+
+func (t *Theme) styles() []*WidgetStyle {
+	return []*WidgetStyle{&t.Button, &t.Checkbox, &t.Slider, &t.Dropdown, &t.TextInput, &t.ScrollPanel, &t.Modal, &t.Tooltip}
+}
+
+// Loads the font and any skins whose Image has changed. Nothing is changed unless everything loads,
+// as the font may be the one the UI is drawing with
+func (t *Theme) load() error {
+	font := t.Font
+	if font == nil || t.FontPath != font.path {
+		var err error
+		if font, err = LoadFont(t.FontPath); err != nil {
+			return err
+		}
+	}
+	var fallbacks []*Font
+	changeFallbacks := font != t.Font || !t.fallbacksLoaded()
+	if changeFallbacks {
+		fallbacks = make([]*Font, len(t.Fallbacks))
+		for i, path := range t.Fallbacks {
+			fallback, err := LoadFont(path)
+			if err != nil {
+				return err
+			}
+			fallbacks[i] = fallback
+		}
+	}
+
+	skins := []*Skin{&t.Skin}
+	for _, s := range t.styles() {
+		skins = append(skins, &s.Skin)
+	}
+	images := make([]Image, len(skins))
+	for i, s := range skins {
+		if s.Image == "" || s.Image == s.source {
+			continue
+		}
+		img, err := NewImage(s.Image)
+		if err != nil {
+			return err
+		}
+		images[i] = img
+	}
+
+	for i, s := range skins {
+		if s.Image != "" && s.Image != s.source {
+			s.tex = Texture{image: images[i], texCoords: mgl32.Vec4{0, 1, 0, 1}}
+			s.source = s.Image
+		}
+	}
+	t.Font = font
+	if changeFallbacks {
+		t.Font.SetFallbacks(fallbacks...)
+	}
+	t.Font.SetSDF(t.SDF)
+	return nil
+}
+
+func (t *Theme) fallbacksLoaded() bool {
+	if len(t.Fallbacks) != len(t.Font.fallbacks) {
+		return false
+	}
+	for i, path := range t.Fallbacks {
+		if t.Font.fallbacks[i].path != path {
+			return false
+		}
+	}
+	return true
+}
+
+// Writes the theme as JSON, a starting point for LoadTheme
+func (t *Theme) Save(path string) error {
+	data, err := json.MarshalIndent(t, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Reads a theme written by Theme.Save and uses it. Anything left out of the file keeps its current value
+func (ui *ui) LoadTheme(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	theme := *ui.theme
+	if err := json.Unmarshal(data, &theme); err != nil {
+		return err
+	}
+	if err := theme.load(); err != nil {
+		return err
+	}
+	ui.theme = &theme
+	return nil
+}
+
+// Uses a theme built in code. Its font and skins are loaded from their paths if they aren't already
+func (ui *ui) SetTheme(t *Theme) error {
+	if err := t.load(); err != nil {
+		return err
+	}
+	ui.theme = t
+	return nil
+}
+
+// The theme in use. Changes to it show from the next draw
+func (ui *ui) Theme() *Theme {
+	return ui.theme
+}
+
+// Widgets between BeginDisabled and EndDisabled are drawn in their Disabled colour and ignore input
+func (ui *ui) BeginDisabled() {
+	ui.disabled++
+}
+
+func (ui *ui) EndDisabled() {
+	if ui.disabled > 0 {
+		ui.disabled--
+	}
+}
+
+// Picks the style's colour for the widget's state
+func (ui *ui) styleColour(s WidgetStyle, hot, active bool) mgl32.Vec4 {
+	switch {
+	case ui.disabled > 0:
+		return s.Disabled
+	case active:
+		return s.Active
+	case hot:
+		return s.Hot
+	}
+	return s.Normal
+}
+
+// Draws the style's skin, or the theme's, as a nine-slice
+func (ui *ui) box(x, y, w, h float32, colour mgl32.Vec4, s WidgetStyle) {
+	skin := s.Skin
+	if !skin.loaded() {
+		skin = ui.theme.Skin
+	}
+	tex := skin.tex
+	texSize := mgl32.Vec2{
+		(tex.texCoords[1] - tex.texCoords[0]) * tex.image.width,
+		(tex.texCoords[3] - tex.texCoords[2]) * tex.image.height,
+	}
+	vertices, indices := nineSliceQuad(w, h, tex.texCoords, mgl32.Vec4(skin.Border), texSize)
+	ui.mesh(x, y, w, h, vertices, indices, tex.image, colour)
+}
+
+// Draws a plain rectangle, using a single pixel from the middle of the theme's skin
+func (ui *ui) fill(x, y, w, h float32, colour mgl32.Vec4) {
+	tex := ui.theme.Skin.tex
+	u, v := (tex.texCoords[0]+tex.texCoords[1])/2, (tex.texCoords[2]+tex.texCoords[3])/2
+	vertices, indices := quad(w, h, mgl32.Vec4{u, u, v, v})
+	ui.mesh(x, y, w, h, vertices, indices, tex.image, colour)
+}
+
+// Draws a mesh centred in the rectangle. It's streamed to the GPU when the UI is rendered, since it only lasts a pass
+func (ui *ui) mesh(x, y, w, h float32, vertices []float32, indices []uint32, image Image, colour mgl32.Vec4) {
+	ui.push(renderItem{
+		vertices:  vertices,
+		elements:  indices,
+		image:     image,
+		shader:    uiShader.Shader,
+		transform: NewTransform(x+(w/2), y+(h/2), 8),
+		colour:    colour,
+	})
+}
+
+// Multiplies two colours
+func tint(colour, by mgl32.Vec4) mgl32.Vec4 {
+	return mgl32.Vec4{colour[0] * by[0], colour[1] * by[1], colour[2] * by[2], colour[3] * by[3]}
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadThemeFailureLeavesThemeAlone(t *testing.T) {
+	h := headless
+	defer func() { headless = h }()
+	headless = true // fonts and skins are loaded without a GL context
+
+	ui := initUI()
+	live, font := ui.theme, ui.theme.Font
+	font.glyph(16, 'a')
+
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"missing skin", `{"SDF": true, "Fallbacks": ["../res/ProggyClean.ttf"], "Skin": {"Image": "missing.png"}}`},
+		{"missing style skin", `{"SDF": true, "Button": {"Skin": {"Image": "missing.png"}}}`},
+		{"missing fallback", `{"SDF": true, "Fallbacks": ["missing.ttf"]}`},
+		{"missing font", `{"FontPath": "missing.ttf", "SDF": true}`},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "theme.json")
+		if err := os.WriteFile(path, []byte(tt.json), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ui.LoadTheme(path); err == nil {
+			t.Fatalf("%s: theme loaded", tt.name)
+		}
+		if ui.theme != live || live.Font != font {
+			t.Errorf("%s: failed load replaced the theme or its font", tt.name)
+		}
+		if font.sdf || len(font.fallbacks) != 0 || len(font.glyphs) == 0 {
+			t.Errorf("%s: failed load changed the font, sdf %v with %d fallbacks and %d glyphs", tt.name, font.sdf, len(font.fallbacks), len(font.glyphs))
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "theme.json")
+	if err := os.WriteFile(path, []byte(`{"SDF": true, "Fallbacks": ["../res/ProggyClean.ttf"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ui.LoadTheme(path); err != nil {
+		t.Fatal(err)
+	}
+	if ui.theme.Font != font || !font.sdf || len(font.fallbacks) != 1 {
+		t.Errorf("theme loaded without changing the font, sdf %v with %d fallbacks", font.sdf, len(font.fallbacks))
+	}
+}
@@ -3,7 +3,6 @@ package engine
 import (
 	"time"
 
-	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/lafriks/go-tiled"
 )
 
@@ -19,10 +18,15 @@ type Tilemap struct {
 	normals        []Texture
 	useNormals     bool
 	staticVAO      uint32
+	staticVBO      uint32
+	staticInd      int32
 	animatedVAO    uint32
 	animatedVBO    uint32
+	animatedInd    int32
 	changed        *bool // if the animated tiles have changed
 	animIndex      *int
+	animTimer      *clockTimer // advances animIndex, nil if no tiles are animated
+	layer          int
 }
 
 var tilemapShader *Shader
@@ -106,21 +110,17 @@ func LoadTilemap(tmxPath, atlasPath string, normalPath string, scale float32) Ti
 		duration := 0
 		duration = int(m.Tilesets[0].Tiles[0].Animation[0].Duration)
 
-		tick := time.NewTicker(time.Duration(duration) * time.Millisecond)
-		go func() {
-			for range tick.C {
-				*tileMap.animIndex++
-				if *tileMap.animIndex > 50 {
-					*tileMap.animIndex = 0
-				}
-
-				*tileMap.changed = true
-				tick.Reset(time.Duration(duration) * time.Millisecond)
+		tileMap.animTimer = Time.every(time.Duration(duration)*time.Millisecond, func() {
+			*tileMap.animIndex++
+			if *tileMap.animIndex > 50 {
+				*tileMap.animIndex = 0
 			}
-		}()
+
+			*tileMap.changed = true
+		})
 	}
 
-	tileMap.staticVAO, tileMap.animatedVAO, tileMap.animatedVBO = tileMap.init()
+	tileMap.init()
 	return tileMap
 }
 
@@ -143,28 +143,32 @@ func atlasToTextures(filepath string, tileSize, atlasWidth, atlasHeight, tileCou
 }
 
 func (t *Tilemap) renderItem() []renderItem {
+	if t.staticVAO == 0 {
+		return nil
+	}
 	staticRI := renderItem{
 		vao:        t.staticVAO,
-		indices:    int32(t.width) * int32(t.height) * 6 * int32(len(t.staticLayers)),
+		indices:    t.staticInd,
 		image:      t.textures[0].image,
 		normals:    t.normals[0].image,
 		useNormals: t.normals != nil,
 		transform:  NewTransform(0, 0, 0),
+		layer:      t.layer,
 	}
 
 	if *t.changed {
 		v, _ := t.vertices(*t.animIndex, true)
-		gl.BindBuffer(gl.ARRAY_BUFFER, t.animatedVBO)
-		gl.BufferData(gl.ARRAY_BUFFER, len(t.staticLayers)*t.width*t.height*5*4*4, gl.Ptr(v), gl.STATIC_DRAW)
+		updateVBO(t.animatedVBO, v)
 		*t.changed = false
 	}
 	animRI := renderItem{
 		vao:        t.animatedVAO,
-		indices:    int32(t.width) * int32(t.height) * 6 * int32(len(t.staticLayers)),
+		indices:    t.animatedInd,
 		image:      t.textures[0].image,
 		normals:    t.normals[0].image,
 		useNormals: t.normals != nil,
 		transform:  NewTransform(0, 0, 0),
+		layer:      t.layer,
 	}
 
 	return []renderItem{staticRI, animRI}
@@ -228,40 +232,30 @@ func (t *Tilemap) vertices(tick int, animated bool) ([]float32, []uint32) {
 	return vertices, indices
 }
 
-func (t *Tilemap) init() (uint32, uint32, uint32) {
+func (t *Tilemap) init() {
 	v, i := t.vertices(0, false)
-	var staticVBO, staticVAO, staticEBO uint32
-	gl.GenVertexArrays(1, &staticVAO)
-	gl.GenBuffers(1, &staticVBO)
-	gl.GenBuffers(1, &staticEBO)
-	gl.BindVertexArray(staticVAO)
-	gl.BindBuffer(gl.ARRAY_BUFFER, staticVBO)
-	gl.BufferData(gl.ARRAY_BUFFER, len(t.staticLayers)*t.width*t.height*5*4*4, gl.Ptr(v), gl.STATIC_DRAW)
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, staticEBO)
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(t.staticLayers)*t.width*t.height*6*4, gl.Ptr(i), gl.STATIC_DRAW)
-	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 5*4, nil)
-	gl.EnableVertexAttribArray(0)
-	gl.VertexAttribPointerWithOffset(1, 2, gl.FLOAT, false, 5*4, 3*4)
-	gl.EnableVertexAttribArray(1)
+	t.staticVAO, t.staticVBO, t.staticInd = genVAO(v, i)
 
 	v2, i2 := t.vertices(0, true)
-	var animVBO, animVAO, animEBO uint32
-	gl.GenVertexArrays(1, &animVAO)
-	gl.GenBuffers(1, &animVBO)
-	gl.GenBuffers(1, &animEBO)
-	gl.BindVertexArray(animVAO)
-	gl.BindBuffer(gl.ARRAY_BUFFER, animVBO)
-	gl.BufferData(gl.ARRAY_BUFFER, len(t.animatedLayers)*t.width*t.height*5*4*4, gl.Ptr(v2), gl.STATIC_DRAW)
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, animEBO)
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(t.animatedLayers)*t.width*t.height*6*4, gl.Ptr(i2), gl.STATIC_DRAW)
-	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 5*4, nil)
-	gl.EnableVertexAttribArray(0)
-	gl.VertexAttribPointerWithOffset(1, 2, gl.FLOAT, false, 5*4, 3*4)
-	gl.EnableVertexAttribArray(1)
-
-	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
-	gl.BindVertexArray(0)
-	return staticVAO, animVAO, animVBO
+	t.animatedVAO, t.animatedVBO, t.animatedInd = genVAO(v2, i2)
+}
+
+// Stops the tile animation and frees the map's buffers. The map can't be drawn afterwards
+func (t *Tilemap) Delete() {
+	if t.animTimer != nil {
+		t.animTimer.stop()
+		t.animTimer = nil
+	}
+	if t.staticVAO != 0 {
+		deleteVAO(t.staticVAO, t.staticVBO)
+		deleteVAO(t.animatedVAO, t.animatedVBO)
+		t.staticVAO, t.staticVBO, t.animatedVAO, t.animatedVBO = 0, 0, 0, 0
+	}
+}
+
+// Sets the render layer the whole map is drawn on
+func (t *Tilemap) SetLayer(layer int) {
+	t.layer = layer
 }
 
 func (t Tilemap) PixelSize() (int, int) {
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTilemapDeleteStopsAnimation(t *testing.T) {
+	h, clock := headless, Time
+	defer func() { headless, Time = h, clock }()
+	headless = true // the map's buffers are made without a GL context
+	Time = NewClock(NewManualTime(time.Unix(0, 0)), 10)
+
+	m := LoadTilemap("../res/test.tmx", "../res/atlas.png", "", 1)
+	if m.animTimer == nil {
+		t.Fatal("the test map's animated tiles have no timer")
+	}
+	// the map's tiles change frame every 600ms
+	for i := 0; i < 6; i++ {
+		Time.tick()
+	}
+	if *m.animIndex != 1 {
+		t.Errorf("animation is on frame %d after 600ms, want 1", *m.animIndex)
+	}
+
+	m.Delete()
+	m.Delete() // deleting twice is harmless
+	for i := 0; i < 12; i++ {
+		Time.tick()
+	}
+	if *m.animIndex != 1 {
+		t.Errorf("deleted map kept animating, to frame %d", *m.animIndex)
+	}
+	if len(Time.timers) != 0 {
+		t.Errorf("%d timers left running", len(Time.timers))
+	}
+	if items := m.renderItem(); items != nil {
+		t.Errorf("deleted map still draws %d items", len(items))
+	}
+}
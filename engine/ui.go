@@ -1,6 +1,10 @@
 package engine
 
 import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-gl/mathgl/mgl32"
@@ -13,153 +17,404 @@ INPUT
 
 type ui struct {
 	input *input
-	skin  Texture
-	font  *Font
+	theme *Theme
 
 	hotItem    int // item hovered over by mouse
 	activeItem int // the id of the currently selected item. 0 means nothing selected
+	lastItem   int // the most recent widget, for tooltips
 
-	idCount int
+	idStack  []uint64
+	idsUsed  map[uint64]int // times each hash was used this pass, to tell apart widgets with the same key
+	pass     uint64         // uiPass of the last Begin
+	disabled int            // depth of BeginDisabled
+
+	focusItem  int  // widget with keyboard and gamepad focus
+	showFocus  bool // focus was moved by keyboard or gamepad, so is outlined
+	focusNext  bool // SetFocus was called
+	modalClose int  // id of the open modal's close button
+	focusables []focusEntry
+	nav        navInput
+	navCapture [2]bool // the focused widget uses the arrow keys along x or y itself
+
+	editors map[*string]*textEditor // text input state, by buffer
+	scroll  map[int]float32         // scroll panel offsets, by id
+
+	layer  int
+	items  [uiLayerCount][]renderItem // pushed to the renderer in layer order by End
+	panels []uiPanel
+
+	layouts     []uiLayout
+	layoutSizes map[int]layoutMeasure // sizes of containers last pass, by id
+
+	popups     []mgl32.Vec4 // areas covered by popups this pass
+	lastPopups []mgl32.Vec4 // and last pass, which block the widgets beneath them
+	modal      bool         // a modal dialog is open this pass
+	lastModal  bool
+
+	openDropdown int
+	hoverItem    int // item the tooltip timer is running for
+	hoverStart   time.Duration
 }
 
+// UI is drawn in layers, so popups can be declared in between the widgets they cover
+const (
+	uiLayerBase = iota
+	uiLayerModal
+	uiLayerPopup
+	uiLayerTooltip
+	uiLayerCount
+)
+
+// Widgets inside a panel are positioned relative to offset and clipped to clip
+type uiPanel struct {
+	offset mgl32.Vec2
+	clip   mgl32.Vec4 // zero width doesn't clip
+	size   mgl32.Vec2 // of the contents, for anchoring areas. Zero uses the screen
+
+	id            int // scroll panels only
+	x, y, w, h    float32
+	contentHeight float32
+}
+
+// Starts a new UI for this update or draw. Scenes drawn in the same frame, like a game and
+// a pause menu over it, add to the same UI instead of clearing each other's
 func (ui *ui) Begin() {
+	if ui.pass == uiPass {
+		return
+	}
+	ui.pass = uiPass
 	Renderer.beginUI()
+	ui.navigate()
+	ui.readNav()
+	ui.focusables = ui.focusables[:0]
+
 	ui.hotItem = 0
-	ui.idCount = 1
+	ui.lastItem = 0
+	ui.idStack = ui.idStack[:0]
+	ui.idsUsed = make(map[uint64]int)
+	ui.lastPopups, ui.popups = ui.popups, nil
+	ui.lastModal, ui.modal = ui.modal, false
 }
 
 func (ui *ui) End() {
-	if Input.KeyOnce(MouseLeft) {
+	// clicking outside the active item unfocuses it
+	if Input.MouseOnce(MouseLeft) && ui.hotItem != ui.activeItem {
 		ui.activeItem = 0
 	}
-}
+	if ui.hotItem != ui.hoverItem {
+		ui.hoverItem = 0
+	}
 
-func (ui *ui) Button(x, y, w, h float32, label string, colour mgl32.Vec4) bool {
-	printData := ui.font.renderItem(x, y, 32, label)
-	printData.ri.transform.Pos = printData.ri.transform.Pos.Add(mgl32.Vec3{(w / 2) - (printData.size[0] / 2), (h / 2) - (printData.size[1] / 2)})
-	printData.ri.colour = mgl32.Vec4{0, 0, 0, 1}
-
-	id := ui.idCount
-	ui.idCount++
-
-	if ui.regionhit(x, y, w, h) {
-		if (colour[0]+colour[1]+colour[2])/3 < 0.5 {
-			colour = colour.Add(mgl32.Vec4{0.3, 0.3, 0.3, 0})
-			y -= 2
-			printData.ri.transform.Pos[1] -= 2
-		} else {
-			colour = colour.Sub(mgl32.Vec4{0.3, 0.3, 0.3, 0})
-			y -= 2
-			printData.ri.transform.Pos[1] -= 2
+	for layer := range ui.items {
+		for _, ri := range ui.items[layer] {
+			Renderer.PushUI(ri)
 		}
+		ui.items[layer] = ui.items[layer][:0]
+	}
+	ui.layer = uiLayerBase
+	ui.panels = ui.panels[:0]
+	ui.layouts = ui.layouts[:0]
+	ui.disabled = 0
+}
 
+// Returns the id for a widget, hashed from key and the id stack so it stays the same when widgets
+// declared before it come and go. Widgets with the same key in the same scope are told apart by order
+func (ui *ui) getID(key string) int {
+	hash := hashID(ui.idSeed(), key)
+	n := ui.idsUsed[hash]
+	ui.idsUsed[hash] = n + 1
+	if n > 0 {
+		hash = hashID(hash, strconv.Itoa(n))
+	}
+	id := int(hash)
+	if id == 0 {
+		id = 1 // 0 means no widget
+	}
+	ui.lastItem = id
+	return id
+}
+
+// Key for widgets that edit a value, which stays the same even if their label changes
+func pointerKey(p any) string {
+	return fmt.Sprintf("%p", p)
+}
+
+// Scopes the ids of widgets up to PopID, so widgets with the same label, like the "Buy" button
+// in each row of a shop, don't clash. Scroll panels and modals push their own scope
+func (ui *ui) PushID(key string) {
+	ui.idStack = append(ui.idStack, hashID(ui.idSeed(), key))
+}
+
+func (ui *ui) PopID() {
+	if len(ui.idStack) > 0 {
+		ui.idStack = ui.idStack[:len(ui.idStack)-1]
+	}
+}
+
+func (ui *ui) pushIDScope(id int) {
+	ui.idStack = append(ui.idStack, uint64(id))
+}
+
+func (ui *ui) idSeed() uint64 {
+	if len(ui.idStack) == 0 {
+		return 0
+	}
+	return ui.idStack[len(ui.idStack)-1]
+}
+
+// FNV-1a, starting from seed
+func hashID(seed uint64, key string) uint64 {
+	hash := seed ^ 14695981039346656037
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= 1099511628211
+	}
+	return hash
+}
+
+// Splits "label##key" into the text shown and the key hashed for its id,
+// so widgets can share a label, or change it without losing their state
+func splitLabel(label string) (string, string) {
+	if i := strings.Index(label, "##"); i >= 0 {
+		return label[:i], label
+	}
+	return label, label
+}
+
+// Shared hover and click handling. A widget becomes active when pressed,
+// and is clicked when released while still under the mouse
+func (ui *ui) behaviour(id int, x, y, w, h float32) (hovered, held, clicked bool) {
+	hovered = ui.regionhit(x, y, w, h)
+	if hovered {
 		ui.hotItem = id
-		if ui.activeItem != id && ui.input.KeyDown(MouseLeft) {
+		if ui.input.MouseOnce(MouseLeft) {
 			ui.activeItem = id
+			ui.focusItem, ui.showFocus = id, false
 		}
 	}
+	held = ui.activeItem == id && ui.input.MouseDown(MouseLeft)
+	clicked = ui.activeItem == id && hovered && ui.input.MouseUp(MouseLeft)
+	return hovered, held, clicked
+}
 
-	vao, _, ind := newQuadVAO(w, h, mgl32.Vec4{0, 1, 0, 1})
-	ri := renderItem{
-		vao:        vao,
-		indices:    ind,
-		image:      ui.skin.image,
-		shader:     uiShader.Shader,
-		useNormals: false,
-		transform:  NewTransform(x+(w/2), y+(h/2), 8),
-		colour:     colour,
+// Adds a render item to the current layer, clipped to the current panel
+func (ui *ui) push(ri renderItem) {
+	if len(ui.panels) > 0 {
+		ri.clip = ui.panels[len(ui.panels)-1].clip
 	}
-	Renderer.PushUI(ri)
-	Renderer.PushUI(printData.ri)
+	ui.items[ui.layer] = append(ui.items[ui.layer], ri)
+}
 
-	if ui.input.KeyDown(MouseLeft) && ui.hotItem == id && ui.activeItem == id {
-		return true
+// Converts a position relative to the current panel to screen pixels
+func (ui *ui) translate(x, y float32) (float32, float32) {
+	if len(ui.panels) == 0 {
+		return x, y
 	}
-	return false
+	offset := ui.panels[len(ui.panels)-1].offset
+	return x + offset[0], y + offset[1]
 }
 
-func (ui *ui) Label(label string, x, y float32, fontSize int, colour mgl32.Vec4) mgl32.Vec2 {
-	printData := ui.font.renderItem(x, y, fontSize, label)
-	printData.ri.colour = colour
-	Renderer.PushUI(printData.ri)
-	return printData.size
+// Starts a panel at offset, clipped to clip and any panel it's inside
+func (ui *ui) pushPanel(p uiPanel) {
+	if len(ui.panels) > 0 {
+		p.clip = intersectRect(ui.panels[len(ui.panels)-1].clip, p.clip)
+	}
+	ui.panels = append(ui.panels, p)
 }
 
-var backspaceRepeat = time.Millisecond * 100
-var backspaceTimer = time.Now()
+func (ui *ui) popPanel() uiPanel {
+	p := ui.panels[len(ui.panels)-1]
+	ui.panels = ui.panels[:len(ui.panels)-1]
+	return p
+}
 
-func (ui *ui) TextInput(hint string, x, y float32, widthInChars, fontSize int, buf *string) {
-	id := ui.idCount
-	ui.idCount++
+// Intersects two {x, y, w, h} rectangles, where zero width means unbounded
+func intersectRect(a, b mgl32.Vec4) mgl32.Vec4 {
+	if a[2] <= 0 {
+		return b
+	}
+	if b[2] <= 0 {
+		return a
+	}
+	x0, y0 := float32(math.Max(float64(a[0]), float64(b[0]))), float32(math.Max(float64(a[1]), float64(b[1])))
+	x1 := float32(math.Min(float64(a[0]+a[2]), float64(b[0]+b[2])))
+	y1 := float32(math.Min(float64(a[1]+a[3]), float64(b[1]+b[3])))
+	// keep a tiny width so an empty intersection still clips everything
+	return mgl32.Vec4{x0, y0, float32(math.Max(float64(x1-x0), 0.001)), float32(math.Max(float64(y1-y0), 0))}
+}
 
-	var w, h float32
-	var stringRender stringRenderItemSize
-	var strlen int
+// Button sized w by h, where 0 fits the label. colour tints the theme's button colours.
+// Its id comes from the label, or the key after ## in "label##key". Returns true when clicked
+func (ui *ui) Button(x, y, w, h float32, label string, colour mgl32.Vec4) bool {
+	label, key := splitLabel(label)
+	id := ui.getID(key)
+	t := ui.theme
+	size := t.ButtonFontSize
+	x, y, w, h = ui.place(x, y, w, h, t.Font.Measure(size, label).Add(mgl32.Vec2{4 * t.Padding, 2 * t.Padding}))
 
-	if len(*buf) > 0 {
-		// Show buf
-		stringRender = ui.font.renderItem(x, y, fontSize, *buf)
-		stringRender.ri.colour = mgl32.Vec4{0, 0, 0, 1}
-		strlen = len(*buf)
-	} else {
-		// Show hint
-		stringRender = ui.font.renderItem(x, y, fontSize, hint)
-		stringRender.ri.colour = mgl32.Vec4{0.5, 0.5, 0.5, 1}
-		strlen = len(hint)
+	hovered, held, clicked := ui.behaviour(id, x, y, w, h)
+	ui.focusable(id, x, y, w, h)
+	clicked = clicked || ui.activated(id)
+	if hovered && ui.disabled == 0 {
+		y -= 2
+	}
+
+	state := ui.styleColour(t.Button, hovered, held)
+	ui.box(x, y, w, h, tint(colour, state), t.Button)
+	textSize := t.Font.Measure(size, label)
+	ui.text(x+(w/2)-(textSize[0]/2), y+(h/2)-(textSize[1]/2), size, label, t.Button.Text)
+	return clicked
+}
+
+func (ui *ui) Label(label string, x, y float32, fontSize int, colour mgl32.Vec4) mgl32.Vec2 {
+	x, y, _, _ = ui.place(x, y, 0, 0, ui.theme.Font.Measure(fontSize, label))
+	return ui.text(x, y, fontSize, label, colour)
+}
+
+// Draws text laid out with the options, so it can wrap, be aligned and use markup. Returns the layout,
+// with glyph positions in screen pixels for hit testing
+func (ui *ui) Text(text string, x, y float32, fontSize int, colour mgl32.Vec4, opts TextOptions) TextLayout {
+	font := ui.theme.Font
+	layout := font.Layout(fontSize, text, opts)
+	x, y, _, _ = ui.place(x, y, 0, 0, layout.Size)
+	printData := font.renderText(x, y, fontSize, text, opts).styled(opts.Style)
+	for _, ri := range printData.coloured(colour) {
+		ui.push(ri)
 	}
+	return layout.offset(x, y)
+}
+
+// Single line text input. Click to focus, then type, move the caret with the arrow keys, Home and End,
+// select with Shift or by dragging, and use Ctrl (Cmd on macOS) for word jumps, select all, copy, cut and paste.
+// The editing state is kept per buffer, so each input needs its own. Returns true on the update Enter is pressed
+func (ui *ui) TextInput(hint string, x, y float32, widthInChars, fontSize int, buf *string, options ...TextInputOptions) bool {
+	id := ui.getID(pointerKey(buf))
 
-	w = (stringRender.size[0] / float32(strlen)) * float32(widthInChars)
-	h = stringRender.size[1]
+	var opts TextInputOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if ui.editors == nil {
+		ui.editors = make(map[*string]*textEditor)
+	}
+	e, ok := ui.editors[buf]
+	if !ok {
+		e = &textEditor{}
+		ui.editors[buf] = e
+	}
+	e.sync(*buf)
 
-	colour := mgl32.Vec4{1, 1, 1, 1}
-	if ui.regionhit(x, y, w, h) {
-		colour = mgl32.Vec4{0.9, 0.9, 0.9, 1}
+	// a width of 0 fills the layout, or fits 16 characters
+	advance := float32(ui.theme.Font.glyph(fontSize, '0').advance)
+	h := ui.theme.Font.lineHeight(fontSize)
+	x, y, w, h := ui.place(x, y, advance*float32(widthInChars), h, mgl32.Vec2{advance * 16, h})
+
+	mouse := ui.input.MousePosition()
+	shift := ui.input.KeyDown(KeyLeftShift) || ui.input.KeyDown(KeyRightShift)
+	if ui.disabled > 0 && ui.activeItem == id {
+		ui.activeItem = 0
+	}
+	hovered := ui.regionhit(x, y, w, h)
+	if hovered {
 		ui.hotItem = id
-		if ui.activeItem != id && ui.input.KeyDown(MouseLeft) {
+		if ui.input.MouseOnce(MouseLeft) {
+			ui.activeItem = id
+			ui.focusItem, ui.showFocus = id, false
+			e.moveTo(e.caretAt(ui.theme.Font.caretPositions(fontSize, e.display(opts)), mouse.X()-x), shift)
+			e.dragging = true
+		}
+	} else if ui.activeItem == id && ui.input.MouseOnce(MouseLeft) {
+		ui.activeItem = 0 // clicked elsewhere, so typing goes to whatever was clicked
+	}
+	if !ui.input.MouseDown(MouseLeft) {
+		e.dragging = false
+	}
+
+	// Enter or A starts editing a focused input, Escape or B stops
+	started := false
+	if ui.focusable(id, x, y, w, h) {
+		if ui.activeItem != id && ui.activated(id) {
 			ui.activeItem = id
+			e.moveTo(len(e.text), false)
+			started = true
+		} else if ui.activeItem == id && ui.nav.cancel {
+			ui.activeItem = 0
 		}
 	}
 
-	if ui.activeItem == id {
-		colour = mgl32.Vec4{0.9, 0.9, 0.9, 1}
-		for _, c := range Input.textInput {
-			if ui.font.isPrintable(c) && len(*buf) <= widthInChars {
-				*buf += string(c)
-			}
+	submitted := false
+	if ui.activeItem == id && !started {
+		ui.navAxis(false) // left and right move the caret
+		caret := e.caret
+		var changed bool
+		changed, submitted = e.handleKeys(ui.input, opts)
+		if changed {
+			*buf = e.String()
+			e.lastInput = *buf
 		}
-		if Input.KeyDown(KeyBackspace) && time.Now().Sub(backspaceTimer) > backspaceRepeat {
-			backspaceTimer = time.Now()
-			if len(*buf) > 0 {
-				s := *buf
-				s = s[:len(s)-1]
-				*buf = s
-			}
+		if e.dragging {
+			e.moveTo(e.caretAt(ui.theme.Font.caretPositions(fontSize, e.display(opts)), mouse.X()-x), true)
+		}
+		if e.caret != caret {
+			e.blink = Time.Uptime()
 		}
 	}
 
-	vao, _, ind := newQuadVAO(w, h, mgl32.Vec4{0, 1, 0, 1})
-	ri := renderItem{
-		vao:        vao,
-		indices:    ind,
-		image:      ui.skin.image,
-		shader:     uiShader.Shader,
-		useNormals: false,
-		transform:  NewTransform(x+(w/2), y+(h/2), 8),
-		colour:     colour,
+	text := e.display(opts)
+	positions := ui.theme.Font.caretPositions(fontSize, text)
+	e.scrollToCaret(positions, w)
+	end := e.scroll
+	for end < len(text) && positions[end+1]-positions[e.scroll] <= w {
+		end++
 	}
 
-	Renderer.PushUI(ri)
-	Renderer.PushUI(stringRender.ri)
-}
+	style := ui.theme.TextInput
+	ui.box(x, y, w, h, ui.styleColour(style, hovered, ui.activeItem == id), style)
 
-func (ui *ui) Checkbox(label string, val *bool) {
+	if ui.activeItem == id && e.hasSelection() {
+		start, stop := e.selection()
+		start, stop = clampInt(start, e.scroll, end), clampInt(stop, e.scroll, end)
+		left, right := positions[start]-positions[e.scroll], positions[stop]-positions[e.scroll]
+		ui.fill(x+left, y, right-left, h, ui.theme.Selection)
+	}
+
+	if len(text) > 0 {
+		ui.text(x, y, fontSize, string(text[e.scroll:end]), style.Text)
+	} else {
+		ui.text(x, y, fontSize, hint, ui.theme.Hint)
+	}
 
+	// blink every half second
+	if ui.activeItem == id && (Time.Uptime()-e.blink)/(time.Millisecond*500)%2 == 0 {
+		ui.fill(x+positions[e.caret]-positions[e.scroll], y, 1, h, style.Text)
+	}
+	return submitted
 }
 
+// True if the widget isn't disabled and the mouse is over the rectangle, inside the current panel's clip and not covered by a popup or modal
 func (ui *ui) regionhit(x, y, w, h float32) bool {
 	mouse := ui.input.MousePosition()
-	if mouse.X() < x || mouse.Y() < y || mouse.X() >= x+w || mouse.Y() >= y+h {
+	if ui.disabled > 0 || !inRect(mouse, mgl32.Vec4{x, y, w, h}) {
+		return false
+	}
+	if len(ui.panels) > 0 {
+		if clip := ui.panels[len(ui.panels)-1].clip; clip[2] > 0 && !inRect(mouse, clip) {
+			return false
+		}
+	}
+	if ui.lastModal && ui.layer == uiLayerBase {
 		return false
 	}
+	if ui.layer != uiLayerPopup {
+		for _, popup := range ui.lastPopups {
+			if inRect(mouse, popup) {
+				return false
+			}
+		}
+	}
 	return true
 }
+
+func inRect(p mgl32.Vec2, r mgl32.Vec4) bool {
+	return p[0] >= r[0] && p[1] >= r[1] && p[0] < r[0]+r[2] && p[1] < r[1]+r[3]
+}
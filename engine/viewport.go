@@ -0,0 +1,110 @@
+package engine
+
+import "math"
+
+// How the game's logical resolution is fitted to the window
+type ScaleMode int
+
+const (
+	ScaleLetterbox ScaleMode = iota // uniform scale to fit, with black bars to keep the aspect ratio
+	ScaleInteger                    // largest whole number scale that fits, for pixel perfect art
+	ScaleStretch                    // fill the window, ignoring the aspect ratio
+	ScaleExpand                     // uniform scale, showing more of the world on the longer axis instead of bars
+)
+
+// The scene is rendered at the logical resolution (ScreenW, ScreenH), then drawn into the
+// destination rectangle of the window's framebuffer. All sizes here are in framebuffer pixels,
+// so high DPI displays are handled the same as any other window size.
+type viewport struct {
+	mode           ScaleMode
+	logicalW       float32 // resolution the game asked for
+	logicalH       float32
+	displayW       float32 // framebuffer size the destination was calculated for
+	displayH       float32
+	x, y, w, h     float32 // destination rectangle, y from the top
+	scaleX, scaleY float32 // framebuffer pixels per logical pixel
+}
+
+var screen viewport
+
+// Sets how the logical resolution is fitted to the window. Defaults to ScaleLetterbox
+func SetScaleMode(mode ScaleMode) {
+	screen.mode = mode
+	screen.displayW, screen.displayH = 0, 0 // force an update
+	screen.update(dispW, dispH)
+}
+
+func GetScaleMode() ScaleMode {
+	return screen.mode
+}
+
+// Changes the logical resolution the game is drawn at. With ScaleExpand this is the minimum size
+func SetResolution(width, height float32) {
+	screen.logicalW, screen.logicalH = width, height
+	screen.displayW, screen.displayH = 0, 0
+	screen.update(dispW, dispH)
+}
+
+// Returns the area of the window's framebuffer the game is drawn in, as x, y from the top left, width and height
+func ScreenRect() (float32, float32, float32, float32) {
+	return screen.x, screen.y, screen.w, screen.h
+}
+
+// Maps a point in framebuffer pixels to logical screen coordinates
+func DisplayToScreen(x, y float32) (float32, float32) {
+	if screen.scaleX == 0 || screen.scaleY == 0 {
+		return x, y
+	}
+	return (x - screen.x) / screen.scaleX, (y - screen.y) / screen.scaleY
+}
+
+func initViewport(width, height float32, mode ScaleMode) {
+	screen = viewport{
+		mode:     mode,
+		logicalW: width,
+		logicalH: height,
+	}
+	screen.update(dispW, dispH)
+}
+
+// Recalculates the destination rectangle for a framebuffer size, resizing the renderer
+// if the logical resolution changed
+func (v *viewport) update(displayW, displayH float32) {
+	if displayW <= 0 || displayH <= 0 {
+		return // minimised
+	}
+	if displayW == v.displayW && displayH == v.displayH {
+		return
+	}
+	v.displayW, v.displayH = displayW, displayH
+
+	width, height := v.logicalW, v.logicalH
+	sx, sy := displayW/v.logicalW, displayH/v.logicalH
+	scale := float32(math.Min(float64(sx), float64(sy)))
+
+	switch v.mode {
+	case ScaleStretch:
+		// scale each axis separately
+	case ScaleInteger:
+		scale = float32(math.Max(1, math.Floor(float64(scale))))
+		sx, sy = scale, scale
+	case ScaleExpand:
+		sx, sy = scale, scale
+		width, height = float32(math.Floor(float64(displayW/scale))), float32(math.Floor(float64(displayH/scale)))
+	default:
+		sx, sy = scale, scale
+	}
+
+	v.scaleX, v.scaleY = sx, sy
+	v.w = float32(math.Round(float64(width * sx)))
+	v.h = float32(math.Round(float64(height * sy)))
+	v.x = float32(math.Floor(float64(displayW-v.w) / 2))
+	v.y = float32(math.Floor(float64(displayH-v.h) / 2))
+
+	if width != ScreenW || height != ScreenH {
+		ScreenW, ScreenH = width, height
+		if Renderer != nil {
+			Renderer.resize(width, height)
+		}
+	}
+}
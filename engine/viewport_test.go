@@ -0,0 +1,88 @@
+package engine
+
+import "testing"
+
+func TestViewportUpdate(t *testing.T) {
+	tests := []struct {
+		name               string
+		mode               ScaleMode
+		displayW, displayH float32
+		rect               [4]float32 // x, y, w, h in framebuffer pixels
+		scaleX, scaleY     float32
+		screenW, screenH   float32 // logical size the scene is rendered at
+	}{
+		{"letterbox exact fit", ScaleLetterbox, 1280, 720, [4]float32{0, 0, 1280, 720}, 4, 4, 320, 180},
+		{"letterbox bars top and bottom", ScaleLetterbox, 1280, 800, [4]float32{0, 40, 1280, 720}, 4, 4, 320, 180},
+		{"letterbox fractional scale", ScaleLetterbox, 1000, 720, [4]float32{0, 78, 1000, 563}, 3.125, 3.125, 320, 180},
+		{"integer rounds the scale down", ScaleInteger, 1000, 720, [4]float32{20, 90, 960, 540}, 3, 3, 320, 180},
+		{"integer never goes below 1", ScaleInteger, 200, 100, [4]float32{-60, -40, 320, 180}, 1, 1, 320, 180},
+		{"stretch scales each axis", ScaleStretch, 640, 720, [4]float32{0, 0, 640, 720}, 2, 4, 320, 180},
+		{"expand shows more of the world", ScaleExpand, 1280, 800, [4]float32{0, 0, 1280, 800}, 4, 4, 320, 200},
+		{"expand with a fractional scale", ScaleExpand, 1000, 720, [4]float32{0, 0, 1000, 719}, 3.125, 3.125, 320, 230},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restore := saveScreen()
+			defer restore()
+
+			v := viewport{mode: tt.mode, logicalW: 320, logicalH: 180}
+			v.update(tt.displayW, tt.displayH)
+			if got := [4]float32{v.x, v.y, v.w, v.h}; got != tt.rect {
+				t.Errorf("destination is %v, want %v", got, tt.rect)
+			}
+			if v.scaleX != tt.scaleX || v.scaleY != tt.scaleY {
+				t.Errorf("scale is %v x %v, want %v x %v", v.scaleX, v.scaleY, tt.scaleX, tt.scaleY)
+			}
+			if ScreenW != tt.screenW || ScreenH != tt.screenH {
+				t.Errorf("screen is %v x %v, want %v x %v", ScreenW, ScreenH, tt.screenW, tt.screenH)
+			}
+		})
+	}
+}
+
+func TestViewportMinimised(t *testing.T) {
+	restore := saveScreen()
+	defer restore()
+
+	v := viewport{mode: ScaleLetterbox, logicalW: 320, logicalH: 180}
+	v.update(1280, 720)
+	before := v
+	v.update(0, 0)
+	if v != before {
+		t.Errorf("minimising changed the viewport from %+v to %+v", before, v)
+	}
+}
+
+func TestDisplayToScreen(t *testing.T) {
+	restore := saveScreen()
+	defer restore()
+
+	tests := []struct {
+		mode               ScaleMode
+		displayW, displayH float32
+		x, y               float32 // framebuffer pixels
+		wantX, wantY       float32
+	}{
+		{ScaleLetterbox, 1280, 800, 640, 400, 160, 90},
+		{ScaleLetterbox, 1280, 800, 0, 40, 0, 0},
+		{ScaleLetterbox, 1280, 800, 0, 0, 0, -10}, // in the bars
+		{ScaleStretch, 640, 720, 320, 360, 160, 90},
+		{ScaleInteger, 1000, 720, 20, 90, 0, 0},
+	}
+	for _, tt := range tests {
+		screen = viewport{mode: tt.mode, logicalW: 320, logicalH: 180}
+		screen.update(tt.displayW, tt.displayH)
+		if x, y := DisplayToScreen(tt.x, tt.y); x != tt.wantX || y != tt.wantY {
+			t.Errorf("mode %v at %vx%v mapped (%v, %v) to (%v, %v), want (%v, %v)", tt.mode, tt.displayW, tt.displayH, tt.x, tt.y, x, y, tt.wantX, tt.wantY)
+		}
+	}
+}
+
+// Keeps viewport tests from resizing the renderer or leaking the screen size into other tests
+func saveScreen() func() {
+	s, w, h, r := screen, ScreenW, ScreenH, Renderer
+	Renderer = nil
+	return func() {
+		screen, ScreenW, ScreenH, Renderer = s, w, h, r
+	}
+}
@@ -0,0 +1,453 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const tooltipDelay = time.Millisecond * 500
+const scrollSpeed = 30 // pixels per wheel step
+const scrollbarWidth = 8
+
+// Box that toggles val when clicked, with a label to its right. Returns true when toggled
+func (ui *ui) Checkbox(x, y float32, label string, val *bool) bool {
+	id := ui.getID(pointerKey(val))
+	t := ui.theme
+	size := ui.lineHeight()
+	x, y, w, _ := ui.place(x, y, 0, size, mgl32.Vec2{size + t.Padding + ui.textWidth(t.FontSize, label), size})
+
+	hovered, held, clicked := ui.behaviour(id, x, y, w, size)
+	ui.focusable(id, x, y, w, size)
+	clicked = clicked || ui.activated(id)
+	if clicked {
+		*val = !*val
+	}
+
+	ui.box(x, y, size, size, ui.styleColour(t.Checkbox, hovered, held), t.Checkbox)
+	if *val {
+		ui.fill(x+size/4, y+size/4, size/2, size/2, t.Accent)
+	}
+	ui.text(x+size+t.Padding, y, t.FontSize, label, t.Label)
+	return clicked
+}
+
+// Vertical list of options where only one can be chosen. Returns true when the selection changes
+func (ui *ui) RadioGroup(x, y float32, options []string, selected *int) bool {
+	t := ui.theme
+	size := ui.lineHeight()
+	var widest float32
+	for _, option := range options {
+		widest = max32(widest, ui.textWidth(t.FontSize, option))
+	}
+	x, y, w, _ := ui.place(x, y, 0, 0, mgl32.Vec2{size + t.Padding + widest, float32(len(options))*(size+t.Padding) - t.Padding})
+
+	changed := false
+	ui.PushID(pointerKey(selected))
+	for i, option := range options {
+		id := ui.getID(strconv.Itoa(i))
+		rowY := y + float32(i)*(size+t.Padding)
+		hovered, held, clicked := ui.behaviour(id, x, rowY, w, size)
+		ui.focusable(id, x, rowY, w, size)
+		if (clicked || ui.activated(id)) && *selected != i {
+			*selected = i
+			changed = true
+		}
+
+		ui.box(x, rowY, size, size, ui.styleColour(t.Checkbox, hovered, held), t.Checkbox)
+		if *selected == i {
+			ui.fill(x+size/3, rowY+size/3, size/3, size/3, t.Accent)
+		}
+		ui.text(x+size+t.Padding, rowY, t.FontSize, option, t.Label)
+	}
+	ui.PopID()
+	return changed
+}
+
+// Horizontal slider between min and max. A size of 0 fills the layout, or is 150 by 16.
+// When focused, left and right move it a twentieth of the way. Returns true when the value changes
+func (ui *ui) Slider(x, y, w, h float32, value *float32, min, max float32) bool {
+	t, changed := ui.slider(ui.getID(pointerKey(value)), x, y, w, h, (*value-min)/(max-min), 0.05)
+	if changed {
+		*value = min + t*(max-min)
+	}
+	return changed
+}
+
+// Slider that snaps to whole numbers
+func (ui *ui) SliderInt(x, y, w, h float32, value *int, min, max int) bool {
+	t, _ := ui.slider(ui.getID(pointerKey(value)), x, y, w, h, float32(*value-min)/float32(max-min), 1/float32(max-min))
+	v := min + int(math.Round(float64(t)*float64(max-min)))
+	if v == *value {
+		return false
+	}
+	*value = v
+	return true
+}
+
+// Shared by the sliders, t is the position along the track from 0 to 1 and step is how far the arrow keys move it
+func (ui *ui) slider(id int, x, y, w, h, t, step float32) (float32, bool) {
+	x, y, w, h = ui.place(x, y, w, h, mgl32.Vec2{150, 16})
+	if math.IsNaN(float64(t)) || math.IsInf(float64(step), 0) {
+		t, step = 0, 0 // min == max
+	}
+	t = mgl32.Clamp(t, 0, 1)
+
+	hovered, held, _ := ui.behaviour(id, x, y, w, h)
+	changed := false
+	if held && w > 0 {
+		dragged := mgl32.Clamp((ui.input.MousePosition().X()-x)/w, 0, 1)
+		changed = dragged != t
+		t = dragged
+	}
+	if ui.focusable(id, x, y, w, h) {
+		if dir := ui.navAxis(false); dir != 0 {
+			stepped := mgl32.Clamp(t+float32(dir)*step, 0, 1)
+			changed = stepped != t
+			t = stepped
+		}
+	}
+
+	theme := ui.theme
+	ui.fill(x, y+h/2-2, w, 4, theme.Track)
+	ui.fill(x, y+h/2-2, w*t, 4, theme.Accent)
+	ui.box(x+w*t-h/4, y, h/2, h, ui.styleColour(theme.Slider, hovered, held), theme.Slider)
+	return t, changed
+}
+
+// Shows the selected option, and a list of all of them when clicked. Returns true when the selection changes
+func (ui *ui) Dropdown(x, y, w, h float32, options []string, selected *int) bool {
+	id := ui.getID(pointerKey(selected))
+	t := ui.theme
+	var widest float32
+	for _, option := range options {
+		widest = max32(widest, ui.textWidth(t.FontSize, option))
+	}
+	x, y, w, h = ui.place(x, y, w, h, mgl32.Vec2{widest + ui.textWidth(t.FontSize, "v") + 4*t.Padding, ui.lineHeight() + 2*t.Padding})
+
+	hovered, _, clicked := ui.behaviour(id, x, y, w, h)
+	focused := ui.focusable(id, x, y, w, h)
+	if clicked || ui.activated(id) {
+		if ui.openDropdown == id {
+			ui.openDropdown = 0
+		} else {
+			ui.openDropdown = id
+		}
+	}
+	changed := false
+	if focused && ui.openDropdown == id {
+		// up and down pick from the list, Enter or A closes it
+		if dir := ui.navAxis(true); dir != 0 && len(options) > 0 {
+			i := clampInt(*selected+dir, 0, len(options)-1)
+			changed = i != *selected
+			*selected = i
+		}
+		if ui.nav.cancel {
+			ui.openDropdown = 0
+		}
+	}
+
+	ui.box(x, y, w, h, ui.styleColour(t.Dropdown, hovered, ui.openDropdown == id), t.Dropdown)
+	textY := y + (h-ui.lineHeight())/2
+	if *selected >= 0 && *selected < len(options) {
+		ui.text(x+t.Padding, textY, t.FontSize, options[*selected], t.Dropdown.Text)
+	}
+	ui.text(x+w-ui.textWidth(t.FontSize, "v")-t.Padding, textY, t.FontSize, "v", t.Dropdown.Text)
+
+	if ui.openDropdown != id {
+		return changed
+	}
+
+	// the list is a popup, drawn over everything declared after it and not clipped to any panel
+	list := mgl32.Vec4{x, y + h, w, h * float32(len(options))}
+	ui.popups = append(ui.popups, list)
+	layer := ui.layer
+	ui.layer = uiLayerPopup
+	ui.panels = append(ui.panels, uiPanel{})
+	for i, option := range options {
+		rowY := list[1] + float32(i)*h
+		hit := ui.regionhit(x, rowY, w, h)
+		if hit && ui.input.MouseOnce(MouseLeft) {
+			changed = *selected != i
+			*selected = i
+			ui.openDropdown = 0
+		}
+
+		colour := ui.styleColour(t.Dropdown, hit, false)
+		if *selected == i {
+			colour = t.Accent
+		}
+		ui.fill(x, rowY, w, h, colour)
+		ui.text(x+t.Padding, rowY+(h-ui.lineHeight())/2, t.FontSize, option, t.Dropdown.Text)
+	}
+	ui.popPanel()
+	ui.layer = layer
+
+	mouse := ui.input.MousePosition()
+	if ui.input.MouseOnce(MouseLeft) && !inRect(mouse, list) && !inRect(mouse, mgl32.Vec4{x, y, w, h}) {
+		ui.openDropdown = 0
+	}
+	return changed
+}
+
+// Bar filled to progress, from 0 to 1
+func (ui *ui) ProgressBar(x, y, w, h, progress float32, colour mgl32.Vec4) {
+	x, y, w, h = ui.place(x, y, w, h, mgl32.Vec2{150, 10})
+	ui.fill(x, y, w, h, ui.theme.Track)
+	ui.fill(x, y, w*mgl32.Clamp(progress, 0, 1), h, colour)
+}
+
+// Button showing a texture instead of a label. A size of 0 uses the texture's size. Returns true when clicked.
+// Its id comes from the part of the texture shown, use PushID to tell apart buttons showing the same one
+func (ui *ui) ImageButton(x, y, w, h float32, tex Texture, colour mgl32.Vec4) bool {
+	id := ui.getID(fmt.Sprintf("##image%v", tex.texCoords))
+	natural := mgl32.Vec2{
+		float32(math.Abs(float64(tex.texCoords[1]-tex.texCoords[0]))) * tex.image.width,
+		float32(math.Abs(float64(tex.texCoords[3]-tex.texCoords[2]))) * tex.image.height,
+	}
+	x, y, w, h = ui.place(x, y, w, h, natural)
+
+	hovered, held, clicked := ui.behaviour(id, x, y, w, h)
+	ui.focusable(id, x, y, w, h)
+	clicked = clicked || ui.activated(id)
+	colour = tint(colour, ui.styleColour(ui.theme.Button, hovered, held))
+	if held {
+		y += 2
+	}
+
+	vertices, indices := quad(w, h, tex.texCoords)
+	ui.mesh(x, y, w, h, vertices, indices, tex.image, colour)
+	return clicked
+}
+
+// Shows text by the mouse once it has rested on the previous widget for a moment
+func (ui *ui) Tooltip(text string) {
+	if ui.lastItem == 0 || ui.hotItem != ui.lastItem {
+		return
+	}
+	if ui.hoverItem != ui.lastItem {
+		ui.hoverItem = ui.lastItem
+		ui.hoverStart = Time.Uptime()
+		return
+	}
+	if Time.Uptime()-ui.hoverStart < tooltipDelay {
+		return
+	}
+
+	t := ui.theme
+	w, h := ui.textWidth(t.FontSize, text)+2*t.Padding, ui.lineHeight()+t.Padding
+	mouse := ui.input.MousePosition()
+	// keep it on screen
+	x := float32(math.Min(float64(mouse.X()+12), float64(ScreenW-w)))
+	y := float32(math.Min(float64(mouse.Y()+16), float64(ScreenH-h)))
+
+	layer := ui.layer
+	ui.layer = uiLayerTooltip
+	ui.panels = append(ui.panels, uiPanel{})
+	ui.box(x, y, w, h, t.Tooltip.Normal, t.Tooltip)
+	ui.text(x+t.Padding, y+t.Padding/2, t.FontSize, text, t.Tooltip.Text)
+	ui.popPanel()
+	ui.layer = layer
+}
+
+// Starts a panel that clips its contents and scrolls them with the mouse wheel or scrollbar.
+// Widgets up to EndScrollPanel are positioned relative to the top left of the content, which is contentHeight tall.
+// Their ids are scoped to the panel
+func (ui *ui) BeginScrollPanel(x, y, w, h, contentHeight float32) {
+	id := ui.getID("##scroll")
+	x, y, w, h = ui.place(x, y, w, h, mgl32.Vec2{150, 100})
+	if ui.scroll == nil {
+		ui.scroll = make(map[int]float32)
+	}
+
+	maxScroll := float32(math.Max(0, float64(contentHeight-h)))
+	scroll := ui.scroll[id]
+	if ui.regionhit(x, y, w, h) {
+		scroll -= ui.input.MouseWheel().Y() * scrollSpeed
+	}
+	scroll = mgl32.Clamp(scroll, 0, maxScroll)
+	ui.scroll[id] = scroll
+
+	contentW := w
+	if contentHeight > h {
+		contentW -= scrollbarWidth
+	}
+	ui.box(x, y, w, h, ui.styleColour(ui.theme.ScrollPanel, false, false), ui.theme.ScrollPanel)
+	ui.pushPanel(uiPanel{
+		offset:        mgl32.Vec2{x, y - scroll},
+		clip:          mgl32.Vec4{x, y, w, h},
+		size:          mgl32.Vec2{contentW, contentHeight},
+		id:            id,
+		x:             x,
+		y:             y,
+		w:             w,
+		h:             h,
+		contentHeight: contentHeight,
+	})
+	ui.pushIDScope(id)
+}
+
+// Ends the panel started by BeginScrollPanel and draws its scrollbar
+func (ui *ui) EndScrollPanel() {
+	p := ui.popPanel()
+	id := ui.getID("##scrollbar")
+	ui.PopID()
+	if p.contentHeight <= p.h {
+		return
+	}
+
+	maxScroll := p.contentHeight - p.h
+	thumbH := float32(math.Max(16, float64(p.h*p.h/p.contentHeight)))
+	x := p.x + p.w - scrollbarWidth
+
+	hovered, held, _ := ui.behaviour(id, x, p.y, scrollbarWidth, p.h)
+	if held {
+		t := (ui.input.MousePosition().Y() - p.y - thumbH/2) / (p.h - thumbH)
+		ui.scroll[p.id] = mgl32.Clamp(t, 0, 1) * maxScroll
+	}
+
+	thumbY := p.y + (p.h-thumbH)*ui.scroll[p.id]/maxScroll
+	ui.fill(x, p.y, scrollbarWidth, p.h, ui.theme.Track)
+	ui.fill(x, thumbY, scrollbarWidth, thumbH, ui.styleColour(ui.theme.Slider, hovered, held))
+}
+
+// Scrolling list of items, one of which can be selected. When focused, up and down move the selection.
+// Returns true when the selection changes
+func (ui *ui) List(x, y, w, h float32, items []string, selected *int) bool {
+	ui.PushID(pointerKey(selected))
+	listID := ui.getID("##list")
+	rowH := ui.lineHeight() + 4
+	ui.BeginScrollPanel(x, y, w, h, rowH*float32(len(items)))
+	p := ui.panels[len(ui.panels)-1]
+	w = p.w // as placed
+
+	changed := false
+	if ui.focusItem == listID && ui.disabled == 0 && len(items) > 0 {
+		if dir := ui.navAxis(true); dir != 0 {
+			i := clampInt(*selected+dir, 0, len(items)-1)
+			changed = i != *selected
+			*selected = i
+			// scroll it into view
+			top := rowH * float32(i)
+			ui.scroll[p.id] = mgl32.Clamp(ui.scroll[p.id], top+rowH-p.h, top)
+		}
+	}
+
+	for i, item := range items {
+		id := ui.getID(strconv.Itoa(i))
+		rx, ry := ui.translate(0, rowH*float32(i))
+		if ry+rowH < p.clip[1] || ry > p.clip[1]+p.clip[3] {
+			continue // scrolled out of view
+		}
+
+		hovered, held, clicked := ui.behaviour(id, rx, ry, w, rowH)
+		if ui.focusItem == id {
+			ui.focusItem = listID // rows are focused through the list
+		}
+		if clicked && *selected != i {
+			*selected = i
+			changed = true
+		}
+		style := ui.theme.ScrollPanel
+		if *selected == i {
+			ui.fill(rx, ry, w, rowH, ui.theme.Accent)
+		} else if hovered || held {
+			ui.fill(rx, ry, w, rowH, ui.styleColour(style, hovered, held))
+		}
+		ui.text(rx+ui.theme.Padding, ry+2, ui.theme.FontSize, item, style.Text)
+	}
+
+	ui.EndScrollPanel()
+	ui.focusable(listID, p.x, p.y, p.w, p.h)
+	ui.PopID()
+	return changed
+}
+
+// Shows a dialog in the middle of the screen while *open is true, dimming and blocking the UI beneath it.
+// Returns true while open, in which case the dialog's widgets follow, positioned relative to its content,
+// then EndModal. The title bar's close button, Escape and B set *open to false. Widget ids inside are scoped to the title
+func (ui *ui) BeginModal(title string, w, h float32, open *bool) bool {
+	if !*open {
+		return false
+	}
+	title, key := splitLabel(title)
+	ui.PushID(key)
+	id := ui.getID("##close")
+	if !ui.lastModal {
+		ui.activeItem = 0 // take focus from text inputs beneath
+		ui.focusItem = 0
+		ui.focusNext = ui.showFocus // focus the first widget inside if using keyboard or gamepad
+		ui.modalClose = id
+	}
+	ui.modal = true
+	ui.layer = uiLayerModal
+	ui.panels = append(ui.panels, uiPanel{}) // covers the screen, whatever panel it's declared in
+
+	t := ui.theme
+	ui.fill(0, 0, ScreenW, ScreenH, t.Dim)
+	x, y := float32(math.Floor(float64(ScreenW-w)/2)), float32(math.Floor(float64(ScreenH-h)/2))
+	titleH := ui.lineHeight() + 2*t.Padding
+	ui.box(x, y, w, h, ui.styleColour(t.Modal, false, false), t.Modal)
+	ui.fill(x, y, w, titleH, t.Accent)
+	ui.text(x+2*t.Padding, y+t.Padding, t.FontSize, title, t.Label)
+
+	closeX := x + w - titleH
+	hovered, _, clicked := ui.behaviour(id, closeX, y, titleH, titleH)
+	focusNext := ui.focusNext // for the first widget inside, rather than the close button
+	ui.focusNext = false
+	ui.focusable(id, closeX, y, titleH, titleH)
+	ui.focusNext = focusNext
+	// Escape and B close open dropdowns and stop text editing first
+	cancel := ui.nav.cancel && ui.openDropdown == 0 && ui.activeItem == 0
+	if clicked || ui.activated(id) || cancel {
+		*open = false
+	}
+	if hovered {
+		ui.fill(closeX, y, titleH, titleH, mgl32.Vec4{1, 1, 1, 0.3})
+	}
+	ui.text(closeX+(titleH-ui.textWidth(t.FontSize, "x"))/2, y+t.Padding, t.FontSize, "x", t.Label)
+
+	ui.pushPanel(uiPanel{
+		offset: mgl32.Vec2{x + 8, y + titleH + 8},
+		clip:   mgl32.Vec4{x, y + titleH, w, h - titleH},
+		size:   mgl32.Vec2{w - 16, h - titleH - 16},
+	})
+	return true
+}
+
+// Ends a dialog started by BeginModal, only call it when BeginModal returned true
+func (ui *ui) EndModal() {
+	if ui.focusNext {
+		// nothing inside could take focus
+		ui.focusItem, ui.focusNext = ui.modalClose, false
+	}
+	ui.popPanel() // content
+	ui.popPanel() // screen
+	ui.PopID()
+	ui.layer = uiLayerBase
+}
+
+// Draws text at x, y in screen pixels and returns its size
+func (ui *ui) text(x, y float32, size int, str string, colour mgl32.Vec4) mgl32.Vec2 {
+	if str == "" {
+		return mgl32.Vec2{}
+	}
+	printData := ui.theme.Font.renderItem(x, y, size, str).styled(ui.theme.TextStyle)
+	for _, ri := range printData.coloured(colour) {
+		ui.push(ri)
+	}
+	return printData.size
+}
+
+func (ui *ui) textWidth(size int, str string) float32 {
+	positions := ui.theme.Font.caretPositions(size, []rune(str))
+	return positions[len(positions)-1]
+}
+
+// Height of a line of widget text
+func (ui *ui) lineHeight() float32 {
+	return ui.theme.Font.Measure(ui.theme.FontSize, "").Y()
+}
@@ -1,47 +1,112 @@
 package engine
 
 import (
+	"image"
 	"log"
 
 	"github.com/go-gl/glfw/v3.2/glfw"
 )
 
+type WindowMode int
+
+const (
+	Windowed   WindowMode = iota
+	Fullscreen            // exclusive fullscreen, changing the monitor to the selected video mode
+	Borderless            // fills the monitor at its current video mode, without changing it
+)
+
+type VideoMode struct {
+	Width       int
+	Height      int
+	RefreshRate int
+}
+
+type MonitorInfo struct {
+	Name           string
+	X, Y           int       // position on the virtual desktop
+	PhysicalWidth  int       // millimetres
+	PhysicalHeight int       // millimetres
+	Current        VideoMode // the monitor's desktop video mode
+	Modes          []VideoMode
+}
+
+// Settings for the game's window. The zero value is a resizable, windowed, vsync off window
+// the size of the game's logical resolution
+type WindowConfig struct {
+	Title     string // defaults to "Go Game Engine"
+	Width     int    // window size in screen coordinates, defaults to the logical resolution
+	Height    int
+	Mode      WindowMode
+	Monitor   int        // index into Monitors() to use when fullscreen, 0 is the primary monitor
+	VideoMode *VideoMode // for Fullscreen, nil uses the monitor's current mode
+	VSync     bool
+	FixedSize bool          // stops the user resizing the window
+	Icon      []image.Image // candidate icon sizes, the closest to what the system wants is used
+	ScaleMode ScaleMode
+
+	QuitOnEscape bool // quits as soon as Escape is pressed, before scenes, rebinding or the UI can use it
+}
+
 type window struct {
 	win    *glfw.Window
 	Width  int
 	Height int
 
+	title     string
+	mode      WindowMode
+	monitor   int
+	videoMode *VideoMode
+	vsync     bool
+
+	// windowed position and size, restored when leaving fullscreen
+	windowedX, windowedY int
+	windowedW, windowedH int
+
 	// These are to fix incorrect rendering on macOS
 	windowMoved bool
 	moveDir     int
 }
 
-func createWindow(width, height int) *window {
+func createWindow(config WindowConfig) *window {
 	if err := glfw.Init(); err != nil {
 		glfw.Terminate()
 		panic(err)
 	}
 	log.Println("Initialised glfw")
 
-	glfw.WindowHint(glfw.Resizable, glfw.True)
+	resizable := glfw.True
+	if config.FixedSize {
+		resizable = glfw.False
+	}
+	glfw.WindowHint(glfw.Resizable, resizable)
 	glfw.WindowHint(glfw.ContextVersionMajor, 4)
 	glfw.WindowHint(glfw.ContextVersionMinor, 1)
 	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
 	glfw.WindowHint(glfw.ContextVersionMajor, 4)
 	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
 
-	win, err := glfw.CreateWindow(width, height, "Go Game Engine", nil, nil)
+	if config.Title == "" {
+		config.Title = "Go Game Engine"
+	}
+
+	win, err := glfw.CreateWindow(config.Width, config.Height, config.Title, nil, nil)
 	if err != nil {
 		panic(err)
 	}
 
 	gameWindow := &window{
 		win:         win,
-		Width:       width,
-		Height:      height,
+		Width:       config.Width,
+		Height:      config.Height,
+		title:       config.Title,
+		monitor:     config.Monitor,
+		videoMode:   config.VideoMode,
+		windowedW:   config.Width,
+		windowedH:   config.Height,
 		windowMoved: false,
 		moveDir:     1,
 	}
+	gameWindow.windowedX, gameWindow.windowedY = win.GetPos()
 
 	win.SetSizeCallback(func(win *glfw.Window, width, height int) {
 		gameWindow.Width = width
@@ -49,7 +114,13 @@ func createWindow(width, height int) *window {
 	})
 
 	win.MakeContextCurrent()
-	glfw.SwapInterval(0)
+	gameWindow.setVSync(config.VSync)
+	if len(config.Icon) > 0 {
+		win.SetIcon(config.Icon)
+	}
+	if config.Mode != Windowed {
+		gameWindow.setMode(config.Mode)
+	}
 
 	log.Println("Created window")
 	return gameWindow
@@ -66,9 +137,50 @@ func (w *window) getFramebuffer() (float32, float32) {
 }
 
 func (w *window) setTitle(title string) {
+	w.title = title
 	w.win.SetTitle(title)
 }
 
+func (w *window) setVSync(enabled bool) {
+	w.vsync = enabled
+	if enabled {
+		glfw.SwapInterval(1)
+	} else {
+		glfw.SwapInterval(0)
+	}
+}
+
+func (w *window) setMode(mode WindowMode) {
+	if w.mode == Windowed && mode != Windowed {
+		w.windowedX, w.windowedY = w.win.GetPos()
+		w.windowedW, w.windowedH = w.win.GetSize()
+	}
+	w.mode = mode
+
+	if mode == Windowed {
+		w.win.SetMonitor(nil, w.windowedX, w.windowedY, w.windowedW, w.windowedH, 0)
+		return
+	}
+
+	monitor := getMonitor(w.monitor)
+	current := monitor.GetVideoMode()
+	vm := VideoMode{current.Width, current.Height, current.RefreshRate}
+	if mode == Fullscreen && w.videoMode != nil {
+		vm = *w.videoMode
+	}
+	w.win.SetMonitor(monitor, 0, 0, vm.Width, vm.Height, vm.RefreshRate)
+	w.setVSync(w.vsync) // some drivers reset the swap interval when the mode changes
+}
+
+// Returns the monitor at index, or the primary monitor if there isn't one
+func getMonitor(index int) *glfw.Monitor {
+	monitors := glfw.GetMonitors()
+	if index > 0 && index < len(monitors) {
+		return monitors[index]
+	}
+	return glfw.GetPrimaryMonitor()
+}
+
 func (w *window) redraw() {
 	// These are to fix incorrect rendering on macOS
 	if !w.windowMoved {
@@ -99,5 +211,124 @@ func (w *window) Terminate() {
 }
 
 func WindowSize() (int, int) {
+	if headless {
+		return int(ScreenW), int(ScreenH)
+	}
 	return glfw.GetCurrentContext().GetSize()
 }
+
+// Connected monitors. GLFW always lists the primary monitor first
+func Monitors() []MonitorInfo {
+	if headless {
+		return nil
+	}
+
+	monitors := glfw.GetMonitors()
+	infos := make([]MonitorInfo, 0, len(monitors))
+	for _, m := range monitors {
+		info := MonitorInfo{Name: m.GetName()}
+		info.X, info.Y = m.GetPos()
+		info.PhysicalWidth, info.PhysicalHeight = m.GetPhysicalSize()
+		if vm := m.GetVideoMode(); vm != nil {
+			info.Current = VideoMode{vm.Width, vm.Height, vm.RefreshRate}
+		}
+		for _, vm := range m.GetVideoModes() {
+			info.Modes = append(info.Modes, VideoMode{vm.Width, vm.Height, vm.RefreshRate})
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Window settings. These do nothing for headless games
+
+func (g *Game) SetTitle(title string) {
+	if g.window != nil {
+		g.window.setTitle(title)
+	}
+}
+
+func (g *Game) Title() string {
+	if g.window == nil {
+		return ""
+	}
+	return g.window.title
+}
+
+// Switches between windowed, fullscreen and borderless fullscreen
+func (g *Game) SetWindowMode(mode WindowMode) {
+	if g.window != nil && mode != g.window.mode {
+		g.window.setMode(mode)
+	}
+}
+
+func (g *Game) WindowMode() WindowMode {
+	if g.window == nil {
+		return Windowed
+	}
+	return g.window.mode
+}
+
+func (g *Game) SetVSync(enabled bool) {
+	if g.window != nil {
+		g.window.setVSync(enabled)
+	}
+}
+
+func (g *Game) VSync() bool {
+	return g.window != nil && g.window.vsync
+}
+
+// Sets the window icon from one or more sizes of the same image. Has no effect on macOS
+func (g *Game) SetIcon(images ...image.Image) {
+	if g.window != nil {
+		g.window.win.SetIcon(images)
+	}
+}
+
+// Selects the monitor, as an index into Monitors(), used when fullscreen
+func (g *Game) SetMonitor(index int) {
+	if g.window == nil {
+		return
+	}
+	g.window.monitor = index
+	if g.window.mode != Windowed {
+		g.window.setMode(g.window.mode)
+	}
+}
+
+// Selects the video mode used for exclusive fullscreen. nil uses the monitor's current mode
+func (g *Game) SetVideoMode(mode *VideoMode) {
+	if g.window == nil {
+		return
+	}
+	g.window.videoMode = mode
+	if g.window.mode == Fullscreen {
+		g.window.setMode(Fullscreen)
+	}
+}
+
+// Sets the window size in screen coordinates. When fullscreen, this is the size restored on returning to windowed
+func (g *Game) SetWindowSize(width, height int) {
+	if g.window == nil {
+		return
+	}
+	if g.window.mode != Windowed {
+		g.window.windowedW, g.window.windowedH = width, height
+		return
+	}
+	g.window.win.SetSize(width, height)
+}
+
+// Ratio of framebuffer pixels to screen coordinates, e.g. 2 on a retina display
+func (g *Game) ContentScale() (float32, float32) {
+	if g.window == nil {
+		return 1, 1
+	}
+	w, h := g.window.getSize()
+	fw, fh := g.window.getFramebuffer()
+	if w == 0 || h == 0 {
+		return 1, 1
+	}
+	return fw / w, fh / h
+}
@@ -20,7 +20,6 @@ require (
 
 require (
 	github.com/faiface/beep v1.1.0
-	github.com/go-gl/gltext v0.0.0-20170328174336-01a355945a70
 	github.com/lafriks/go-tiled v0.11.0
 	golang.org/x/image v0.3.0
 )
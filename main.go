@@ -32,7 +32,7 @@ func main() {
 
 	// ==================================
 	// Code for actually running the game
-	game := engine.CreateGame(width, height)
+	game := engine.CreateGame(width, height, engine.WindowConfig{Title: "Go Game Engine", QuitOnEscape: true})
 	s2 := newScene2(game)
 	s := newScene(game, s2)
 	game.SetScene(s)
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/R-Mckenzie/go-engine/engine"
 	"github.com/go-gl/mathgl/mgl32"
@@ -23,22 +25,7 @@ func NewPlayer() Player {
 }
 
 func (p *Player) Update(t engine.Tilemap) {
-	move := mgl32.Vec3{0, 0, 0}
-	if engine.Input.KeyDown(engine.KeyW) {
-		move[1] -= 1
-	}
-	if engine.Input.KeyDown(engine.KeyA) {
-		move[0] -= 1
-	}
-	if engine.Input.KeyDown(engine.KeyS) {
-		move[1] += 1
-	}
-	if engine.Input.KeyDown(engine.KeyD) {
-		move[0] += 1
-	}
-	if move.Len() > 0 {
-		move = move.Normalize()
-	}
+	move := engine.Input.Axis2D("move")
 
 	oldPos := p.Pos
 	p.Pos[0] = p.Pos[0] + move[0]*p.speed
@@ -97,6 +84,17 @@ func newScene(game *engine.Game, s2 *scene2) *testScene {
 	animator.Add(runLeft, "run_left")
 	animator.Add(idleAnim, "idle")
 
+	engine.Input.BindAction("left", engine.KeyBinding(engine.KeyA), engine.GamepadAxisBinding(engine.GamepadLeftX, -1))
+	engine.Input.BindAction("right", engine.KeyBinding(engine.KeyD), engine.GamepadAxisBinding(engine.GamepadLeftX, 1))
+	engine.Input.BindAction("up", engine.KeyBinding(engine.KeyW), engine.GamepadAxisBinding(engine.GamepadLeftY, -1))
+	engine.Input.BindAction("down", engine.KeyBinding(engine.KeyS), engine.GamepadAxisBinding(engine.GamepadLeftY, 1))
+	engine.Input.BindAxis("moveX", "left", "right")
+	engine.Input.BindAxis("moveY", "up", "down")
+	engine.Input.BindAxis2D("move", "moveX", "moveY")
+	if err := engine.Input.LoadBindings("bindings.json"); err != nil && !os.IsNotExist(err) {
+		fmt.Println("Error loading bindings: ", err)
+	}
+
 	engine.LoadShader("shaders/postprocessVertex.glsl", "shaders/funkyEdgesFragment.glsl", "funky lines")
 	norm := engine.NewTexture("res/atlascobble_n.png")
 
@@ -111,7 +109,6 @@ func newScene(game *engine.Game, s2 *scene2) *testScene {
 	}
 }
 
-var invOpen bool = false
 var isfunky = false
 
 var r, g, b float32 = 0.3, 0.3, 0.3
@@ -151,7 +148,7 @@ func (s *testScene) Update() {
 	}
 
 	if engine.Input.KeyOnce(engine.KeyI) {
-		invOpen = !invOpen
+		s.game.PushScene(&inventory{game: s.game})
 	}
 
 	if engine.Input.KeyOnce(engine.KeyC) {
@@ -164,13 +161,14 @@ func (s *testScene) Update() {
 		}
 	}
 
-	if engine.Input.KeyDown(engine.KeyA) {
+	if engine.Input.Action("left") {
 		animator.Trigger("run_left")
-	} else if engine.Input.KeyDown(engine.KeyD) {
+	} else if engine.Input.Action("right") {
 		animator.Trigger("run_right")
 	} else {
 		animator.Trigger("idle")
 	}
+	animator.Update(engine.Time.Delta())
 
 	if animator.Current.Changed {
 		s.p.Sprite.SetTexture(animator.Frame())
@@ -188,10 +186,47 @@ func (s *testScene) Update() {
 	engine.UI.Begin()
 	if engine.UI.Button(100, 100, 300, 100, "Button", mgl32.Vec4{1, 0.3, 0.2, 1}) {
 		fmt.Printf("clicked\n")
-		s.game.SetScene(s.s2)
+		s.game.SetScene(s.s2, engine.Fade(500*time.Millisecond, mgl32.Vec4{0, 0, 0, 1}))
+	}
+
+	if engine.UI.TextInput("username", 300, 50, 30, 16, &buf, engine.TextInputOptions{MaxLength: 24}) {
+		fmt.Printf("username: %s\n", buf)
 	}
 
-	engine.UI.TextInput("username", 300, 50, 30, 16, &buf)
+	engine.UI.End()
+}
+
+// Drawn over the game, which stays on screen but stops updating until the inventory is closed
+type inventory struct {
+	game *engine.Game
+
+	volume     float32
+	fullscreen bool
+	quality    int
+}
+
+func (i *inventory) Overlay() bool {
+	return true
+}
 
+func (i *inventory) Update() {
+	if engine.Input.KeyOnce(engine.KeyI) {
+		i.game.PopScene()
+	}
+
+	engine.UI.Begin()
+	engine.UI.BeginArea(engine.AnchorCentre, 240, 0, engine.LayoutOptions{Spacing: 10, Align: engine.AlignCentre})
+	engine.UI.Label("Inventory", 0, 0, 32, mgl32.Vec4{1, 1, 1, 1})
+	engine.UI.Slider(0, 0, 0, 20, &i.volume, 0, 1)
+	engine.UI.Tooltip("Volume")
+	if engine.UI.Checkbox(0, 0, "Fullscreen", &i.fullscreen) {
+		mode := engine.Windowed
+		if i.fullscreen {
+			mode = engine.Borderless
+		}
+		i.game.SetWindowMode(mode)
+	}
+	engine.UI.Dropdown(0, 0, 0, 24, []string{"Low", "Medium", "High"}, &i.quality)
+	engine.UI.EndLayout()
 	engine.UI.End()
 }